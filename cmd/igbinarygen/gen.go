@@ -0,0 +1,463 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/vmihailenco/tagparser"
+	"github.com/zarken-go/igbinary"
+	"github.com/zarken-go/igbinary/internal/structtag"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// basic describes how values of a basic type are encoded and decoded.
+type basic struct {
+	// conv is the Go type the Encoder primitive accepts.
+	conv   string
+	encode string
+	decode string
+}
+
+var basics = map[string]basic{
+	`string`:  {`string`, `EncodeString`, `DecodeString`},
+	`bool`:    {`bool`, `EncodeBool`, `DecodeBool`},
+	`int`:     {`int64`, `EncodeInt64`, `DecodeInt`},
+	`int8`:    {`int64`, `EncodeInt64`, `DecodeInt8`},
+	`int16`:   {`int64`, `EncodeInt64`, `DecodeInt16`},
+	`int32`:   {`int64`, `EncodeInt64`, `DecodeInt32`},
+	`rune`:    {`int64`, `EncodeInt64`, `DecodeInt32`},
+	`int64`:   {`int64`, `EncodeInt64`, `DecodeInt64`},
+	`uint`:    {`uint64`, `EncodeUint64`, `DecodeUint`},
+	`uint8`:   {`uint64`, `EncodeUint64`, `DecodeUint8`},
+	`byte`:    {`uint64`, `EncodeUint64`, `DecodeUint8`},
+	`uint16`:  {`uint64`, `EncodeUint64`, `DecodeUint16`},
+	`uint32`:  {`uint64`, `EncodeUint64`, `DecodeUint32`},
+	`uint64`:  {`uint64`, `EncodeUint64`, `DecodeUint64`},
+	`float32`: {`float64`, `EncodeFloat64`, `DecodeFloat32`},
+	`float64`: {`float64`, `EncodeFloat64`, `DecodeFloat64`},
+	`[]byte`:  {`[]byte`, `EncodeBytes`, `DecodeString`},
+
+	`time.Time`:     {`time.Time`, `EncodeTime`, `DecodeTime`},
+	`time.Duration`: {`time.Duration`, `EncodeDuration`, `DecodeDuration`},
+}
+
+// fieldType is the resolved type of a struct field.
+type fieldType struct {
+	// name is the Go type as written in the source.
+	name string
+	ptr  bool
+	// basic is set when the underlying type is in basics, named reports
+	// whether name differs from it and needs a conversion.
+	basic *basic
+	named bool
+	// generated is set for struct types generated in the same run.
+	generated bool
+}
+
+// nilSlice reports whether the type is a byte slice, which like reflection
+// encodes nil as null.
+func (t fieldType) nilSlice() bool {
+	return !t.ptr && t.basic != nil && t.basic.conv == `[]byte`
+}
+
+type structField struct {
+	// key is name mangled for the visibility class, see
+	// igbinary.MangleProperty.
+	key   string
+	name  string
+	class string
+	expr  string
+	typ   fieldType
+	// timeMode is the immutable, unix or format option of a time.Time
+	// field, layout the value of the latter.
+	timeMode string
+	layout   string
+}
+
+type generator struct {
+	pkg string
+	// specs holds every type declared in the package.
+	specs map[string]ast.Expr
+	gen   map[string]bool
+	// enums holds the types declaring a MarshalEnum method, they encode as
+	// PHP enum cases rather than their underlying type.
+	enums map[string]bool
+	buf   bytes.Buffer
+	// usesIgcode is set once generated code needs the igcode package,
+	// usesTime once it converts types defined from time.Time.
+	usesIgcode bool
+	usesTime   bool
+}
+
+func generate(file string, typeNames []string) ([]byte, error) {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, filepath.Dir(file), func(fi os.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), `_test.go`) && !strings.HasSuffix(fi.Name(), `_igbinary.go`)
+	}, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	g := &generator{specs: make(map[string]ast.Expr), gen: make(map[string]bool), enums: make(map[string]bool)}
+	var target *ast.File
+	for name, pkg := range pkgs {
+		for path, f := range pkg.Files {
+			if filepath.Base(path) == filepath.Base(file) {
+				target = f
+				g.pkg = name
+			}
+		}
+		if target != nil && g.pkg == name {
+			for _, f := range pkg.Files {
+				g.collect(f)
+			}
+		}
+	}
+	if target == nil {
+		return nil, fmt.Errorf(`%s: file not found`, file)
+	}
+
+	if len(typeNames) == 0 {
+		for _, decl := range target.Decls {
+			for _, spec := range typeSpecs(decl) {
+				if _, ok := spec.Type.(*ast.StructType); ok {
+					typeNames = append(typeNames, spec.Name.Name)
+				}
+			}
+		}
+	}
+	for _, name := range typeNames {
+		if _, ok := g.specs[name].(*ast.StructType); !ok {
+			return nil, fmt.Errorf(`%s: struct type not found`, name)
+		}
+		g.gen[name] = true
+	}
+
+	var body bytes.Buffer
+	for _, name := range typeNames {
+		fields, class, err := g.fields(name, g.specs[name].(*ast.StructType))
+		if err != nil {
+			return nil, fmt.Errorf(`%s: %s`, name, err)
+		}
+		g.buf.Reset()
+		g.marshal(name, class, fields)
+		g.unmarshal(name, fields)
+		body.Write(g.buf.Bytes())
+	}
+
+	var src bytes.Buffer
+	src.WriteString("// Code generated by igbinarygen. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&src, "package %s\n\nimport (\n\t%q\n", g.pkg, `github.com/zarken-go/igbinary`)
+	if g.usesIgcode {
+		fmt.Fprintf(&src, "\t%q\n", `github.com/zarken-go/igbinary/igcode`)
+	}
+	if g.usesTime {
+		fmt.Fprintf(&src, "\t%q\n", `time`)
+	}
+	src.WriteString(")\n")
+	src.Write(body.Bytes())
+	return format.Source(src.Bytes())
+}
+
+func typeSpecs(decl ast.Decl) []*ast.TypeSpec {
+	gen, ok := decl.(*ast.GenDecl)
+	if !ok || gen.Tok != token.TYPE {
+		return nil
+	}
+	specs := make([]*ast.TypeSpec, 0, len(gen.Specs))
+	for _, spec := range gen.Specs {
+		specs = append(specs, spec.(*ast.TypeSpec))
+	}
+	return specs
+}
+
+func (g *generator) collect(f *ast.File) {
+	for _, decl := range f.Decls {
+		for _, spec := range typeSpecs(decl) {
+			g.specs[spec.Name.Name] = spec.Type
+		}
+		if fn, ok := decl.(*ast.FuncDecl); ok && fn.Recv != nil && fn.Name.Name == `MarshalEnum` {
+			g.enums[embeddedName(fn.Recv.List[0].Type)] = true
+		}
+	}
+}
+
+// fields lists the fields of the struct typeName as getFields does for the
+// reflection based decoder, along with the class set on a blank field.
+func (g *generator) fields(typeName string, st *ast.StructType) ([]structField, string, error) {
+	var fields []structField
+	var class string
+	var private []int
+	for _, f := range st.Fields.List {
+		var tag reflect.StructTag
+		if f.Tag != nil {
+			s, err := strconv.Unquote(f.Tag.Value)
+			if err != nil {
+				return nil, ``, err
+			}
+			tag = reflect.StructTag(s)
+		}
+		parsed := tagparser.Parse(tag.Get(`igbinary`))
+		if parsed.Name == `-` {
+			continue
+		}
+
+		names := make([]string, 0, len(f.Names))
+		for _, n := range f.Names {
+			if n.Name == `_` {
+				if c, ok := structtag.Option(parsed, `class`); ok {
+					class = c
+				}
+				continue
+			}
+			names = append(names, n.Name)
+		}
+		if len(names) == 0 && len(f.Names) == 0 {
+			names = append(names, embeddedName(f.Type))
+		}
+
+		var visibility string
+		if parsed.HasOption(`protected`) {
+			visibility = `*`
+		} else if c, ok := structtag.Option(parsed, `private`); ok {
+			visibility = c
+		}
+
+		typ := g.resolve(f.Type)
+		// Reflection promotes the fields of embedded structs, which the
+		// generated methods do not.
+		if len(f.Names) == 0 && parsed.Name == `` && typ.basic == nil && !g.enums[names[0]] {
+			return nil, ``, fmt.Errorf(`embedded field %s needs a tag name, promoted fields are not supported`, names[0])
+		}
+		var timeMode, layout string
+		if typ.basic != nil && typ.basic.conv == `time.Time` {
+			for _, mode := range []string{`immutable`, `unix`, `format`} {
+				if value, ok := structtag.Option(parsed, mode); ok {
+					timeMode, layout = mode, value
+					break
+				}
+			}
+		}
+		for _, name := range names {
+			key := parsed.Name
+			if key == `` {
+				key = name
+			}
+			if _, ok := structtag.Option(parsed, `private`); ok && visibility == `` {
+				private = append(private, len(fields))
+			}
+			fields = append(fields, structField{
+				name: key, class: visibility, expr: `v.` + name, typ: typ, timeMode: timeMode, layout: layout,
+			})
+		}
+	}
+
+	// Private fields without a class belong to the class of the struct.
+	for _, i := range private {
+		fields[i].class = class
+		if class == `` {
+			fields[i].class = typeName
+		}
+	}
+	for i := range fields {
+		fields[i].key = igbinary.MangleProperty(fields[i].class, fields[i].name)
+	}
+	return fields, class, nil
+}
+
+func embeddedName(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		return embeddedName(t.X)
+	case *ast.SelectorExpr:
+		return t.Sel.Name
+	case *ast.Ident:
+		return t.Name
+	}
+	return ``
+}
+
+func (g *generator) resolve(expr ast.Expr) fieldType {
+	typ := fieldType{name: exprString(expr)}
+	if star, ok := expr.(*ast.StarExpr); ok {
+		typ.ptr = true
+		typ.name = exprString(star.X)
+		expr = star.X
+	}
+
+	// Follow named types declared in the package to their underlying type.
+	for depth := 0; depth < 10; depth++ {
+		ident, ok := expr.(*ast.Ident)
+		if !ok {
+			break
+		}
+		if g.gen[ident.Name] {
+			typ.generated = depth == 0
+			return typ
+		}
+		if depth == 0 && g.enums[ident.Name] {
+			return typ
+		}
+		if b, ok := basics[ident.Name]; ok && g.specs[ident.Name] == nil {
+			typ.basic = &b
+			typ.named = depth > 0
+			return typ
+		}
+		if expr = g.specs[ident.Name]; expr == nil {
+			return typ
+		}
+	}
+	if b, ok := basics[exprString(expr)]; ok {
+		typ.named = typ.name != exprString(expr)
+		// Reflection only sees the kind of types defined from
+		// time.Duration and encodes them as integers.
+		if typ.named && b.conv == `time.Duration` {
+			b = basics[`int64`]
+		}
+		typ.basic = &b
+	}
+	return typ
+}
+
+// exprString formats a type expression the way it is written in the source.
+func exprString(expr ast.Expr) string {
+	var buf bytes.Buffer
+	if err := format.Node(&buf, token.NewFileSet(), expr); err != nil {
+		return ``
+	}
+	return buf.String()
+}
+
+func (g *generator) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+func (g *generator) marshal(name, class string, fields []structField) {
+	g.printf("\n// MarshalIgbinary implements igbinary.Marshaler.\n")
+	g.printf("func (v %s) MarshalIgbinary(e *igbinary.Encoder) error {\n", name)
+	if class != `` {
+		g.printf("if err := e.EncodeObjectLen(%q, %d); err != nil {\nreturn err\n}\n", class, len(fields))
+	} else {
+		g.printf("if err := e.EncodeArrayLen(%d); err != nil {\nreturn err\n}\n", len(fields))
+	}
+	for _, f := range fields {
+		if f.typ.named && f.typ.basic != nil && strings.HasPrefix(f.typ.basic.conv, `time.`) {
+			g.usesTime = true
+		}
+		g.printf("if err := e.EncodeStringKey(%q); err != nil {\nreturn err\n}\n", f.key)
+		value := f.expr
+		if f.typ.ptr && f.typ.basic != nil {
+			value = `*` + f.expr
+		}
+		if f.typ.ptr && (f.typ.basic != nil || f.typ.generated) || f.typ.nilSlice() {
+			g.printf("if %s == nil {\nif err := e.EncodeNil(); err != nil {\nreturn err\n}\n} else ", f.expr)
+		}
+		g.printf("if err := %s; err != nil {\nreturn err\n}\n", encodeCall(f, value))
+	}
+	g.printf("return nil\n}\n")
+}
+
+func encodeCall(f structField, value string) string {
+	typ := f.typ
+	if f.timeMode != `` && typ.named {
+		value = typ.basic.conv + `(` + value + `)`
+	} else if f.timeMode != `` && strings.HasPrefix(value, `*`) {
+		value = `(` + value + `)`
+	}
+	switch f.timeMode {
+	case `immutable`:
+		return `e.EncodeDateTime(igbinary.DateTimeImmutableClass, ` + value + `)`
+	case `unix`:
+		return `e.EncodeInt64(` + value + `.Unix())`
+	case `format`:
+		return `e.EncodeString(` + value + `.Format(` + strconv.Quote(f.layout) + `))`
+	}
+
+	switch {
+	case typ.generated:
+		return value + `.MarshalIgbinary(e)`
+	case typ.basic == nil:
+		return `e.Encode(` + value + `)`
+	case typ.named || typ.basic.conv != typ.name:
+		return `e.` + typ.basic.encode + `(` + typ.basic.conv + `(` + value + `))`
+	}
+	return `e.` + typ.basic.encode + `(` + value + `)`
+}
+
+func (g *generator) unmarshal(name string, fields []structField) {
+	g.printf("\n// UnmarshalIgbinary implements igbinary.Unmarshaler.\n")
+	g.printf("func (v *%s) UnmarshalIgbinary(d *igbinary.Decoder) error {\n", name)
+	g.printf("_, n, err := d.DecodeObjectLen()\nif err != nil {\nreturn err\n}\n")
+	g.printf("for i := 0; i < n; i++ {\n")
+	g.printf("key, err := d.DecodeString()\nif err != nil {\nreturn err\n}\n")
+	// Mangled keys match by name, fields declaring a visibility also
+	// require the key to carry it.
+	class := `_`
+	for _, f := range fields {
+		if f.class != `` {
+			class = `class`
+		}
+	}
+	g.printf("%s, name := igbinary.DemangleProperty(key)\n", class)
+	g.printf("switch name {\n")
+	for _, f := range fields {
+		g.printf("case %q:\n", f.name)
+		if f.class != `` {
+			g.printf("if class != %q {\nif err := d.SkipUnknownField(key); err != nil {\nreturn err\n}\ncontinue\n}\n", f.class)
+		}
+		g.decodeField(f)
+	}
+	g.printf("default:\nif err := d.SkipUnknownField(key); err != nil {\nreturn err\n}\n")
+	g.printf("}\n}\nreturn nil\n}\n")
+}
+
+func (g *generator) decodeField(f structField) {
+	typ := f.typ
+	if typ.basic == nil && !typ.generated {
+		g.printf("if err := d.Decode(&%s); err != nil {\nreturn err\n}\n", f.expr)
+		return
+	}
+
+	if typ.ptr || typ.nilSlice() {
+		g.usesIgcode = true
+		g.printf("c, err := d.PeekCode()\nif err != nil {\nreturn err\n}\n")
+		g.printf("if c == igcode.Nil {\n%s = nil\nif err := d.DecodeNil(); err != nil {\nreturn err\n}\ncontinue\n}\n", f.expr)
+	}
+
+	if typ.generated {
+		if typ.ptr {
+			g.printf("if %s == nil {\n%s = new(%s)\n}\n", f.expr, f.expr, typ.name)
+		}
+		value := `&` + f.expr
+		if typ.ptr {
+			value = f.expr
+		}
+		g.printf("if err := d.DecodeUnmarshaler(%s); err != nil {\nreturn err\n}\n", value)
+		return
+	}
+
+	if f.timeMode == `format` {
+		g.printf("x, err := d.DecodeTimeLayout(%q)\nif err != nil {\nreturn err\n}\n", f.layout)
+	} else {
+		g.printf("x, err := d.%s()\nif err != nil {\nreturn err\n}\n", typ.basic.decode)
+	}
+	value := `x`
+	if typ.named || typ.basic.decode == `DecodeString` && typ.name != `string` {
+		value = typ.name + `(x)`
+	}
+	if typ.ptr {
+		if value != `x` {
+			g.printf("y := %s\n", value)
+			value = `y`
+		}
+		value = `&` + value
+	}
+	g.printf("%s = %s\n", f.expr, value)
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"flag"
+	"github.com/stretchr/testify/suite"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool(`update`, false, `update golden files`)
+
+type GenerateSuite struct {
+	suite.Suite
+}
+
+func (Suite *GenerateSuite) TestGolden() {
+	src, err := generate(`testdata/models.go`, []string{`User`, `Address`, `Account`, `Event`, `Order`, `Node`})
+	Suite.Require().Nil(err)
+
+	if *update {
+		Suite.Require().Nil(ioutil.WriteFile(`testdata/models_igbinary.golden`, src, 0o644))
+	}
+	golden, err := ioutil.ReadFile(`testdata/models_igbinary.golden`)
+	Suite.Require().Nil(err)
+	Suite.Equal(string(golden), string(src))
+}
+
+// TestGoldenRoundTrip builds the golden file with the models and checks its
+// methods write and read the same bytes and values as reflection does.
+func (Suite *GenerateSuite) TestGoldenRoundTrip() {
+	if testing.Short() {
+		Suite.T().Skip(`builds a package`)
+	}
+	goTool, err := exec.LookPath(`go`)
+	if err != nil {
+		Suite.T().Skip(`go tool not found`)
+	}
+
+	// The package lives inside the module to build against this igbinary.
+	dir, err := ioutil.TempDir(`testdata`, `build`)
+	Suite.Require().Nil(err)
+	defer os.RemoveAll(dir)
+	for src, dst := range map[string]string{
+		`models.go`:              `models.go`,
+		`models_igbinary.golden`: `models_igbinary.go`,
+		`roundtrip_test.go`:      `roundtrip_test.go`,
+	} {
+		b, err := ioutil.ReadFile(filepath.Join(`testdata`, src))
+		Suite.Require().Nil(err)
+		Suite.Require().Nil(ioutil.WriteFile(filepath.Join(dir, dst), b, 0o644))
+	}
+
+	cmd := exec.Command(goTool, `test`, `.`)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	Suite.Nil(err, string(out))
+}
+
+func (Suite *GenerateSuite) TestAllStructs() {
+	src, err := generate(`testdata/models.go`, nil)
+	Suite.Require().Nil(err)
+	Suite.Contains(string(src), `func (v User) MarshalIgbinary(`)
+	Suite.Contains(string(src), `func (v *Address) UnmarshalIgbinary(`)
+}
+
+func (Suite *GenerateSuite) TestErrors() {
+	_, err := generate(`testdata/models.go`, []string{`UserID`})
+	Suite.EqualError(err, `UserID: struct type not found`)
+	_, err = generate(`testdata/missing.go`, nil)
+	Suite.EqualError(err, `testdata/missing.go: file not found`)
+	_, err = generate(`testdata/embedded.go`, nil)
+	Suite.EqualError(err, `Admin: embedded field User needs a tag name, promoted fields are not supported`)
+}
+
+func TestGenerateSuite(t *testing.T) {
+	suite.Run(t, new(GenerateSuite))
+}
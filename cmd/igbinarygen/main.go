@@ -0,0 +1,69 @@
+// Command igbinarygen generates reflection-free MarshalIgbinary and
+// UnmarshalIgbinary methods for struct types. The methods implement
+// igbinary.Marshaler and igbinary.Unmarshaler, which the Encoder and Decoder
+// use in place of reflection.
+//
+// Fields are named following the same `igbinary` struct tags as the
+// reflection based decoder. Fields of basic types, []byte, time.Time,
+// time.Duration and pointers to them are handled by the Encoder and Decoder
+// primitives, fields of struct types generated in the same run call their
+// generated methods, any other field falls back to Encoder.Encode and
+// Decoder.Decode. The unix, immutable and format options of time.Time
+// fields select the same encodings as for reflection. The protected and
+// private options and the class option of a blank field encode the struct
+// as a PHP object with mangled property names, see igbinary.MangleProperty.
+//
+// Typical use is a go:generate directive in the file declaring the types:
+//
+//	//go:generate igbinarygen -type User,Address
+//
+// Without -type, methods are generated for every struct type declared in
+// the file. The output is written to <file>_igbinary.go unless -output is
+// given.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	typeNames := flag.String(`type`, ``, `comma-separated list of struct type names; default all structs of the file`)
+	output := flag.String(`output`, ``, `output file name; default <file>_igbinary.go`)
+	flag.Parse()
+
+	file := flag.Arg(0)
+	if file == `` {
+		file = os.Getenv(`GOFILE`)
+	}
+	if file == `` {
+		fmt.Fprintln(os.Stderr, `igbinarygen: no input file, run through go generate or pass a file name`)
+		os.Exit(2)
+	}
+
+	var types []string
+	if *typeNames != `` {
+		types = strings.Split(*typeNames, `,`)
+	}
+
+	src, err := generate(file, types)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, `igbinarygen:`, err)
+		os.Exit(1)
+	}
+
+	out := *output
+	if out == `` {
+		out = strings.TrimSuffix(file, `.go`) + `_igbinary.go`
+	} else if !filepath.IsAbs(out) {
+		out = filepath.Join(filepath.Dir(file), out)
+	}
+	if err := ioutil.WriteFile(out, src, 0o644); err != nil {
+		fmt.Fprintln(os.Stderr, `igbinarygen:`, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,7 @@
+package models
+
+// Admin embeds User, whose fields reflection promotes.
+type Admin struct {
+	User
+	Level int `igbinary:"level"`
+}
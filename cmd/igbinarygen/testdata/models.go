@@ -0,0 +1,88 @@
+package models
+
+import (
+	"time"
+)
+
+type UserID int64
+
+type Email string
+
+type Raw []byte
+
+type Stamp time.Time
+
+type Wait time.Duration
+
+type User struct {
+	ID       UserID            `igbinary:"id"`
+	Name     string            `igbinary:"name"`
+	Email    *Email            `igbinary:"email"`
+	Age      uint8             `igbinary:"age"`
+	Score    float32           `igbinary:"score"`
+	Active   bool              `igbinary:"active"`
+	Avatar   Raw               `igbinary:"avatar"`
+	Address  Address           `igbinary:"address"`
+	Billing  *Address          `igbinary:"billing"`
+	Settings map[string]string `igbinary:"settings"`
+	Session  string            `igbinary:"-"`
+	Nickname *string
+}
+
+type Address struct {
+	Street, City string
+	Zip          int
+}
+
+type Account struct {
+	_       struct{} `igbinary:",class=App\\Account"`
+	ID      int64    `igbinary:"id"`
+	Balance float64  `igbinary:"balance,protected"`
+	Token   string   `igbinary:"token,private"`
+	Owner   string   `igbinary:"owner,private=App\\Model"`
+}
+
+type Event struct {
+	Created time.Time     `igbinary:"created"`
+	Updated *time.Time    `igbinary:"updated,immutable"`
+	Seen    time.Time     `igbinary:"seen,unix"`
+	Day     time.Time     `igbinary:"day,format=2006-01-02"`
+	At      time.Time     `igbinary:"at,format=15:04"`
+	Sent    *time.Time    `igbinary:"sent,format=2006-01-02T15:04:05Z07:00"`
+	Timeout time.Duration `igbinary:"timeout"`
+	Logged  Stamp         `igbinary:"logged"`
+	Grace   *Wait         `igbinary:"grace"`
+}
+
+type Status string
+
+const (
+	Pending Status = `pending`
+	Shipped Status = `shipped`
+)
+
+func (s Status) MarshalEnum() (string, string, error) {
+	if s == Shipped {
+		return `App\OrderStatus`, `Shipped`, nil
+	}
+	return `App\OrderStatus`, `Pending`, nil
+}
+
+func (s *Status) UnmarshalEnum(class, name string) error {
+	*s = Pending
+	if name == `Shipped` {
+		*s = Shipped
+	}
+	return nil
+}
+
+type Order struct {
+	ID     int64   `igbinary:"id"`
+	Status Status  `igbinary:"status"`
+	Prev   *Status `igbinary:"prev"`
+}
+
+type Node struct {
+	Value int   `igbinary:"value"`
+	Next  *Node `igbinary:"next"`
+}
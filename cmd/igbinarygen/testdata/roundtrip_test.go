@@ -0,0 +1,106 @@
+package models
+
+import (
+	"bytes"
+	"github.com/zarken-go/igbinary"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// The plain types share the fields of the models but not their generated
+// methods, so igbinary encodes and decodes them through reflection.
+type (
+	plainUser    User
+	plainAddress Address
+	plainAccount Account
+	plainEvent   Event
+	plainOrder   Order
+	plainNode    Node
+)
+
+func TestRoundTrip(t *testing.T) {
+	email := Email(`ann@example.com`)
+	nickname := `annie`
+	updated := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+	sent := time.Date(2024, 1, 2, 13, 45, 0, 0, time.UTC)
+	grace := Wait(time.Minute)
+	shipped := Shipped
+
+	for _, test := range []struct {
+		generated, plain interface{}
+	}{
+		{
+			User{ID: 7, Name: `Ann`, Email: &email, Age: 42, Score: 1.5, Active: true, Avatar: Raw("\x00\xff"),
+				Address: Address{Street: `Main St`, City: `Springfield`, Zip: 12345}, Billing: &Address{City: `Shelbyville`},
+				Settings: map[string]string{`theme`: `dark`}, Nickname: &nickname},
+			new(plainUser),
+		},
+		{User{Name: `Bob`}, new(plainUser)},
+		{Address{Street: `Main St`, City: `Springfield`, Zip: 12345}, new(plainAddress)},
+		{Account{ID: 7, Balance: 9.5, Token: `secret`, Owner: `Ann`}, new(plainAccount)},
+		{
+			Event{Created: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), Updated: &updated,
+				Seen: time.Unix(1700000000, 0), Day: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+				At: time.Date(0, 1, 1, 13, 45, 0, 0, time.UTC), Sent: &sent, Timeout: 90 * time.Second,
+				Logged: Stamp(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)), Grace: &grace},
+			new(plainEvent),
+		},
+		{Order{ID: 7, Status: Pending, Prev: &shipped}, new(plainOrder)},
+		{Order{ID: 8, Status: Shipped}, new(plainOrder)},
+		{Node{Value: 1, Next: &Node{Value: 2}}, new(plainNode)},
+	} {
+		name := reflect.TypeOf(test.generated).Name()
+		generated, err := igbinary.Marshal(test.generated)
+		if err != nil {
+			t.Fatalf(`%s: Marshal: %v`, name, err)
+		}
+		value := reflect.ValueOf(test.generated).Convert(reflect.TypeOf(test.plain).Elem()).Interface()
+		plain, err := igbinary.Marshal(value)
+		if err != nil {
+			t.Fatalf(`%s: Marshal through reflection: %v`, name, err)
+		}
+		if !bytes.Equal(generated, plain) {
+			t.Errorf("%s: MarshalIgbinary writes\n%x\nreflection writes\n%x", name, generated, plain)
+			continue
+		}
+
+		decoded := reflect.New(reflect.TypeOf(test.generated))
+		if err := igbinary.Unmarshal(generated, decoded.Interface()); err != nil {
+			t.Fatalf(`%s: Unmarshal: %v`, name, err)
+		}
+		if err := igbinary.Unmarshal(generated, test.plain); err != nil {
+			t.Fatalf(`%s: Unmarshal through reflection: %v`, name, err)
+		}
+		fromPlain := reflect.ValueOf(test.plain).Elem().Convert(reflect.TypeOf(test.generated)).Interface()
+		if !reflect.DeepEqual(decoded.Elem().Interface(), fromPlain) {
+			t.Errorf("%s: UnmarshalIgbinary reads\n%#v\nreflection reads\n%#v", name, decoded.Elem().Interface(), fromPlain)
+		}
+	}
+}
+
+func TestMaxDepth(t *testing.T) {
+	// Nested decoders count toward the max depth, so a long list fails
+	// instead of overflowing the stack.
+	for _, test := range []struct {
+		length int
+		err    string
+	}{
+		{10000, ``},
+		{10001, `igbinary: Decode(exceeded max depth of 10000)`},
+	} {
+		var list *Node
+		for i := 0; i < test.length; i++ {
+			list = &Node{Value: i, Next: list}
+		}
+		b, err := igbinary.Marshal(list)
+		if err != nil {
+			t.Fatalf(`%d: Marshal: %v`, test.length, err)
+		}
+		var decoded Node
+		err = igbinary.Unmarshal(b, &decoded)
+		if test.err == `` && err != nil || test.err != `` && (err == nil || err.Error() != test.err) {
+			t.Errorf(`%d: Unmarshal returns %v, want %q`, test.length, err, test.err)
+		}
+	}
+}
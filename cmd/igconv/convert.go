@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"github.com/zarken-go/igbinary"
+	"github.com/zarken-go/igbinary/phpserialize"
+)
+
+type options struct {
+	header bool
+	pretty bool
+	strict bool
+}
+
+func convert(from, to string, data []byte, opts options) ([]byte, error) {
+	v, err := decode(from, data, opts)
+	if err != nil {
+		return nil, err
+	}
+	return encode(to, v, opts)
+}
+
+func decode(format string, data []byte, opts options) (interface{}, error) {
+	switch format {
+	case `igbinary`:
+		return decodeIgbinary(data, opts)
+	case `json`:
+		return decodeJSON(data, opts)
+	case `php`:
+		if opts.strict {
+			return phpserialize.Unmarshal(data)
+		}
+		v, _, err := phpserialize.Decode(data)
+		return v, err
+	case `text`:
+		return decodeText(data)
+	}
+	return nil, fmt.Errorf(`unknown input format %q`, format)
+}
+
+func encode(format string, v interface{}, opts options) ([]byte, error) {
+	switch format {
+	case `igbinary`:
+		return encodeIgbinary(v, opts)
+	case `json`:
+		return encodeJSON(v, opts)
+	case `php`:
+		return phpserialize.Marshal(v)
+	case `text`:
+		return encodeText(v, opts)
+	}
+	return nil, fmt.Errorf(`unknown output format %q`, format)
+}
+
+func decodeIgbinary(data []byte, opts options) (interface{}, error) {
+	r := bytes.NewReader(data)
+	d := igbinary.NewDecoder(r)
+	if opts.header && (opts.strict || igbinary.HasHeader(data)) {
+		if err := d.DecodeHeader(); err != nil {
+			return nil, err
+		}
+	}
+	v, err := d.DecodeInterface()
+	if err != nil {
+		return nil, err
+	}
+	if opts.strict && r.Len() > 0 {
+		return nil, fmt.Errorf(`trailing data at offset %d`, len(data)-r.Len())
+	}
+	return v, nil
+}
+
+func encodeIgbinary(v interface{}, opts options) ([]byte, error) {
+	var buf bytes.Buffer
+	e := igbinary.NewEncoder(&buf)
+	if opts.header {
+		if err := e.EncodeHeader(); err != nil {
+			return nil, err
+		}
+	}
+	if err := e.Encode(v); err != nil {
+		return nil, err
+	}
+	if opts.pretty {
+		return []byte(hex.Dump(buf.Bytes())), nil
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/hex"
+	"github.com/stretchr/testify/suite"
+	"github.com/zarken-go/igbinary"
+	"testing"
+)
+
+type ConvertSuite struct {
+	suite.Suite
+}
+
+func (Suite *ConvertSuite) sample() interface{} {
+	return igbinary.Array{
+		{Key: `id`, Value: int64(42)},
+		{Key: `name`, Value: "Jörg \"J\""},
+		{Key: `score`, Value: 2.0},
+		{Key: `tags`, Value: igbinary.Array{
+			{Key: int64(0), Value: `a`},
+			{Key: int64(1), Value: nil},
+		}},
+		{Key: int64(7), Value: igbinary.Array{}},
+		{Key: `user`, Value: &igbinary.Object{Class: `App\User`, Props: igbinary.Array{
+			{Key: `active`, Value: true},
+		}}},
+		{Key: `blob`, Value: &igbinary.SerializedObject{Class: `Blob`, Data: []byte("\x00\xff")}},
+	}
+}
+
+func (Suite *ConvertSuite) TestRoundTrip() {
+	for _, format := range []string{`igbinary`, `php`, `text`} {
+		b, err := encode(format, Suite.sample(), options{header: true})
+		if !Suite.Nil(err, format) {
+			continue
+		}
+		v, err := decode(format, b, options{header: true, strict: true})
+		if Suite.Nil(err, format) {
+			Suite.Equal(Suite.sample(), v, format)
+		}
+	}
+}
+
+func (Suite *ConvertSuite) TestText() {
+	b, err := encode(`text`, Suite.sample(), options{})
+	Suite.Nil(err)
+	Suite.Equal(`"id": 42
+"name": "Jörg \"J\""
+"score": 2.0
+"tags":
+  0: "a"
+  1: null
+7: []
+"user": !App\User
+  "active": true
+"blob": !Blob serialized "\x00\xff"
+`, string(b))
+
+	v, err := decode(`text`, []byte("!Foo\n  \"a\":\n    0: .inf\n"), options{})
+	Suite.Nil(err)
+	Suite.Equal(`!Foo
+  "a":
+    0: .inf
+`, string(Suite.mustEncode(`text`, v, options{})))
+
+	_, err = decode(`text`, []byte("\"a\": 1\n    \"b\": 2\n"), options{})
+	Suite.EqualError(err, `text: line 2: unexpected indentation`)
+}
+
+func (Suite *ConvertSuite) TestJSON() {
+	_, err := encode(`json`, Suite.sample(), options{strict: true})
+	Suite.EqualError(err, `object of class App\User cannot be represented in JSON`)
+
+	Suite.Equal(`{"id":42,"name":"Jörg \"J\"","score":2.0,"tags":["a",null],"7":[],`+
+		`"user":{"active":true},"blob":"\u0000`+"\ufffd"+`"}`+"\n",
+		string(Suite.mustEncode(`json`, Suite.sample(), options{})))
+
+	v, err := decode(`json`, []byte(`{"b":1,"a":[true,1.5],"3":"x"} trailing`), options{})
+	Suite.Nil(err)
+	Suite.Equal(igbinary.Array{
+		{Key: `b`, Value: int64(1)},
+		{Key: `a`, Value: igbinary.Array{{Key: int64(0), Value: true}, {Key: int64(1), Value: 1.5}}},
+		{Key: int64(3), Value: `x`},
+	}, v)
+
+	_, err = decode(`json`, []byte(`{"b":1} trailing`), options{strict: true})
+	Suite.EqualError(err, `trailing data at offset 7`)
+}
+
+func (Suite *ConvertSuite) TestIgbinaryHeader() {
+	b, err := convert(`json`, `igbinary`, []byte(`[1,"a"]`), options{header: false})
+	Suite.Nil(err)
+	Suite.Equal(`1402060006010601110161`, hex.EncodeToString(b))
+
+	b, err = convert(`igbinary`, `php`, b, options{header: true})
+	Suite.Nil(err)
+	Suite.Equal(`a:2:{i:0;i:1;i:1;s:1:"a";}`, string(b))
+
+	_, err = convert(`igbinary`, `php`, []byte{0x06, 0x01}, options{header: true, strict: true})
+	Suite.Error(err)
+}
+
+func (Suite *ConvertSuite) mustEncode(format string, v interface{}, opts options) []byte {
+	b, err := encode(format, v, opts)
+	Suite.Require().Nil(err)
+	return b
+}
+
+func TestConvertSuite(t *testing.T) {
+	suite.Run(t, new(ConvertSuite))
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/zarken-go/igbinary"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// decodeJSON reads a JSON document keeping the order of object members. As
+// with PHP's json_decode($data, true) objects become arrays.
+func decodeJSON(data []byte, opts options) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	v, err := readJSON(dec)
+	if err != nil {
+		return nil, err
+	}
+	if opts.strict {
+		if _, err := dec.Token(); err != io.EOF {
+			return nil, fmt.Errorf(`trailing data at offset %d`, dec.InputOffset())
+		}
+	}
+	return v, nil
+}
+
+func readJSON(dec *json.Decoder) (interface{}, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch t := tok.(type) {
+	case json.Delim:
+		arr := igbinary.Array{}
+		for i := int64(0); dec.More(); i++ {
+			var key interface{} = i
+			if t == '{' {
+				tok, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				key, _ = igbinary.ArrayKey(tok.(string))
+			}
+			v, err := readJSON(dec)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, igbinary.Entry{Key: key, Value: v})
+		}
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+		return arr, nil
+	case json.Number:
+		if n, err := t.Int64(); err == nil {
+			return n, nil
+		}
+		return t.Float64()
+	}
+	return tok, nil
+}
+
+func encodeJSON(v interface{}, opts options) ([]byte, error) {
+	w := &jsonWriter{strict: opts.strict, active: make(map[interface{}]bool)}
+	if err := w.value(v); err != nil {
+		return nil, err
+	}
+	if !opts.pretty {
+		w.buf.WriteByte('\n')
+		return w.buf.Bytes(), nil
+	}
+	var out bytes.Buffer
+	if err := json.Indent(&out, w.buf.Bytes(), ``, `  `); err != nil {
+		return nil, err
+	}
+	out.WriteByte('\n')
+	return out.Bytes(), nil
+}
+
+type jsonWriter struct {
+	buf    bytes.Buffer
+	strict bool
+	// active holds the objects being written, to break reference cycles.
+	active map[interface{}]bool
+}
+
+func (w *jsonWriter) lossy(format string, args ...interface{}) error {
+	if w.strict {
+		return fmt.Errorf(format+` cannot be represented in JSON`, args...)
+	}
+	w.buf.WriteString(`null`)
+	return nil
+}
+
+func (w *jsonWriter) string(s string) error {
+	if w.strict && !utf8.ValidString(s) {
+		return fmt.Errorf(`invalid UTF-8 string %q cannot be represented in JSON`, s)
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(s); err != nil {
+		return err
+	}
+	w.buf.Write(bytes.TrimRight(buf.Bytes(), "\n"))
+	return nil
+}
+
+func (w *jsonWriter) value(v interface{}) error {
+	switch v := v.(type) {
+	case nil:
+		w.buf.WriteString(`null`)
+	case bool:
+		w.buf.WriteString(strconv.FormatBool(v))
+	case int64:
+		w.buf.WriteString(strconv.FormatInt(v, 10))
+	case float64:
+		if math.IsInf(v, 0) || math.IsNaN(v) {
+			return w.lossy(`float %v`, v)
+		}
+		w.buf.WriteString(formatFloat(v))
+	case string:
+		return w.string(v)
+	case igbinary.Array:
+		if v.IsList() {
+			return w.list(v)
+		}
+		return w.object(v)
+	case *igbinary.Object:
+		if w.strict {
+			return w.lossy(`object of class %s`, v.Class)
+		}
+		if w.active[v] {
+			return w.lossy(`recursive object of class %s`, v.Class)
+		}
+		w.active[v] = true
+		defer delete(w.active, v)
+		return w.object(v.Props)
+	case *igbinary.SerializedObject:
+		if w.strict {
+			return w.lossy(`serialized object of class %s`, v.Class)
+		}
+		return w.string(string(v.Data))
+	default:
+		return fmt.Errorf(`unsupported type %T`, v)
+	}
+	return nil
+}
+
+func (w *jsonWriter) list(a igbinary.Array) error {
+	w.buf.WriteByte('[')
+	for i, e := range a {
+		if i > 0 {
+			w.buf.WriteByte(',')
+		}
+		if err := w.value(e.Value); err != nil {
+			return err
+		}
+	}
+	w.buf.WriteByte(']')
+	return nil
+}
+
+func (w *jsonWriter) object(a igbinary.Array) error {
+	w.buf.WriteByte('{')
+	for i, e := range a {
+		if i > 0 {
+			w.buf.WriteByte(',')
+		}
+		var err error
+		switch k := e.Key.(type) {
+		case int64:
+			err = w.string(strconv.FormatInt(k, 10))
+		case string:
+			err = w.string(k)
+		default:
+			err = fmt.Errorf(`unsupported array key %T`, k)
+		}
+		if err != nil {
+			return err
+		}
+		w.buf.WriteByte(':')
+		if err := w.value(e.Value); err != nil {
+			return err
+		}
+	}
+	w.buf.WriteByte('}')
+	return nil
+}
+
+// formatFloat formats f so that it reads back as a float rather than an
+// integer.
+func formatFloat(f float64) string {
+	s := strconv.FormatFloat(f, 'g', -1, 64)
+	if !strings.ContainsAny(s, `.e`) {
+		s += `.0`
+	}
+	return s
+}
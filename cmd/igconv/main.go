@@ -0,0 +1,75 @@
+// Command igconv converts values between igbinary, JSON, PHP serialize() and
+// a YAML-like text format. Conversions go through the generic value tree of
+// package igbinary, so igconv doubles as a conformance harness for the
+// library's Encoder and Decoder.
+//
+// Usage:
+//
+//	igconv [-from format] [-to format] [-header] [-pretty] [-strict] [-o file] [file]
+//
+// The formats are igbinary, json, php and text. Input is read from file or
+// from stdin when no file is given.
+//
+// In strict mode trailing input after the first value is an error, as are
+// conversions losing information, e.g. PHP objects or invalid UTF-8 strings
+// written as JSON. Lenient mode ignores trailing input, converts objects to
+// their properties, writes infinite and NaN floats and recursive objects as
+// null and replaces invalid UTF-8 in strings with U+FFFD.
+//
+// The text format writes one array entry per line as "key: value". Integer
+// keys are bare, string keys and string values are double quoted using Go
+// escapes. Nested arrays and objects continue on the following lines
+// indented by two spaces, objects carry their class as "!Class". Empty
+// arrays and objects are written as "[]" and "!Class {}", objects of classes
+// implementing Serializable as `!Class serialized "data"`.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+)
+
+func main() {
+	from := flag.String(`from`, `igbinary`, `input format: igbinary, json, php or text`)
+	to := flag.String(`to`, `json`, `output format: igbinary, json, php or text`)
+	header := flag.Bool(`header`, true, `read and write the igbinary version header`)
+	pretty := flag.Bool(`pretty`, false, `indent JSON output and hex dump igbinary output`)
+	strict := flag.Bool(`strict`, false, `reject trailing input and lossy conversions`)
+	output := flag.String(`o`, ``, `output file (default stdout)`)
+	flag.Parse()
+
+	if err := run(*from, *to, options{
+		header: *header,
+		pretty: *pretty,
+		strict: *strict,
+	}, flag.Arg(0), *output); err != nil {
+		fmt.Fprintln(os.Stderr, `igconv:`, err)
+		os.Exit(1)
+	}
+}
+
+func run(from, to string, opts options, input, output string) error {
+	var data []byte
+	var err error
+	if input == `` || input == `-` {
+		data, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		data, err = ioutil.ReadFile(input)
+	}
+	if err != nil {
+		return err
+	}
+
+	b, err := convert(from, to, data, opts)
+	if err != nil {
+		return err
+	}
+
+	if output == `` {
+		_, err = os.Stdout.Write(b)
+		return err
+	}
+	return ioutil.WriteFile(output, b, 0o644)
+}
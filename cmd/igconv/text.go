@@ -0,0 +1,308 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/zarken-go/igbinary"
+	"math"
+	"strconv"
+	"strings"
+)
+
+func encodeText(v interface{}, opts options) ([]byte, error) {
+	w := &textWriter{strict: opts.strict, active: make(map[interface{}]bool)}
+	if arr, ok := v.(igbinary.Array); ok && len(arr) > 0 {
+		if err := w.entries(arr, 0); err != nil {
+			return nil, err
+		}
+		return w.buf.Bytes(), nil
+	}
+	if err := w.value(v, 2); err != nil {
+		return nil, err
+	}
+	// value starts with the space separating it from its key.
+	return w.buf.Bytes()[1:], nil
+}
+
+type textWriter struct {
+	buf    bytes.Buffer
+	strict bool
+	// active holds the objects being written, to break reference cycles.
+	active map[interface{}]bool
+}
+
+func (w *textWriter) entries(a igbinary.Array, indent int) error {
+	for _, e := range a {
+		w.buf.WriteString(strings.Repeat(` `, indent))
+		switch k := e.Key.(type) {
+		case int64:
+			w.buf.WriteString(strconv.FormatInt(k, 10))
+		case string:
+			w.buf.WriteString(strconv.Quote(k))
+		default:
+			return fmt.Errorf(`unsupported array key %T`, k)
+		}
+		w.buf.WriteByte(':')
+		if err := w.value(e.Value, indent+2); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// value writes v following a key, nested entries are indented by indent.
+func (w *textWriter) value(v interface{}, indent int) error {
+	switch v := v.(type) {
+	case igbinary.Array:
+		if len(v) == 0 {
+			w.buf.WriteString(" []\n")
+			return nil
+		}
+		w.buf.WriteByte('\n')
+		return w.entries(v, indent)
+	case *igbinary.Object:
+		if w.active[v] {
+			if w.strict {
+				return fmt.Errorf(`recursive object of class %s cannot be represented as text`, v.Class)
+			}
+			w.buf.WriteString(" null\n")
+			return nil
+		}
+		w.active[v] = true
+		defer delete(w.active, v)
+		w.buf.WriteString(` !` + v.Class)
+		if len(v.Props) == 0 {
+			w.buf.WriteString(" {}\n")
+			return nil
+		}
+		w.buf.WriteByte('\n')
+		return w.entries(v.Props, indent)
+	case *igbinary.SerializedObject:
+		w.buf.WriteString(` !` + v.Class + ` serialized ` + strconv.Quote(string(v.Data)) + "\n")
+		return nil
+	}
+
+	s, err := formatScalar(v)
+	if err != nil {
+		return err
+	}
+	w.buf.WriteString(` ` + s + "\n")
+	return nil
+}
+
+func formatScalar(v interface{}) (string, error) {
+	switch v := v.(type) {
+	case nil:
+		return `null`, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case float64:
+		switch {
+		case math.IsInf(v, 1):
+			return `.inf`, nil
+		case math.IsInf(v, -1):
+			return `-.inf`, nil
+		case math.IsNaN(v):
+			return `.nan`, nil
+		}
+		return formatFloat(v), nil
+	case string:
+		return strconv.Quote(v), nil
+	}
+	return ``, fmt.Errorf(`unsupported type %T`, v)
+}
+
+type textLine struct {
+	num    int
+	indent int
+	text   string
+}
+
+type textParser struct {
+	lines []textLine
+	pos   int
+}
+
+func decodeText(data []byte) (interface{}, error) {
+	p := &textParser{}
+	for i, line := range strings.Split(string(data), "\n") {
+		text := strings.TrimLeft(line, ` `)
+		if text = strings.TrimRight(text, " \r"); text == `` || text[0] == '#' {
+			continue
+		}
+		p.lines = append(p.lines, textLine{
+			num:    i + 1,
+			indent: len(line) - len(strings.TrimLeft(line, ` `)),
+			text:   text,
+		})
+	}
+	if len(p.lines) == 0 {
+		return nil, fmt.Errorf(`text: empty input`)
+	}
+
+	first := p.lines[0]
+	if first.indent != 0 {
+		return nil, p.errorf(first, `unexpected indentation`)
+	}
+
+	var v interface{}
+	var err error
+	if _, _, ok := splitKey(first.text); ok {
+		v, err = p.entries(0)
+	} else {
+		p.pos++
+		v, err = p.value(first, first.text, 2)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.lines) {
+		return nil, p.errorf(p.lines[p.pos], `unexpected line`)
+	}
+	return v, nil
+}
+
+func (p *textParser) errorf(l textLine, format string, args ...interface{}) error {
+	return fmt.Errorf(`text: line %d: %s`, l.num, fmt.Sprintf(format, args...))
+}
+
+func (p *textParser) entries(indent int) (igbinary.Array, error) {
+	arr := igbinary.Array{}
+	for p.pos < len(p.lines) && p.lines[p.pos].indent == indent {
+		l := p.lines[p.pos]
+		key, rest, ok := splitKey(l.text)
+		if !ok {
+			return nil, p.errorf(l, `expected "key: value"`)
+		}
+		p.pos++
+		v, err := p.value(l, rest, indent+2)
+		if err != nil {
+			return nil, err
+		}
+		arr = append(arr, igbinary.Entry{Key: key, Value: v})
+	}
+	if p.pos < len(p.lines) && p.lines[p.pos].indent > indent {
+		return nil, p.errorf(p.lines[p.pos], `unexpected indentation`)
+	}
+	return arr, nil
+}
+
+// nested reports whether the next line holds an entry indented by indent.
+func (p *textParser) nested(indent int) bool {
+	return p.pos < len(p.lines) && p.lines[p.pos].indent == indent
+}
+
+// value parses the text following a key, nested entries are expected at
+// indent.
+func (p *textParser) value(l textLine, text string, indent int) (interface{}, error) {
+	text = strings.TrimSpace(text)
+	switch {
+	case text == ``:
+		if !p.nested(indent) {
+			return nil, p.errorf(l, `missing nested entries`)
+		}
+		return p.entries(indent)
+	case text == `[]`:
+		return igbinary.Array{}, nil
+	case text[0] == '!':
+		class, tail := text[1:], ``
+		if i := strings.IndexByte(class, ' '); i >= 0 {
+			class, tail = class[:i], strings.TrimSpace(class[i+1:])
+		}
+		switch {
+		case tail == `{}`:
+			return &igbinary.Object{Class: class, Props: igbinary.Array{}}, nil
+		case tail == ``:
+			obj := &igbinary.Object{Class: class, Props: igbinary.Array{}}
+			if p.nested(indent) {
+				props, err := p.entries(indent)
+				if err != nil {
+					return nil, err
+				}
+				obj.Props = props
+			}
+			return obj, nil
+		case strings.HasPrefix(tail, `serialized `):
+			data, err := strconv.Unquote(strings.TrimSpace(tail[len(`serialized `):]))
+			if err != nil {
+				return nil, p.errorf(l, `invalid serialized data`)
+			}
+			return &igbinary.SerializedObject{Class: class, Data: []byte(data)}, nil
+		}
+		return nil, p.errorf(l, `invalid object %q`, text)
+	}
+
+	v, err := parseScalar(text)
+	if err != nil {
+		return nil, p.errorf(l, `%s`, err)
+	}
+	return v, nil
+}
+
+func parseScalar(s string) (interface{}, error) {
+	switch s {
+	case `null`:
+		return nil, nil
+	case `true`:
+		return true, nil
+	case `false`:
+		return false, nil
+	case `.inf`:
+		return math.Inf(1), nil
+	case `-.inf`:
+		return math.Inf(-1), nil
+	case `.nan`:
+		return math.NaN(), nil
+	}
+	if s[0] == '"' {
+		return strconv.Unquote(s)
+	}
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return n, nil
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return f, nil
+	}
+	return nil, fmt.Errorf(`invalid value %q`, s)
+}
+
+// splitKey splits an entry line into its key and the text after the colon.
+func splitKey(text string) (interface{}, string, bool) {
+	if text[0] == '"' {
+		end := quotedLen(text)
+		if end < 0 || end >= len(text) || text[end] != ':' {
+			return nil, ``, false
+		}
+		key, err := strconv.Unquote(text[:end])
+		if err != nil {
+			return nil, ``, false
+		}
+		return key, text[end+1:], true
+	}
+
+	i := strings.IndexByte(text, ':')
+	if i < 0 {
+		return nil, ``, false
+	}
+	n, err := strconv.ParseInt(text[:i], 10, 64)
+	if err != nil {
+		return nil, ``, false
+	}
+	return n, text[i+1:], true
+}
+
+// quotedLen returns the length of the double quoted string at the start of
+// s, or -1 when it is not terminated.
+func quotedLen(s string) int {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1
+		}
+	}
+	return -1
+}
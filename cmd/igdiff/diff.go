@@ -0,0 +1,250 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/zarken-go/igbinary"
+	"math"
+	"strconv"
+	"strings"
+)
+
+type options struct {
+	ignoreOrder bool
+	tolerance   float64
+}
+
+// change is a single difference between two values. Path holds the array
+// keys and property names leading to it, as int64 or string.
+type change struct {
+	Op      string        `json:"op"`
+	Path    []interface{} `json:"path"`
+	Old     interface{}   `json:"old"`
+	New     interface{}   `json:"new"`
+	OldType string        `json:"oldType,omitempty"`
+	NewType string        `json:"newType,omitempty"`
+}
+
+const (
+	opAdded     = `added`
+	opRemoved   = `removed`
+	opChanged   = `changed`
+	opClass     = `class`
+	opReordered = `reordered`
+)
+
+type differ struct {
+	options
+	changes []change
+	// seen holds the pairs of objects already compared, objects reaching
+	// themselves through their properties are compared once.
+	seen map[[2]*igbinary.Object]bool
+}
+
+func diff(a, b interface{}, opts options) []change {
+	d := &differ{options: opts, seen: make(map[[2]*igbinary.Object]bool)}
+	d.diff(nil, a, b)
+	return d.changes
+}
+
+func (d *differ) add(op string, path []interface{}, a, b interface{}) {
+	c := change{Op: op, Path: append([]interface{}{}, path...), Old: a, New: b}
+	if op == opChanged || op == opRemoved {
+		c.OldType = typeName(a)
+	}
+	if op == opChanged || op == opAdded {
+		c.NewType = typeName(b)
+	}
+	d.changes = append(d.changes, c)
+}
+
+func (d *differ) diff(path []interface{}, a, b interface{}) {
+	if typeName(a) != typeName(b) {
+		d.add(opChanged, path, a, b)
+		return
+	}
+
+	switch a := a.(type) {
+	case igbinary.Array:
+		d.diffEntries(path, a, b.(igbinary.Array))
+	case *igbinary.Object:
+		b := b.(*igbinary.Object)
+		pair := [2]*igbinary.Object{a, b}
+		if d.seen[pair] {
+			return
+		}
+		d.seen[pair] = true
+		if a.Class != b.Class {
+			d.add(opClass, path, a.Class, b.Class)
+		}
+		d.diffEntries(path, a.Props, b.Props)
+	default:
+		opts := igbinary.EqualOptions{FloatTolerance: d.tolerance}
+		if !opts.EqualValues(a, b) {
+			d.add(opChanged, path, a, b)
+		}
+	}
+}
+
+func (d *differ) diffEntries(path []interface{}, a, b igbinary.Array) {
+	index := make(map[interface{}]int, len(b))
+	for i, e := range b {
+		index[normalizeKey(e.Key)] = i
+	}
+
+	var common []interface{}
+	for _, e := range a {
+		k := normalizeKey(e.Key)
+		i, ok := index[k]
+		if !ok {
+			d.add(opRemoved, append(path, k), e.Value, nil)
+			continue
+		}
+		common = append(common, k)
+		d.diff(append(path, k), e.Value, b[i].Value)
+	}
+
+	inA := make(map[interface{}]bool, len(a))
+	for _, e := range a {
+		inA[normalizeKey(e.Key)] = true
+	}
+	var order []interface{}
+	for _, e := range b {
+		k := normalizeKey(e.Key)
+		if !inA[k] {
+			d.add(opAdded, append(path, k), nil, e.Value)
+			continue
+		}
+		order = append(order, k)
+	}
+
+	if d.ignoreOrder {
+		return
+	}
+	for i := range common {
+		if common[i] != order[i] {
+			d.add(opReordered, path, nil, nil)
+			return
+		}
+	}
+}
+
+// normalizeKey converts numeric string keys to integers as PHP does.
+func normalizeKey(key interface{}) interface{} {
+	if s, ok := key.(string); ok {
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil && strconv.FormatInt(n, 10) == s {
+			return n
+		}
+	}
+	return key
+}
+
+func typeName(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return `null`
+	case bool:
+		return `bool`
+	case int64:
+		return `int`
+	case float64:
+		return `float`
+	case string:
+		return `string`
+	case igbinary.Array:
+		return `array`
+	case *igbinary.Object:
+		return `object`
+	case *igbinary.SerializedObject:
+		return `serialized`
+	default:
+		return fmt.Sprintf(`%T`, v)
+	}
+}
+
+// formatPath writes path as PHP array access on $, e.g. $["user"][0].
+func formatPath(path []interface{}) string {
+	var sb strings.Builder
+	sb.WriteString(`$`)
+	for _, k := range path {
+		if s, ok := k.(string); ok {
+			fmt.Fprintf(&sb, `[%s]`, strconv.Quote(s))
+		} else {
+			fmt.Fprintf(&sb, `[%v]`, k)
+		}
+	}
+	return sb.String()
+}
+
+// formatValue summarises v on one line. Arrays and objects are described by
+// their size and class only.
+func formatValue(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return `null`
+	case string:
+		return strconv.Quote(v)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case igbinary.Array:
+		return fmt.Sprintf(`array(%d)`, len(v))
+	case *igbinary.Object:
+		return fmt.Sprintf(`object(%s)`, v.Class)
+	case *igbinary.SerializedObject:
+		return fmt.Sprintf(`serialized(%s)`, v.Class)
+	}
+	return fmt.Sprint(v)
+}
+
+func formatText(changes []change) []byte {
+	var sb strings.Builder
+	for _, c := range changes {
+		path := formatPath(c.Path)
+		switch c.Op {
+		case opAdded:
+			fmt.Fprintf(&sb, "+ %s: %s\n", path, formatValue(c.New))
+		case opRemoved:
+			fmt.Fprintf(&sb, "- %s: %s\n", path, formatValue(c.Old))
+		case opClass:
+			fmt.Fprintf(&sb, "~ %s: class %s -> %s\n", path, c.Old, c.New)
+		case opReordered:
+			fmt.Fprintf(&sb, "~ %s: key order changed\n", path)
+		default:
+			fmt.Fprintf(&sb, "~ %s: %s -> %s", path, formatValue(c.Old), formatValue(c.New))
+			if c.OldType != c.NewType {
+				fmt.Fprintf(&sb, " (%s -> %s)", c.OldType, c.NewType)
+			}
+			sb.WriteString("\n")
+		}
+	}
+	return []byte(sb.String())
+}
+
+// formatJSON writes changes as a JSON array. Scalars keep their JSON form,
+// floats JSON cannot represent and arrays and objects are summarised as in
+// the text output.
+func formatJSON(changes []change) ([]byte, error) {
+	out := make([]change, len(changes))
+	for i, c := range changes {
+		c.Old, c.New = jsonValue(c.Old), jsonValue(c.New)
+		out[i] = c
+	}
+	b, err := json.MarshalIndent(out, ``, `  `)
+	if err != nil {
+		return nil, err
+	}
+	return append(b, '\n'), nil
+}
+
+func jsonValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case nil, bool, int64, string:
+		return v
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return formatValue(v)
+		}
+		return v
+	}
+	return formatValue(v)
+}
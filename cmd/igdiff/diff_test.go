@@ -0,0 +1,96 @@
+package main
+
+import (
+	"github.com/stretchr/testify/suite"
+	"github.com/zarken-go/igbinary"
+	"math"
+	"testing"
+)
+
+type DiffSuite struct {
+	suite.Suite
+}
+
+func (Suite *DiffSuite) documents() (interface{}, interface{}) {
+	a := igbinary.Array{
+		{Key: `user`, Value: &igbinary.Object{Class: `App\User`, Props: igbinary.Array{
+			{Key: `id`, Value: int64(42)},
+			{Key: `score`, Value: 1.5},
+		}}},
+		{Key: `cart`, Value: igbinary.Array{{Key: int64(0), Value: `a`}, {Key: int64(1), Value: `b`}}},
+		{Key: `old`, Value: true},
+	}
+	b := igbinary.Array{
+		{Key: `user`, Value: &igbinary.Object{Class: `App\Admin`, Props: igbinary.Array{
+			{Key: `id`, Value: `42`},
+			{Key: `score`, Value: 1.5000001},
+		}}},
+		{Key: `cart`, Value: igbinary.Array{{Key: `1`, Value: `b`}, {Key: int64(0), Value: `a`}}},
+		{Key: `new`, Value: igbinary.Array{}},
+	}
+	return a, b
+}
+
+func (Suite *DiffSuite) TestText() {
+	a, b := Suite.documents()
+	Suite.Equal(`~ $["user"]: class App\User -> App\Admin
+~ $["user"]["id"]: 42 -> "42" (int -> string)
+~ $["user"]["score"]: 1.5 -> 1.5000001
+~ $["cart"]: key order changed
+- $["old"]: true
++ $["new"]: array(0)
+`, string(formatText(diff(a, b, options{}))))
+
+	Suite.Equal(`~ $["user"]: class App\User -> App\Admin
+~ $["user"]["id"]: 42 -> "42" (int -> string)
+- $["old"]: true
++ $["new"]: array(0)
+`, string(formatText(diff(a, b, options{ignoreOrder: true, tolerance: 1e-6}))))
+
+	Suite.Empty(diff(a, a, options{}))
+}
+
+func (Suite *DiffSuite) TestJSON() {
+	b, err := formatJSON([]change{
+		{Op: opChanged, Path: []interface{}{`a`, int64(0)}, Old: math.Inf(1), New: igbinary.Array{},
+			OldType: `float`, NewType: `array`},
+		{Op: opRemoved, Path: []interface{}{`b`}, Old: false, OldType: `bool`},
+	})
+	Suite.Nil(err)
+	Suite.Equal(`[
+  {
+    "op": "changed",
+    "path": [
+      "a",
+      0
+    ],
+    "old": "+Inf",
+    "new": "array(0)",
+    "oldType": "float",
+    "newType": "array"
+  },
+  {
+    "op": "removed",
+    "path": [
+      "b"
+    ],
+    "old": false,
+    "new": null,
+    "oldType": "bool"
+  }
+]
+`, string(b))
+}
+
+func (Suite *DiffSuite) TestCycles() {
+	self := func(class string) *igbinary.Object {
+		obj := &igbinary.Object{Class: class}
+		obj.Props = igbinary.Array{{Key: `self`, Value: obj}}
+		return obj
+	}
+	Suite.Equal([]change{{Op: opClass, Path: []interface{}{}, Old: `A`, New: `B`}}, diff(self(`A`), self(`B`), options{}))
+}
+
+func TestDiffSuite(t *testing.T) {
+	suite.Run(t, new(DiffSuite))
+}
@@ -0,0 +1,100 @@
+// Command igdiff prints the structural differences between two igbinary
+// documents. Both are decoded into the generic value tree of package
+// igbinary, so differences in encoding alone, such as string IDs or integer
+// widths, are not reported.
+//
+// Usage:
+//
+//	igdiff [-json] [-ignore-order] [-tolerance f] old new
+//
+// Either file may be "-" for stdin. Each difference is printed on one line
+// with the path of the value as PHP array access on $:
+//
+//	$ igdiff old.igb new.igb
+//	+ $["flags"]["beta"]: true
+//	- $["cart"][3]: array(2)
+//	~ $["user"]["id"]: 42 -> "42" (int -> string)
+//	~ $["user"]: class App\User -> App\Admin
+//	~ $["tags"]: key order changed
+//
+// With -json the differences are written as a JSON array of objects with
+// the fields op (added, removed, changed, class or reordered), path, old,
+// new, oldType and newType.
+//
+// The exit status is 0 when the documents are equal, 1 when they differ and
+// 2 on errors.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/zarken-go/igbinary"
+	"io/ioutil"
+	"os"
+)
+
+func main() {
+	asJSON := flag.Bool(`json`, false, `write the differences as JSON`)
+	ignoreOrder := flag.Bool(`ignore-order`, false, `ignore the order of array keys`)
+	tolerance := flag.Float64(`tolerance`, 0, `largest difference at which floats are equal`)
+	flag.Parse()
+
+	if flag.NArg() != 2 {
+		fmt.Fprintln(os.Stderr, `usage: igdiff [-json] [-ignore-order] [-tolerance f] old new`)
+		os.Exit(2)
+	}
+
+	changes, err := run(flag.Arg(0), flag.Arg(1), options{
+		ignoreOrder: *ignoreOrder,
+		tolerance:   *tolerance,
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, `igdiff:`, err)
+		os.Exit(2)
+	}
+
+	out := formatText(changes)
+	if *asJSON {
+		if out, err = formatJSON(changes); err != nil {
+			fmt.Fprintln(os.Stderr, `igdiff:`, err)
+			os.Exit(2)
+		}
+	}
+	if _, err := os.Stdout.Write(out); err != nil {
+		os.Exit(2)
+	}
+	if len(changes) > 0 {
+		os.Exit(1)
+	}
+}
+
+func run(oldFile, newFile string, opts options) ([]change, error) {
+	a, err := load(oldFile)
+	if err != nil {
+		return nil, err
+	}
+	b, err := load(newFile)
+	if err != nil {
+		return nil, err
+	}
+	return diff(a, b, opts), nil
+}
+
+func load(file string) (interface{}, error) {
+	var data []byte
+	var err error
+	if file == `-` {
+		data, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		data, err = ioutil.ReadFile(file)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var v interface{}
+	if err := igbinary.Unmarshal(data, &v); err != nil {
+		return nil, fmt.Errorf(`%s: %s`, file, err)
+	}
+	return v, nil
+}
@@ -0,0 +1,32 @@
+package igbinary
+
+import "bytes"
+
+// Compact appends to dst the smallest equivalent encoding of the igbinary
+// stream src and returns the extended buffer. Integers, lengths and
+// references take their minimal width, every repeated string and class
+// name becomes a string ID and empty strings use StringEmpty. src may hold
+// several documents, their headers are kept.
+func Compact(dst, src []byte) ([]byte, error) {
+	buf := bytes.NewBuffer(dst)
+	d := NewDecoder(bytes.NewReader(src))
+	e := NewEncoder(buf)
+	for d.More() {
+		if d.peekHeader() {
+			if err := d.DecodeHeader(); err != nil {
+				return nil, err
+			}
+			e.resetTables()
+			if err := e.EncodeHeader(); err != nil {
+				return nil, err
+			}
+		}
+		err := copyValue(d, e, func(ID int) (int, error) {
+			return ID, nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
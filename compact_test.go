@@ -0,0 +1,42 @@
+package igbinary
+
+import (
+	"encoding/hex"
+	"github.com/stretchr/testify/suite"
+	"io"
+	"testing"
+)
+
+type CompactSuite struct {
+	suite.Suite
+}
+
+func (Suite *CompactSuite) TestCompact() {
+	tests := []struct {
+		src      string
+		expected string
+	}{
+		// [0 => 1, 1 => 'a', 2 => 'a', 3 => ''] with a 32-bit integer, a
+		// repeated literal string and an empty String8.
+		{`00000002140406000a000000010601110161060211016106031100`, `00000002140406000601060111016106020e0006030d`},
+		// [0 => new Foo(), 1 => $foo] with a 16-bit object reference.
+		{`00000002140206001703466f6f14000601230001`, `00000002140206001703466f6f140006012201`},
+		// Two documents each get a fresh string table.
+		{`00000002110161` + `00000002110161`, `00000002110161` + `00000002110161`},
+		{``, ``},
+	}
+	for _, test := range tests {
+		src, err := hex.DecodeString(test.src)
+		Suite.Require().Nil(err, test.src)
+		out, err := Compact([]byte{0xff}, src)
+		Suite.Nil(err, test.src)
+		Suite.Equal(`ff`+test.expected, hex.EncodeToString(out), test.src)
+	}
+
+	_, err := Compact(nil, []byte{0, 0, 0, 2, 0x14, 0x01})
+	Suite.Equal(io.EOF, err)
+}
+
+func TestCompactSuite(t *testing.T) {
+	suite.Run(t, new(CompactSuite))
+}
@@ -0,0 +1,188 @@
+package igbinary
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+)
+
+// Compressor is a compression format for NewCompressedEncoder and
+// NewCompressedDecoder. Implement it to plug in formats such as zstd, lz4
+// or FastLZ.
+type Compressor interface {
+	// Magic returns the bytes compressed data starts with, which
+	// NewCompressedDecoder detects the format by. Formats without a magic
+	// number return nil.
+	Magic() []byte
+	// NewWriter returns a writer compressing to w, closing it flushes the
+	// compressed data without closing w.
+	NewWriter(w io.Writer) (io.WriteCloser, error)
+	// NewReader returns a reader decompressing r.
+	NewReader(r io.Reader) (io.Reader, error)
+}
+
+// Compressors of the standard library. Zlib matches PHP's gzcompress, Gzip
+// gzencode and Flate gzdeflate.
+var (
+	Zlib  Compressor = zlibCompressor{}
+	Gzip  Compressor = gzipCompressor{}
+	Flate Compressor = flateCompressor{}
+)
+
+type zlibCompressor struct{}
+
+// Magic returns the first byte of zlib streams using deflate with a 32K
+// window, which all zlib writers produce.
+func (zlibCompressor) Magic() []byte {
+	return []byte{0x78}
+}
+
+func (zlibCompressor) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return zlib.NewWriter(w), nil
+}
+
+func (zlibCompressor) NewReader(r io.Reader) (io.Reader, error) {
+	return zlib.NewReader(r)
+}
+
+type gzipCompressor struct{}
+
+func (gzipCompressor) Magic() []byte {
+	return []byte{0x1f, 0x8b}
+}
+
+func (gzipCompressor) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return gzip.NewWriter(w), nil
+}
+
+func (gzipCompressor) NewReader(r io.Reader) (io.Reader, error) {
+	return gzip.NewReader(r)
+}
+
+type flateCompressor struct{}
+
+func (flateCompressor) Magic() []byte {
+	return nil
+}
+
+func (flateCompressor) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return flate.NewWriter(w, flate.DefaultCompression)
+}
+
+func (flateCompressor) NewReader(r io.Reader) (io.Reader, error) {
+	return flate.NewReader(r), nil
+}
+
+// CompressedEncoder is an Encoder whose output is compressed. Close must be
+// called once encoding is done.
+type CompressedEncoder struct {
+	*Encoder
+	w *compressWriter
+}
+
+// NewCompressedEncoder returns an encoder writing to w that compresses its
+// output with c once it reaches threshold bytes. Shorter output is written
+// uncompressed when the encoder is closed, so values too small to gain from
+// compression are stored as is.
+func NewCompressedEncoder(w io.Writer, c Compressor, threshold int) *CompressedEncoder {
+	cw := &compressWriter{w: w, c: c, threshold: threshold}
+	return &CompressedEncoder{Encoder: NewEncoder(cw), w: cw}
+}
+
+// Close writes the data held back below the threshold, or flushes the
+// compressor. It does not close the underlying writer.
+func (e *CompressedEncoder) Close() error {
+	return e.w.Close()
+}
+
+// compressWriter holds data back until threshold bytes are written, then
+// compresses them and everything after.
+type compressWriter struct {
+	w         io.Writer
+	c         Compressor
+	threshold int
+	buf       bytes.Buffer
+	// zw is set once the threshold is reached.
+	zw io.WriteCloser
+}
+
+func (w *compressWriter) Write(p []byte) (int, error) {
+	if w.zw != nil {
+		return w.zw.Write(p)
+	}
+	w.buf.Write(p)
+	if w.buf.Len() < w.threshold {
+		return len(p), nil
+	}
+	zw, err := w.c.NewWriter(w.w)
+	if err != nil {
+		return 0, err
+	}
+	w.zw = zw
+	if _, err := zw.Write(w.buf.Bytes()); err != nil {
+		return 0, err
+	}
+	w.buf.Reset()
+	return len(p), nil
+}
+
+func (w *compressWriter) Close() error {
+	if w.zw != nil {
+		return w.zw.Close()
+	}
+	_, err := w.w.Write(w.buf.Bytes())
+	w.buf.Reset()
+	return err
+}
+
+// NewCompressedDecoder returns a decoder reading r, which decompresses data
+// compressed with one of compressors. The format is detected by its magic
+// bytes, a compressor without them, such as Flate, reads data no other one
+// matches. Data starting with an igbinary header is read as is, as is any
+// data no compressor matches. With no compressors, Zlib and Gzip are
+// detected.
+func NewCompressedDecoder(r io.Reader, compressors ...Compressor) (*Decoder, error) {
+	if len(compressors) == 0 {
+		compressors = []Compressor{Zlib, Gzip}
+	}
+	n := len(headerBytes)
+	for _, c := range compressors {
+		if len(c.Magic()) > n {
+			n = len(c.Magic())
+		}
+	}
+	br := bufio.NewReader(r)
+	b, _ := br.Peek(n)
+	if HasHeader(b) {
+		return NewDecoder(br), nil
+	}
+
+	var fallback Compressor
+	for _, c := range compressors {
+		magic := c.Magic()
+		if len(magic) == 0 {
+			if fallback == nil {
+				fallback = c
+			}
+			continue
+		}
+		if bytes.HasPrefix(b, magic) {
+			return newDecompressingDecoder(br, c)
+		}
+	}
+	if fallback != nil && len(b) > 0 {
+		return newDecompressingDecoder(br, fallback)
+	}
+	return NewDecoder(br), nil
+}
+
+func newDecompressingDecoder(r io.Reader, c Compressor) (*Decoder, error) {
+	zr, err := c.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	return NewDecoder(zr), nil
+}
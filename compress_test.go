@@ -0,0 +1,162 @@
+package igbinary
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"github.com/stretchr/testify/suite"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+// reverseCompressor stands in for a pluggable format: its data is a magic
+// followed by the input reversed.
+type reverseCompressor struct{}
+
+func (reverseCompressor) Magic() []byte {
+	return []byte(`REV`)
+}
+
+func (reverseCompressor) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return &reverseWriter{w: w}, nil
+}
+
+func (reverseCompressor) NewReader(r io.Reader) (io.Reader, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.HasPrefix(b, []byte(`REV`)) {
+		return nil, errors.New(`missing magic`)
+	}
+	b = b[3:]
+	for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+		b[i], b[j] = b[j], b[i]
+	}
+	return bytes.NewReader(b), nil
+}
+
+type reverseWriter struct {
+	w   io.Writer
+	buf []byte
+}
+
+func (w *reverseWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	return len(p), nil
+}
+
+func (w *reverseWriter) Close() error {
+	b := append([]byte(`REV`), make([]byte, len(w.buf))...)
+	for i, c := range w.buf {
+		b[len(b)-1-i] = c
+	}
+	_, err := w.w.Write(b)
+	return err
+}
+
+type CompressSuite struct {
+	suite.Suite
+}
+
+func (Suite *CompressSuite) TestRoundTrip() {
+	Value := Array{{Key: `text`, Value: strings.Repeat(`igbinary `, 200)}}
+	for _, Compressor := range []Compressor{Zlib, Gzip, Flate, reverseCompressor{}} {
+		var buf bytes.Buffer
+		Encoder := NewCompressedEncoder(&buf, Compressor, 100)
+		Suite.Require().Nil(Encoder.EncodeHeader())
+		Suite.Require().Nil(Encoder.Encode(Value))
+		Suite.Require().Nil(Encoder.Close())
+		Suite.False(HasHeader(buf.Bytes()))
+		if Magic := Compressor.Magic(); Magic != nil {
+			Suite.True(bytes.HasPrefix(buf.Bytes(), Magic))
+		}
+
+		Decoder, err := NewCompressedDecoder(bytes.NewReader(buf.Bytes()), Zlib, Gzip, reverseCompressor{}, Flate)
+		Suite.Require().Nil(err)
+		var Decoded interface{}
+		Suite.Nil(Decoder.Decode(&Decoded))
+		Suite.Equal(Value, Decoded)
+	}
+}
+
+func (Suite *CompressSuite) TestThreshold() {
+	var buf bytes.Buffer
+	Encoder := NewCompressedEncoder(&buf, Zlib, 100)
+	Suite.Require().Nil(Encoder.EncodeHeader())
+	Suite.Require().Nil(Encoder.Encode(`short`))
+	Suite.Empty(buf.Bytes())
+	Suite.Require().Nil(Encoder.Close())
+
+	B, err := Marshal(`short`)
+	Suite.Require().Nil(err)
+	Suite.Equal(B, buf.Bytes())
+
+	Decoder, err := NewCompressedDecoder(bytes.NewReader(buf.Bytes()), Zlib)
+	Suite.Require().Nil(err)
+	var S string
+	Suite.Nil(Decoder.Decode(&S))
+	Suite.Equal(`short`, S)
+
+	// A threshold of 0 compresses everything.
+	buf.Reset()
+	Encoder = NewCompressedEncoder(&buf, Zlib, 0)
+	Suite.Require().Nil(Encoder.Encode(true))
+	Suite.Require().Nil(Encoder.Close())
+	Suite.Equal(byte(0x78), buf.Bytes()[0])
+}
+
+func (Suite *CompressSuite) TestDetection() {
+	// gzcompress(igbinary_serialize('php'))
+	var buf bytes.Buffer
+	w := zlib.NewWriter(&buf)
+	_, _ = w.Write([]byte{0, 0, 0, 2, 0x11, 0x03, 'p', 'h', 'p'})
+	Suite.Require().Nil(w.Close())
+
+	Decoder, err := NewCompressedDecoder(bytes.NewReader(buf.Bytes()))
+	Suite.Require().Nil(err)
+	var S string
+	Suite.Nil(Decoder.Decode(&S))
+	Suite.Equal(`php`, S)
+
+	// Data no compressor matches is read as is.
+	Decoder, err = NewCompressedDecoder(bytes.NewReader([]byte{0x11, 0x03, 'p', 'h', 'p'}), Gzip)
+	Suite.Require().Nil(err)
+	S = ``
+	Suite.Nil(Decoder.Decode(&S))
+	Suite.Equal(`php`, S)
+
+	_, err = NewCompressedDecoder(bytes.NewReader([]byte{0x1f, 0x8b, 0x00}))
+	Suite.NotNil(err)
+
+	Decoder, err = NewCompressedDecoder(bytes.NewReader(nil), Flate)
+	Suite.Require().Nil(err)
+	Suite.False(Decoder.More())
+}
+
+func (Suite *CompressSuite) TestDocuments() {
+	var buf bytes.Buffer
+	Encoder := NewCompressedEncoder(&buf, Gzip, 0)
+	Encoder.SetDocumentMode(true)
+	for i := 0; i < 3; i++ {
+		Suite.Require().Nil(Encoder.Encode(Array{{Key: `n`, Value: int64(i)}}))
+	}
+	Suite.Require().Nil(Encoder.Close())
+
+	Decoder, err := NewCompressedDecoder(&buf)
+	Suite.Require().Nil(err)
+	var Documents []interface{}
+	for Decoder.More() {
+		var Document interface{}
+		Suite.Require().Nil(Decoder.Decode(&Document))
+		Documents = append(Documents, Document)
+	}
+	Suite.Len(Documents, 3)
+	Suite.Equal(Array{{Key: `n`, Value: int64(2)}}, Documents[2])
+}
+
+func TestCompressSuite(t *testing.T) {
+	suite.Run(t, new(CompressSuite))
+}
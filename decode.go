@@ -8,11 +8,15 @@ import (
 	"github.com/zarken-go/igbinary/igcode"
 	"io"
 	"reflect"
+	"time"
 )
 
 const (
 	disallowUnknownFieldsFlag uint32 = 1 << iota
-	maxMapSize                       = 1e6
+	refPendingFlag
+	// decodingFlag is set while Decode decodes a top-level value, nested
+	// calls from Unmarshalers do not look for a header.
+	decodingFlag
 )
 
 type bufReader interface {
@@ -30,6 +34,9 @@ type Decoder struct {
 	rec []byte // accumulates read data if not nil
 
 	strings []string
+	refs    []interface{}
+	// depth is the number of arrays, objects and references being decoded.
+	depth int
 }
 
 func Unmarshal(data []byte, v interface{}) error {
@@ -54,8 +61,16 @@ func (d *Decoder) resetReader(r io.Reader) {
 	}
 }
 
+// Decode decodes the next value of the stream into v. A version header in
+// front of the value is consumed and starts a new document: the string and
+// reference tables of the previous one are discarded.
+//
 //nolint:gocyclo
 func (d *Decoder) Decode(v interface{}) error {
+	if d.flags&decodingFlag == 0 {
+		return d.decodeDocument(v)
+	}
+
 	var err error
 	switch v := v.(type) {
 	case *string:
@@ -117,39 +132,37 @@ func (d *Decoder) Decode(v interface{}) error {
 			*v, err = d.DecodeUint64()
 			return err
 		}
-	//case *bool:
-	//	if v != nil {
-	//		*v, err = d.DecodeBool()
-	//		return err
-	//	}
-	//case *float32:
-	//	if v != nil {
-	//		*v, err = d.DecodeFloat32()
-	//		return err
-	//	}
-	//case *float64:
-	//	if v != nil {
-	//		*v, err = d.DecodeFloat64()
-	//		return err
-	//	}
+	case *bool:
+		if v != nil {
+			*v, err = d.DecodeBool()
+			return err
+		}
+	case *float32:
+		if v != nil {
+			*v, err = d.DecodeFloat32()
+			return err
+		}
+	case *float64:
+		if v != nil {
+			*v, err = d.DecodeFloat64()
+			return err
+		}
 	//case *[]string:
 	//	return ErrUnsupported // d.decodeStringSlicePtr(v)
 	case *map[string]string:
 		return d.decodeMapStringStringPtr(v)
 		//case *map[string]interface{}:
 		//	return ErrUnsupported // d.decodeMapStringInterfacePtr(v)
-		//case *time.Duration:
-		//	if v != nil {
-		//		vv, err := d.DecodeInt64()
-		//		*v = time.Duration(vv)
-		//		return err
-		//	}
-		//case *time.Time:
-		//	return ErrUnsupported
-		//	/*if v != nil {
-		//		*v, err = d.DecodeTime()
-		//		return err
-		//	}*/
+	case *time.Duration:
+		if v != nil {
+			*v, err = d.DecodeDuration()
+			return err
+		}
+	case *time.Time:
+		if v != nil {
+			*v, err = d.DecodeTime()
+			return err
+		}
 	}
 
 	vv := reflect.ValueOf(v)
@@ -193,6 +206,96 @@ func (d *Decoder) DecodeNil() error {
 	return d.skipExpected(igcode.Nil)
 }
 
+func (d *Decoder) decodeDocument(v interface{}) error {
+	if d.peekHeader() {
+		if err := d.DecodeHeader(); err != nil {
+			return err
+		}
+	}
+	d.flags |= decodingFlag
+	err := d.Decode(v)
+	d.flags &= ^decodingFlag
+	return err
+}
+
+// More reports whether another value follows in the stream. Together with
+// Decode it reads a sequence of documents:
+//
+//	for d.More() {
+//		if err := d.Decode(&v); err != nil {
+//			return err
+//		}
+//	}
+func (d *Decoder) More() bool {
+	_, err := d.PeekCode()
+	return err == nil
+}
+
+// peekHeader reports whether the stream continues with a version header
+// without consuming it. Readers that cannot look ahead four bytes never
+// report a header, NewDecoder wraps all others in a bufio.Reader which can.
+func (d *Decoder) peekHeader() bool {
+	var b []byte
+	switch r := d.r.(type) {
+	case interface{ Peek(int) ([]byte, error) }:
+		b, _ = r.Peek(len(headerBytes))
+	case interface{ Bytes() []byte }:
+		b = r.Bytes()
+	case interface {
+		io.ReaderAt
+		Len() int
+		Size() int64
+	}:
+		b = make([]byte, len(headerBytes))
+		n, _ := r.ReadAt(b, r.Size()-int64(r.Len()))
+		b = b[:n]
+	}
+	return isHeader(b)
+}
+
+// HasHeader reports whether b starts with an igbinary version header, which
+// tells igbinary data apart from other serialization formats.
+func HasHeader(b []byte) bool {
+	return isHeader(b)
+}
+
+func isHeader(b []byte) bool {
+	return len(b) >= 4 && b[0] == 0x00 && b[1] == 0x00 && b[2] == 0x00 && (b[3] == 0x01 || b[3] == 0x02)
+}
+
+// DecodeHeader reads the igbinary version header written by
+// Encoder.EncodeHeader. Versions 1 and 2 are accepted. The header starts a
+// new document, so the string and reference tables are reset.
+func (d *Decoder) DecodeHeader() error {
+	if err := d.skipExpected(0x00, 0x00, 0x00); err != nil {
+		return err
+	}
+	c, err := d.s.ReadByte()
+	if err != nil {
+		return err
+	}
+	if c != 0x01 && c != 0x02 {
+		return decodeErrorF(`unsupported version %d`, c)
+	}
+	d.strings = d.strings[:0]
+	d.refs = d.refs[:0]
+	return nil
+}
+
+func (d *Decoder) DecodeBool() (bool, error) {
+	c, err := d.readCode()
+	if err != nil {
+		return false, err
+	}
+	switch c {
+	case igcode.BoolFalse:
+		return false, nil
+	case igcode.BoolTrue:
+		return true, nil
+	}
+	return false, decodeErrorF(`invalid code=%x decoding bool`, c)
+}
+
 func (d *Decoder) DecodeValue(v reflect.Value) error {
 	decode := getDecoder(v.Type())
 	if decode == nil {
@@ -201,6 +304,55 @@ func (d *Decoder) DecodeValue(v reflect.Value) error {
 	return decode(d, v)
 }
 
+// Skip skips the next value including all of its entries. The string and
+// reference tables are updated as if the value had been decoded.
+func (d *Decoder) Skip() error {
+	// pending holds the number of tokens left in each open array, object
+	// and reference, the last entry is the innermost.
+	pending := []int{1}
+	for len(pending) > 0 {
+		top := len(pending) - 1
+		if pending[top] == 0 {
+			pending = pending[:top]
+			continue
+		}
+		pending[top]--
+
+		t, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch t.Kind {
+		case ArrayStartToken, ObjectStartToken, NewRefToken:
+			if d.depth+len(pending) > maxDepth {
+				return errMaxDepth
+			}
+			if t.Kind == NewRefToken {
+				pending = append(pending, 1)
+			} else {
+				pending = append(pending, 2*t.Len)
+			}
+		}
+	}
+	return nil
+}
+
+var errMaxDepth = decodeErrorF(`exceeded max depth of %d`, maxDepth)
+
+// enter starts decoding an array, object or reference nested in the values
+// being decoded, every successful call must be paired with a call to leave.
+func (d *Decoder) enter() error {
+	if d.depth >= maxDepth {
+		return errMaxDepth
+	}
+	d.depth++
+	return nil
+}
+
+func (d *Decoder) leave() {
+	d.depth--
+}
+
 func (d *Decoder) skipExpected(expected ...byte) error {
 	for _, e := range expected {
 		c, err := d.s.ReadByte()
@@ -238,7 +390,49 @@ func (d *Decoder) readN(n int) ([]byte, error) {
 	return d.buf, nil
 }
 
+// addRef registers v as the next entry of the reference table, which holds
+// every array and object of the document in order of appearance. It returns
+// the index assigned to v.
+func (d *Decoder) addRef(v interface{}) int {
+	d.refs = append(d.refs, v)
+	return len(d.refs) - 1
+}
+
+// DecodeArrayLen decodes the header of an array and returns its number of
+// entries. The array is assigned the next slot of the reference table.
 func (d *Decoder) DecodeArrayLen() (int, error) {
+	n, err := d.arrayLen()
+	if err != nil {
+		return 0, err
+	}
+	d.addRef(nil)
+	return n, nil
+}
+
+// DecodeObjectLen decodes the header of an object and returns its class and
+// number of properties, which follow as key/value pairs. Arrays are
+// accepted as objects without a class, so structs decode from both.
+func (d *Decoder) DecodeObjectLen() (string, int, error) {
+	c, err := d.PeekCode()
+	if err != nil {
+		return ``, 0, err
+	}
+	if !igcode.IsObject(c) {
+		n, err := d.DecodeArrayLen()
+		return ``, n, err
+	}
+	class, err := d.className()
+	if err != nil {
+		return ``, 0, err
+	}
+	d.addRef(nil)
+	n, err := d.arrayLen()
+	return class, n, err
+}
+
+// arrayLen decodes an array header without assigning it a reference, as
+// used for object properties.
+func (d *Decoder) arrayLen() (int, error) {
 	c, err := d.readCode()
 	if err != nil {
 		return 0, err
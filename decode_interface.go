@@ -0,0 +1,258 @@
+package igbinary
+
+import (
+	"github.com/zarken-go/igbinary/igcode"
+	"reflect"
+)
+
+// DecodeInterface decodes any igbinary value without a Go destination type.
+// It returns one of nil, bool, int64, float64, string, Array, *Object or
+// *SerializedObject. Object references resolve to the same *Object.
+func (d *Decoder) DecodeInterface() (interface{}, error) {
+	c, err := d.PeekCode()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case c == igcode.Nil:
+		return nil, d.DecodeNil()
+	case c == igcode.BoolFalse, c == igcode.BoolTrue:
+		return d.DecodeBool()
+	case igcode.IsInteger(c):
+		return d.DecodeInt64()
+	case c == igcode.Double:
+		return d.DecodeFloat64()
+	case igcode.IsString(c):
+		return d.DecodeString()
+	case igcode.IsArray(c):
+		return d.decodeArray()
+	case igcode.IsObject(c):
+		return d.decodeObject()
+	case igcode.IsRef(c):
+		return d.decodeRef()
+	case c == igcode.SimpleRef:
+		return d.decodeSimpleRef()
+	}
+
+	return nil, decodeErrorF(`invalid code=%x decoding interface`, c)
+}
+
+func (d *Decoder) decodeArray() (Array, error) {
+	n, err := d.DecodeArrayLen()
+	if err != nil {
+		return nil, err
+	}
+	slot := len(d.refs) - 1
+	if err := d.enter(); err != nil {
+		return nil, err
+	}
+	defer d.leave()
+	arr, err := d.decodeEntries(n)
+	if err != nil {
+		return nil, err
+	}
+	d.refs[slot] = arr
+	return arr, nil
+}
+
+func (d *Decoder) decodeEntries(n int) (Array, error) {
+	arr := make(Array, 0, min(n, sliceAllocLimit))
+	for i := 0; i < n; i++ {
+		k, err := d.decodeKey()
+		if err != nil {
+			return nil, err
+		}
+		v, err := d.DecodeInterface()
+		if err != nil {
+			return nil, err
+		}
+		arr = append(arr, Entry{Key: k, Value: v})
+	}
+	return arr, nil
+}
+
+func (d *Decoder) decodeKey() (interface{}, error) {
+	c, err := d.PeekCode()
+	if err != nil {
+		return nil, err
+	}
+	if igcode.IsInteger(c) {
+		return d.DecodeInt64()
+	}
+	if igcode.IsString(c) {
+		return d.DecodeString()
+	}
+	return nil, decodeErrorF(`invalid code=%x decoding array key`, c)
+}
+
+func (d *Decoder) className() (string, error) {
+	c, err := d.readCode()
+	if err != nil {
+		return ``, err
+	}
+	switch c {
+	case igcode.Object8:
+		n, err := d.uint8()
+		if err != nil {
+			return ``, err
+		}
+		return d.stringWithLen(int(n))
+	case igcode.Object16:
+		n, err := d.uint16()
+		if err != nil {
+			return ``, err
+		}
+		return d.stringWithLen(int(n))
+	case igcode.Object32:
+		n, err := d.uint32()
+		if err != nil {
+			return ``, err
+		}
+		return d.stringWithLen(int(n))
+	case igcode.ObjectID8, igcode.ObjectID16, igcode.ObjectID32:
+		return d.stringByID(c - igcode.ObjectID8 + igcode.StringID8)
+	}
+	return ``, decodeErrorF(`invalid code=%x decoding class name`, c)
+}
+
+func (d *Decoder) decodeObject() (interface{}, error) {
+	class, err := d.className()
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := d.PeekCode()
+	if err != nil {
+		return nil, err
+	}
+	if igcode.IsObjectSer(c) {
+		obj := &SerializedObject{Class: class}
+		d.addRef(obj)
+		if obj.Data, err = d.serializedData(); err != nil {
+			return nil, err
+		}
+		return obj, nil
+	}
+
+	obj := &Object{Class: class}
+	d.addRef(obj)
+	if err := d.enter(); err != nil {
+		return nil, err
+	}
+	defer d.leave()
+	n, err := d.arrayLen()
+	if err != nil {
+		return nil, err
+	}
+	if obj.Props, err = d.decodeEntries(n); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+func (d *Decoder) serializedData() ([]byte, error) {
+	c, err := d.readCode()
+	if err != nil {
+		return nil, err
+	}
+	var n int
+	switch c {
+	case igcode.ObjectSer8:
+		v, err := d.uint8()
+		if err != nil {
+			return nil, err
+		}
+		n = int(v)
+	case igcode.ObjectSer16:
+		v, err := d.uint16()
+		if err != nil {
+			return nil, err
+		}
+		n = int(v)
+	case igcode.ObjectSer32:
+		v, err := d.uint32()
+		if err != nil {
+			return nil, err
+		}
+		n = int(v)
+	default:
+		return nil, decodeErrorF(`invalid code=%x decoding serialized object`, c)
+	}
+	b, err := d.readN(n)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(nil), b...), nil
+}
+
+func (d *Decoder) refID() (int, error) {
+	c, err := d.readCode()
+	if err != nil {
+		return 0, err
+	}
+	switch c {
+	case igcode.ArrayRef8, igcode.ObjectRef8:
+		n, err := d.uint8()
+		return int(n), err
+	case igcode.ArrayRef16, igcode.ObjectRef16:
+		n, err := d.uint16()
+		return int(n), err
+	case igcode.ArrayRef32, igcode.ObjectRef32:
+		n, err := d.uint32()
+		return int(n), err
+	}
+	return 0, decodeErrorF(`invalid code=%x decoding reference`, c)
+}
+
+func (d *Decoder) decodeRef() (interface{}, error) {
+	ID, err := d.refID()
+	if err != nil {
+		return nil, err
+	}
+	if ID < len(d.refs) {
+		return d.refs[ID], nil
+	}
+	return nil, decodeErrorF(`reference id %d not found`, ID)
+}
+
+// decodeSimpleRef decodes a value marked as a PHP reference (&$value). Arrays
+// and objects already own a slot in the reference table, scalars get one here.
+func (d *Decoder) decodeSimpleRef() (interface{}, error) {
+	if err := d.skipExpected(igcode.SimpleRef); err != nil {
+		return nil, err
+	}
+	if err := d.enter(); err != nil {
+		return nil, err
+	}
+	defer d.leave()
+	c, err := d.PeekCode()
+	if err != nil {
+		return nil, err
+	}
+	v, err := d.DecodeInterface()
+	if err != nil {
+		return nil, err
+	}
+	if !igcode.IsArray(c) && !igcode.IsObject(c) && !igcode.IsRef(c) {
+		d.addRef(v)
+	}
+	return v, nil
+}
+
+func decodeInterfaceValue(d *Decoder, v reflect.Value) error {
+	iface, err := d.DecodeInterface()
+	if err != nil {
+		return err
+	}
+	if iface == nil {
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	}
+	value := reflect.ValueOf(iface)
+	if !value.Type().AssignableTo(v.Type()) {
+		return decodeErrorF(`cannot assign %s to %s`, value.Type(), v.Type())
+	}
+	v.Set(value)
+	return nil
+}
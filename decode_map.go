@@ -6,16 +6,21 @@ var (
 	mapStringStringPtrType = reflect.TypeOf((*map[string]string)(nil))
 )
 
+// decodeMapValue decodes arrays and objects such as stdClass into maps.
 func decodeMapValue(d *Decoder, v reflect.Value) error {
-	n, err := d.DecodeArrayLen()
-	if err != nil {
+	typ := v.Type()
+	if d.hasNilCode() {
+		v.Set(reflect.Zero(typ))
+		return d.DecodeNil()
+	}
+	if err := d.enter(); err != nil {
 		return err
 	}
+	defer d.leave()
 
-	typ := v.Type()
-	if n == -1 {
-		v.Set(reflect.Zero(typ))
-		return nil
+	_, n, err := d.DecodeObjectLen()
+	if err != nil {
+		return err
 	}
 
 	if v.IsNil() {
@@ -55,18 +60,18 @@ func (d *Decoder) decodeTypedMapValue(v reflect.Value, n int) error {
 }
 
 func (d *Decoder) decodeMapStringStringPtr(ptr *map[string]string) error {
-	size, err := d.DecodeArrayLen()
+	if d.hasNilCode() {
+		*ptr = nil
+		return d.DecodeNil()
+	}
+	_, size, err := d.DecodeObjectLen()
 	if err != nil {
 		return err
 	}
-	if size == -1 {
-		*ptr = nil
-		return nil
-	}
 
 	m := *ptr
 	if m == nil {
-		*ptr = make(map[string]string, min(size, maxMapSize))
+		*ptr = make(map[string]string, min(size, sliceAllocLimit))
 		m = *ptr
 	}
 
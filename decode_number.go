@@ -2,6 +2,7 @@ package igbinary
 
 import (
 	"github.com/zarken-go/igbinary/igcode"
+	"math"
 	"reflect"
 )
 
@@ -133,6 +134,46 @@ func (d *Decoder) DecodeUint() (uint, error) {
 	return uint(value), nil
 }
 
+// DecodeFloat64 decodes a double. Integers are accepted as well since PHP
+// freely converts between the two.
+func (d *Decoder) DecodeFloat64() (float64, error) {
+	c, err := d.PeekCode()
+	if err != nil {
+		return 0, err
+	}
+	if igcode.IsInteger(c) {
+		n, err := d.DecodeInt64()
+		return float64(n), err
+	}
+	if _, err := d.readCode(); err != nil {
+		return 0, err
+	}
+	if c != igcode.Double {
+		return 0, decodeErrorF(`invalid code=%x decoding float`, c)
+	}
+	b, err := d.readN(8)
+	if err != nil {
+		return 0, err
+	}
+	n := (uint64(b[0]) << 56) |
+		(uint64(b[1]) << 48) |
+		(uint64(b[2]) << 40) |
+		(uint64(b[3]) << 32) |
+		(uint64(b[4]) << 24) |
+		(uint64(b[5]) << 16) |
+		(uint64(b[6]) << 8) |
+		uint64(b[7])
+	return math.Float64frombits(n), nil
+}
+
+func (d *Decoder) DecodeFloat32() (float32, error) {
+	value, err := d.DecodeFloat64()
+	if err != nil {
+		return 0, err
+	}
+	return float32(value), nil
+}
+
 func (d *Decoder) decodeSignedInt(limit uint64) (int64, error) {
 	code, value, err := d.readInteger()
 	if err != nil {
@@ -297,3 +338,21 @@ func decodeUintValue(d *Decoder, v reflect.Value) error {
 	v.SetUint(value)
 	return nil
 }
+
+func decodeFloat32Value(d *Decoder, v reflect.Value) error {
+	value, err := d.DecodeFloat32()
+	if err != nil {
+		return err
+	}
+	v.SetFloat(float64(value))
+	return nil
+}
+
+func decodeFloat64Value(d *Decoder, v reflect.Value) error {
+	value, err := d.DecodeFloat64()
+	if err != nil {
+		return err
+	}
+	v.SetFloat(value)
+	return nil
+}
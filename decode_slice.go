@@ -0,0 +1,83 @@
+package igbinary
+
+import "reflect"
+
+// decodeSliceValue decodes strings into byte slices and arrays into other
+// slices. Array keys are ignored, the values are taken in order as PHP's
+// array_values returns them.
+func decodeSliceValue(d *Decoder, v reflect.Value) error {
+	if d.hasNilCode() {
+		v.Set(reflect.Zero(v.Type()))
+		return d.DecodeNil()
+	}
+	if v.Type().Elem().Kind() == reflect.Uint8 {
+		return decodeBytesValue(d, v)
+	}
+	if err := d.enter(); err != nil {
+		return err
+	}
+	defer d.leave()
+
+	n, err := d.DecodeArrayLen()
+	if err != nil {
+		return err
+	}
+	if v.IsNil() || v.Cap() < min(n, sliceAllocLimit) {
+		v.Set(reflect.MakeSlice(v.Type(), 0, min(n, sliceAllocLimit)))
+	}
+	v.SetLen(0)
+
+	elem := reflect.New(v.Type().Elem()).Elem()
+	for i := 0; i < n; i++ {
+		if err := d.Skip(); err != nil {
+			return err
+		}
+		elem.Set(reflect.Zero(elem.Type()))
+		if err := d.DecodeValue(elem); err != nil {
+			return err
+		}
+		v.Set(reflect.Append(v, elem))
+	}
+	return nil
+}
+
+// decodeArrayValue decodes arrays into Go arrays like decodeSliceValue,
+// values beyond the length of the Go array are skipped and missing ones set
+// to zero.
+func decodeArrayValue(d *Decoder, v reflect.Value) error {
+	if err := d.enter(); err != nil {
+		return err
+	}
+	defer d.leave()
+	n, err := d.DecodeArrayLen()
+	if err != nil {
+		return err
+	}
+	for i := 0; i < n; i++ {
+		if err := d.Skip(); err != nil {
+			return err
+		}
+		if i >= v.Len() {
+			if err := d.Skip(); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := d.DecodeValue(v.Index(i)); err != nil {
+			return err
+		}
+	}
+	for i := n; i < v.Len(); i++ {
+		v.Index(i).Set(reflect.Zero(v.Type().Elem()))
+	}
+	return nil
+}
+
+func decodeBytesValue(d *Decoder, v reflect.Value) error {
+	s, err := d.DecodeString()
+	if err != nil {
+		return err
+	}
+	v.SetBytes([]byte(s))
+	return nil
+}
@@ -6,7 +6,11 @@ import (
 )
 
 func decodeStructValue(d *Decoder, v reflect.Value) error {
-	arrayLen, err := d.DecodeArrayLen()
+	if err := d.enter(); err != nil {
+		return err
+	}
+	defer d.leave()
+	_, arrayLen, err := d.DecodeObjectLen()
 	if err != nil {
 		return err
 	}
@@ -18,18 +22,35 @@ func decodeStructValue(d *Decoder, v reflect.Value) error {
 			return err
 		}
 
-		if f := fields.Map[name]; f != nil {
+		if f := fields.Lookup(name); f != nil {
 			if err := f.DecodeValue(d, v); err != nil {
 				return err
 			}
-		} else if d.flags&disallowUnknownFieldsFlag != 0 {
-			return fmt.Errorf("igbinary: unknown field %q", name)
-			/*} else if err := d.Skip(); err != nil {
-			return err*/
-		} else {
-			return decodeErrorF(`skipping: not supported %s`, name)
+		} else if err := d.SkipUnknownField(name); err != nil {
+			return err
 		}
 	}
 
 	return nil
 }
+
+// DecodeUnmarshaler decodes the next value with u.UnmarshalIgbinary,
+// counting it toward the maximum nesting depth as Decode does. It is meant
+// for Unmarshaler implementations such as those of igbinarygen.
+func (d *Decoder) DecodeUnmarshaler(u Unmarshaler) error {
+	if err := d.enter(); err != nil {
+		return err
+	}
+	defer d.leave()
+	return u.UnmarshalIgbinary(d)
+}
+
+// SkipUnknownField skips the value of an array key matching no field of the
+// destination struct, or fails when DisallowUnknownFields is set. It is
+// meant for Unmarshaler implementations such as those of igbinarygen.
+func (d *Decoder) SkipUnknownField(name string) error {
+	if d.flags&disallowUnknownFieldsFlag != 0 {
+		return fmt.Errorf("igbinary: unknown field %q", name)
+	}
+	return d.Skip()
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/hex"
 	"github.com/stretchr/testify/suite"
 	"github.com/zarken-go/igbinary/igcode"
+	"io"
 	"testing"
 )
 
@@ -224,6 +225,173 @@ func (Suite *DecodeSuite) TestDecodeArrayLen() {
 	Suite.Equal(100000, ArrayLen)
 }
 
+func (Suite *DecodeSuite) TestDecodeInterface() {
+	// [0 => true, 'k' => new Foo(a: 1.5), 'r' => $foo, 'a' => 'k']
+	B, err := hex.DecodeString(`140406000511016b1703466f6f14010e00` +
+		`0c3ff8000000000000110172220111016106ff`)
+	Suite.Nil(err)
+
+	var v interface{}
+	if Suite.Nil(Unmarshal(B, &v)) {
+		foo := &Object{Class: `Foo`, Props: Array{{Key: `k`, Value: 1.5}}}
+		Suite.Equal(Array{
+			{Key: int64(0), Value: true},
+			{Key: `k`, Value: foo},
+			{Key: `r`, Value: foo},
+			{Key: `a`, Value: int64(255)},
+		}, v)
+		arr := v.(Array)
+		Suite.Same(arr[1].Value, arr[2].Value)
+	}
+
+	var invalid interface{}
+	Suite.EqualError(Unmarshal([]byte{igcode.ObjectRef8, 0}, &invalid),
+		`igbinary: Decode(reference id 0 not found)`)
+	Suite.EqualError(Unmarshal([]byte{0x30}, &invalid),
+		`igbinary: Decode(invalid code=30 decoding interface)`)
+}
+
+func (Suite *DecodeSuite) TestDecodeHeader() {
+	Decoder := NewDecoder(bytes.NewReader([]byte{0, 0, 0, 2, igcode.BoolTrue}))
+	Suite.Nil(Decoder.DecodeHeader())
+	v, err := Decoder.DecodeBool()
+	Suite.Nil(err)
+	Suite.True(v)
+
+	Decoder = NewDecoder(bytes.NewReader([]byte{0, 0, 0, 3}))
+	Suite.EqualError(Decoder.DecodeHeader(), `igbinary: Decode(unsupported version 3)`)
+
+	Suite.True(HasHeader([]byte{0, 0, 0, 1}))
+	Suite.True(HasHeader([]byte{0, 0, 0, 2, igcode.Nil}))
+	Suite.False(HasHeader([]byte{0, 0, 0, 3}))
+	Suite.False(HasHeader([]byte(`a:0:{}`)))
+}
+
+func (Suite *DecodeSuite) TestArrayKey() {
+	for _, Test := range []struct {
+		Key      interface{}
+		Expected interface{}
+	}{
+		{7, int64(7)},
+		{uint32(7), int64(7)},
+		{`7`, int64(7)},
+		{`-7`, int64(-7)},
+		{`07`, `07`},
+		{`7.0`, `7.0`},
+		{`9223372036854775808`, `9223372036854775808`},
+	} {
+		Key, ok := ArrayKey(Test.Key)
+		Suite.True(ok)
+		Suite.Equal(Test.Expected, Key, Test.Key)
+	}
+	_, ok := ArrayKey(1.5)
+	Suite.False(ok)
+}
+
+func (Suite *DecodeSuite) TestDocuments() {
+	var buf bytes.Buffer
+	Encoder := NewEncoder(&buf)
+	Encoder.SetDocumentMode(true)
+	docs := []interface{}{
+		Array{{Key: `user`, Value: `alice`}},
+		Array{{Key: `action`, Value: `login`}, {Key: `user`, Value: `bob`}},
+		nil,
+		Array{{Key: `user`, Value: `user`}},
+	}
+	for _, doc := range docs {
+		Suite.Require().Nil(Encoder.Encode(doc))
+	}
+	B := buf.Bytes()
+	Suite.Equal(`0000000214011104757365721105616c696365`, hex.EncodeToString(B[:19]))
+
+	readers := map[string]func() io.Reader{
+		`bytes.Reader`: func() io.Reader { return bytes.NewReader(B) },
+		`bytes.Buffer`: func() io.Reader { return bytes.NewBuffer(B) },
+		`io.Reader`:    func() io.Reader { return io.MultiReader(bytes.NewReader(B)) },
+	}
+	for name, r := range readers {
+		Decoder := NewDecoder(r())
+		var decoded []interface{}
+		for Decoder.More() {
+			var v interface{}
+			Suite.Require().Nil(Decoder.Decode(&v), name)
+			decoded = append(decoded, v)
+		}
+		Suite.Equal(docs, decoded, name)
+	}
+
+	var s string
+	Suite.Nil(Unmarshal([]byte{0, 0, 0, 2, 0x11, 1, 'a'}, &s))
+	Suite.Equal(`a`, s)
+
+	// A headerless nil followed by a document.
+	Decoder := NewDecoder(bytes.NewReader([]byte{0, 0, 0, 0, 2, igcode.BoolTrue}))
+	var v, w interface{}
+	Suite.Nil(Decoder.Decode(&v))
+	Suite.Nil(v)
+	Suite.Nil(Decoder.Decode(&w))
+	Suite.Equal(true, w)
+	Suite.False(Decoder.More())
+}
+
+func (Suite *DecodeSuite) TestSkipUnknownField() {
+	// ['skip' => [1, ['a']], 'v' => 'a']
+	B, err := hex.DecodeString(`14021104736b69701402060006010601140106001101611101760e01`)
+	Suite.Nil(err)
+
+	container := struct {
+		V string `igbinary:"v"`
+	}{}
+	Suite.Nil(Unmarshal(B, &container))
+	Suite.Equal(`a`, container.V)
+
+	Decoder := NewDecoder(bytes.NewReader(B))
+	Decoder.DisallowUnknownFields(true)
+	Suite.EqualError(Decoder.Decode(&container), `igbinary: unknown field "skip"`)
+}
+
+type testNested []testNested
+
+type testNode struct {
+	Next *testNode `igbinary:"next"`
+}
+
+func (Suite *DecodeSuite) TestMaxDepth() {
+	// nested returns n nested arrays [[...[null]]], n objects each holding
+	// the next in 'next' and n references &&...&null.
+	nested := func(n int) [][]byte {
+		Arrays := append(bytes.Repeat([]byte("\x14\x01\x06\x00"), n), igcode.Nil)
+		Objects := append([]byte("\x17\x04Node\x14\x01\x11\x04next"), bytes.Repeat([]byte("\x1a\x00\x14\x01\x0e\x01"), n-1)...)
+		References := bytes.Repeat([]byte{igcode.SimpleRef}, n)
+		return [][]byte{Arrays, append(Objects, igcode.Nil), append(References, igcode.Nil)}
+	}
+
+	var Slice testNested
+	var Node testNode
+	for _, Data := range nested(maxDepth) {
+		Suite.Nil(Valid(Data))
+		Suite.Nil(NewDecoder(bytes.NewReader(Data)).Skip())
+		// The depth drops back once a value is decoded.
+		Decoder := NewDecoder(bytes.NewReader(append(Data, Data...)))
+		var First, Second interface{}
+		Suite.Nil(Decoder.Decode(&First))
+		Suite.Nil(Decoder.Decode(&Second))
+	}
+	Tests := nested(maxDepth)
+	Suite.Nil(Unmarshal(Tests[0], &Slice))
+	Suite.Nil(Unmarshal(Tests[1], &Node))
+
+	Tests = nested(maxDepth + 1)
+	for _, Data := range Tests {
+		var Value interface{}
+		Suite.EqualError(Unmarshal(Data, &Value), `igbinary: Decode(exceeded max depth of 10000)`)
+		Suite.EqualError(Valid(Data), `igbinary: Decode(exceeded max depth of 10000)`)
+		Suite.EqualError(NewDecoder(bytes.NewReader(Data)).Skip(), `igbinary: Decode(exceeded max depth of 10000)`)
+	}
+	Suite.EqualError(Unmarshal(Tests[0], &Slice), `igbinary: Decode(exceeded max depth of 10000)`)
+	Suite.EqualError(Unmarshal(Tests[1], &Node), `igbinary: Decode(exceeded max depth of 10000)`)
+}
+
 func TestDecodeSuite(t *testing.T) {
 	suite.Run(t, new(DecodeSuite))
 }
@@ -26,13 +26,33 @@ func _getDecoder(typ reflect.Type) decoderFunc {
 		}
 	}
 
+	if typ.Implements(unmarshalerType) {
+		return unmarshalValue
+	}
+	if kind != reflect.Ptr && reflect.PtrTo(typ).Implements(unmarshalerType) {
+		return unmarshalValueAddr
+	}
+	if typ.Implements(enumUnmarshalerType) {
+		return decodeEnumValue
+	}
+	if kind != reflect.Ptr && reflect.PtrTo(typ).Implements(enumUnmarshalerType) {
+		return decodeEnumValueAddr
+	}
+
+	switch typ {
+	case timeType:
+		return decodeTimeValue
+	case durationType:
+		return decodeDurationValue
+	}
+	if isTime(typ) {
+		return decodeTimeValue
+	}
+
 	/*
 		if typ.Implements(customDecoderType) {
 			return decodeCustomValue
 		}
-		if typ.Implements(unmarshalerType) {
-			return unmarshalValue
-		}
 		if typ.Implements(binaryUnmarshalerType) {
 			return unmarshalBinaryValue
 		}
@@ -46,9 +66,6 @@ func _getDecoder(typ reflect.Type) decoderFunc {
 			if ptr.Implements(customDecoderType) {
 				return decodeCustomValueAddr
 			}
-			if ptr.Implements(unmarshalerType) {
-				return unmarshalValueAddr
-			}
 			if ptr.Implements(binaryUnmarshalerType) {
 				return unmarshalBinaryValueAddr
 			}
@@ -108,7 +125,7 @@ var valueDecoders []decoderFunc
 //nolint:gochecknoinits
 func init() {
 	valueDecoders = []decoderFunc{
-		reflect.Bool:          decodeUnsupportedValue, // decodeBoolValue,
+		reflect.Bool:          decodeBoolValue,
 		reflect.Int:           decodeIntValue,
 		reflect.Int8:          decodeInt8Value,
 		reflect.Int16:         decodeInt16Value,
@@ -119,23 +136,54 @@ func init() {
 		reflect.Uint16:        decodeUint16Value,
 		reflect.Uint32:        decodeUint32Value,
 		reflect.Uint64:        decodeUint64Value,
-		reflect.Float32:       decodeUnsupportedValue, // decodeFloat32Value,
-		reflect.Float64:       decodeUnsupportedValue, // decodeFloat64Value,
+		reflect.Float32:       decodeFloat32Value,
+		reflect.Float64:       decodeFloat64Value,
 		reflect.Complex64:     decodeUnsupportedValue,
 		reflect.Complex128:    decodeUnsupportedValue,
-		reflect.Array:         decodeUnsupportedValue, //   decodeArrayValue,
+		reflect.Array:         decodeArrayValue,
 		reflect.Chan:          decodeUnsupportedValue,
 		reflect.Func:          decodeUnsupportedValue,
-		reflect.Interface:     decodeUnsupportedValue, //decodeInterfaceValue,
+		reflect.Interface:     decodeInterfaceValue,
 		reflect.Map:           decodeMapValue,
 		reflect.Ptr:           decodeUnsupportedValue,
-		reflect.Slice:         decodeUnsupportedValue, // decodeSliceValue,
+		reflect.Slice:         decodeSliceValue,
 		reflect.String:        decodeStringValue,
 		reflect.Struct:        decodeStructValue,
 		reflect.UnsafePointer: decodeUnsupportedValue,
 	}
 }
 
+func unmarshalValue(d *Decoder, v reflect.Value) error {
+	if v.Kind() == reflect.Ptr {
+		if d.hasNilCode() {
+			if !v.IsNil() {
+				v.Set(reflect.Zero(v.Type()))
+			}
+			return d.DecodeNil()
+		}
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+	}
+	return d.DecodeUnmarshaler(v.Interface().(Unmarshaler))
+}
+
+func unmarshalValueAddr(d *Decoder, v reflect.Value) error {
+	if !v.CanAddr() {
+		return decodeErrorF(`nonaddressable %s`, v.Type())
+	}
+	return unmarshalValue(d, v.Addr())
+}
+
 func decodeUnsupportedValue(_ *Decoder, v reflect.Value) error {
 	return decodeErrorF(`unsupported %s`, v.Type())
 }
+
+func decodeBoolValue(d *Decoder, v reflect.Value) error {
+	value, err := d.DecodeBool()
+	if err != nil {
+		return err
+	}
+	v.SetBool(value)
+	return nil
+}
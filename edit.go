@@ -0,0 +1,352 @@
+package igbinary
+
+import (
+	"bytes"
+	"github.com/zarken-go/igbinary/igcode"
+)
+
+// Set returns a copy of the igbinary document data with the value at path
+// replaced by value. Missing keys at the end of the path are appended to
+// their array, creating nested arrays as needed. Like Get, each path element
+// selects the first entry with that key. Everything else is copied byte for
+// byte, only string and reference IDs shifted by the edit are renumbered,
+// keeping their width when the new ID fits.
+func Set(data []byte, path []interface{}, value interface{}) ([]byte, error) {
+	return edit(data, path, false, value)
+}
+
+// Delete returns a copy of the igbinary document data without the entry at
+// path, copying everything else as Set does. References to the removed
+// value elsewhere in the document are an error.
+func Delete(data []byte, path ...interface{}) ([]byte, error) {
+	if len(path) == 0 {
+		return nil, decodeErrorF(`cannot delete the root value`)
+	}
+	return edit(data, path, true, nil)
+}
+
+type editor struct {
+	data []byte
+	r    *bytes.Reader
+	d    *Decoder
+	e    *Encoder
+
+	path []interface{}
+	// matched is the number of path elements present in the input.
+	matched int
+	del     bool
+	value   interface{}
+
+	// refs and strs map the reference and string tables of the input to
+	// those of the output, removed entries map to -1. refSlot and strSlot
+	// are the sizes of the input tables and start the offset of the input
+	// before the last read.
+	refs, strs       []int
+	refSlot, strSlot int
+	start            int
+}
+
+func edit(data []byte, path []interface{}, del bool, value interface{}) ([]byte, error) {
+	keys := make([]interface{}, len(path))
+	for i, elem := range path {
+		k, ok := arrayKey(elem)
+		if !ok {
+			return nil, decodeErrorF(`unsupported path element %T`, elem)
+		}
+		keys[i] = k
+	}
+	matched, err := NewDecoder(bytes.NewReader(data)).find(keys)
+	if err != nil {
+		return nil, err
+	}
+	if del && matched < len(keys) {
+		return nil, ErrNotFound
+	}
+
+	var buf bytes.Buffer
+	r := bytes.NewReader(data)
+	ed := &editor{
+		data:    data,
+		r:       r,
+		d:       NewDecoder(r),
+		e:       NewEncoder(&buf),
+		path:    keys,
+		matched: matched,
+		del:     del,
+		value:   value,
+	}
+	if isHeader(data) {
+		buf.Write(data[:4])
+		if err := ed.d.DecodeHeader(); err != nil {
+			return nil, err
+		}
+	}
+	if err := ed.edit(0); err != nil {
+		return nil, err
+	}
+	if ed.d.More() {
+		return nil, decodeErrorF(`trailing data after value`)
+	}
+	return buf.Bytes(), nil
+}
+
+// edit copies the value at path[:depth], applying the edit below it.
+func (ed *editor) edit(depth int) error {
+	if depth == len(ed.path) {
+		if err := ed.skip(); err != nil {
+			return err
+		}
+		return ed.e.Encode(ed.value)
+	}
+
+	t, err := ed.read()
+	for err == nil && t.Kind == NewRefToken {
+		if err = ed.write(t); err == nil {
+			t, err = ed.read()
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if t.Kind != ArrayStartToken && t.Kind != ObjectStartToken {
+		return decodeErrorF(`path %v: %s is not an array`, ed.path[:depth], t.Kind)
+	}
+
+	n := t.Len
+	appending := depth == ed.matched
+	removing := ed.del && depth == len(ed.path)-1
+	switch {
+	case appending:
+		err = ed.writeLen(t, n+1)
+	case removing:
+		err = ed.writeLen(t, n-1)
+	default:
+		err = ed.write(t)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Only the first entry with the key is edited, later ones are copied.
+	found := appending
+	for i := 0; i < n; i++ {
+		key, err := ed.read()
+		if err != nil {
+			return err
+		}
+		if k, _ := arrayKey(key.value()); found || k != ed.path[depth] {
+			if err := ed.write(key); err != nil {
+				return err
+			}
+			if err := ed.copy(); err != nil {
+				return err
+			}
+			continue
+		}
+		found = true
+		if removing {
+			ed.drop()
+			if err := ed.skip(); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := ed.write(key); err != nil {
+			return err
+		}
+		if err := ed.edit(depth + 1); err != nil {
+			return err
+		}
+	}
+
+	if appending {
+		v := ed.value
+		for i := len(ed.path) - 1; i > depth; i-- {
+			v = Array{{Key: ed.path[i], Value: v}}
+		}
+		if err := ed.e.encodeKey(ed.path[depth]); err != nil {
+			return err
+		}
+		return ed.e.Encode(v)
+	}
+	return nil
+}
+
+// copy copies the next value unchanged.
+func (ed *editor) copy() error {
+	for n := 1; n > 0; n-- {
+		t, err := ed.read()
+		if err != nil {
+			return err
+		}
+		switch t.Kind {
+		case ArrayStartToken, ObjectStartToken:
+			n += 2 * t.Len
+		case NewRefToken:
+			n++
+		}
+		if err := ed.write(t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// read reads the next token.
+func (ed *editor) read() (Token, error) {
+	ed.start = ed.offset()
+	ed.refSlot, ed.strSlot = len(ed.d.refs), len(ed.d.strings)
+	return ed.d.Token()
+}
+
+// offset returns the offset of the Decoder in the input.
+func (ed *editor) offset() int {
+	return len(ed.data) - ed.r.Len()
+}
+
+// write copies the token returned by the last read.
+func (ed *editor) write(t Token) error {
+	return ed.writeLen(t, t.Len)
+}
+
+// writeLen copies the token returned by the last read, an array or object
+// header with n entries, renumbering the string and reference IDs it holds,
+// and maps the table entries it took in the input to those it takes in the
+// output.
+func (ed *editor) writeLen(t Token, n int) error {
+	raw := ed.data[ed.start:ed.offset()]
+	var err error
+	switch t.Kind {
+	case StringToken:
+		err = ed.writeString(raw, igcode.StringID8)
+	case ObjectStartToken, SerializedObjectToken:
+		class := classLen(raw)
+		if err = ed.writeString(raw[:class], igcode.ObjectID8); err == nil {
+			err = ed.writeArrayLen(raw[class:], t.Len, n)
+		}
+	case ArrayStartToken:
+		err = ed.writeArrayLen(raw, t.Len, n)
+	case RefToken, ObjectRefToken:
+		if t.Ref >= len(ed.refs) || ed.refs[t.Ref] < 0 {
+			return decodeErrorF(`reference id %d points to a removed value`, t.Ref)
+		}
+		code8 := igcode.ArrayRef8
+		if t.Kind == ObjectRefToken {
+			code8 = igcode.ObjectRef8
+		}
+		err = ed.writeID(raw, code8, t.Ref, ed.refs[t.Ref])
+	default:
+		err = ed.e.write(raw)
+	}
+	if err != nil {
+		return err
+	}
+
+	for i := ed.refSlot; i < len(ed.d.refs); i++ {
+		ed.refs = append(ed.refs, int(ed.e.refID))
+		ed.e.refID++
+	}
+	for i := ed.strSlot; i < len(ed.d.strings); i++ {
+		str := ed.d.strings[i]
+		if _, ok := ed.e.strings[str]; !ok {
+			if ed.e.strings == nil {
+				ed.e.strings = make(map[string]uint)
+			}
+			ed.e.strings[str] = ed.e.stringID
+		}
+		ed.strs = append(ed.strs, int(ed.e.stringID))
+		ed.e.stringID++
+	}
+	return nil
+}
+
+// writeString copies the string or class name raw, renumbering its string
+// ID, or writing it in full when the string it points to was removed.
+func (ed *editor) writeString(raw []byte, code8 byte) error {
+	if raw[0] < code8 || raw[0] > code8+2 {
+		return ed.e.write(raw)
+	}
+	ID := int(rawUint(raw[1:]))
+	if ed.strs[ID] >= 0 {
+		return ed.writeID(raw, code8, ID, ed.strs[ID])
+	}
+
+	stringID := ed.e.stringID
+	var err error
+	if code8 == igcode.ObjectID8 {
+		err = ed.e.encodeClassName(ed.d.strings[ID])
+	} else {
+		err = ed.e.EncodeString(ed.d.strings[ID])
+	}
+	if ed.e.stringID > stringID {
+		ed.strs[ID] = int(stringID)
+	}
+	return err
+}
+
+// writeArrayLen copies the array length raw, rewriting it when it changes
+// from n to length.
+func (ed *editor) writeArrayLen(raw []byte, n, length int) error {
+	if length == n {
+		return ed.e.write(raw)
+	}
+	return ed.writeID(raw, igcode.Array8, n, length)
+}
+
+// writeID copies raw, the code of an ID or length followed by its value
+// from. A different value to is written in the width of raw when it fits
+// and in the next width holding it otherwise. code8 is the 8-bit variant of
+// the code.
+func (ed *editor) writeID(raw []byte, code8 byte, from, to int) error {
+	if from == to {
+		return ed.e.write(raw)
+	}
+	switch width := raw[0] - code8; {
+	case width == 0 && to <= 0xff:
+		return ed.e.write1(code8, uint8(to))
+	case width <= 1 && to <= 0xffff:
+		return ed.e.write2(code8+1, uint16(to))
+	}
+	return ed.e.write4(code8+2, uint32(to))
+}
+
+// drop marks the table entries taken by the token returned by the last read
+// as removed.
+func (ed *editor) drop() {
+	for len(ed.refs) < len(ed.d.refs) {
+		ed.refs = append(ed.refs, -1)
+	}
+	for len(ed.strs) < len(ed.d.strings) {
+		ed.strs = append(ed.strs, -1)
+	}
+}
+
+// skip skips the next value, marking its table entries as removed.
+func (ed *editor) skip() error {
+	if err := ed.d.Skip(); err != nil {
+		return err
+	}
+	ed.drop()
+	return nil
+}
+
+// classLen returns the length of the class name at the start of the raw
+// object token.
+func classLen(raw []byte) int {
+	switch c := raw[0]; c {
+	case igcode.Object8, igcode.Object16, igcode.Object32:
+		n := 1 << (c - igcode.Object8)
+		return 1 + n + int(rawUint(raw[1:1+n]))
+	default:
+		return 1 + 1<<(c-igcode.ObjectID8)
+	}
+}
+
+// rawUint returns the big-endian unsigned integer b.
+func rawUint(b []byte) uint64 {
+	var n uint64
+	for _, c := range b {
+		n = n<<8 | uint64(c)
+	}
+	return n
+}
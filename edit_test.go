@@ -0,0 +1,130 @@
+package igbinary
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/suite"
+	"testing"
+)
+
+type EditSuite struct {
+	suite.Suite
+}
+
+func (Suite *EditSuite) marshal(v interface{}) []byte {
+	B, err := Marshal(v)
+	Suite.Require().Nil(err)
+	return B
+}
+
+func (Suite *EditSuite) session(admin interface{}, extra ...Entry) Array {
+	user := &Object{Class: `User`, Props: Array{{Key: `id`, Value: 1}, {Key: `name`, Value: `alice`}}}
+	flags := Array{{Key: `admin`, Value: admin}, {Key: `name`, Value: `flags`}}
+	return Array{
+		{Key: `user`, Value: user},
+		{Key: `flags`, Value: append(flags, extra...)},
+		{Key: `again`, Value: user},
+	}
+}
+
+func (Suite *EditSuite) TestSet() {
+	B := Suite.marshal(Suite.session(true))
+
+	out, err := Set(B, []interface{}{`flags`, `admin`}, false)
+	Suite.Nil(err)
+	Suite.Equal(Suite.marshal(Suite.session(false)), out)
+
+	out, err = Set(B, []interface{}{`flags`, `admin`}, `alice`)
+	Suite.Nil(err)
+	Suite.Equal(Suite.marshal(Suite.session(`alice`)), out)
+
+	out, err = Set(B, []interface{}{`flags`, `new`, 5}, `x`)
+	Suite.Nil(err)
+	Suite.Equal(Suite.marshal(Suite.session(true, Entry{Key: `new`, Value: Array{{Key: int64(5), Value: `x`}}})), out)
+
+	out, err = Set(B, nil, 1)
+	Suite.Nil(err)
+	Suite.Equal(Suite.marshal(1), out)
+
+	_, err = Set(B, []interface{}{`flags`, `admin`, `x`}, 1)
+	Suite.EqualError(err, `igbinary: Decode(path [flags admin]: Bool is not an array)`)
+}
+
+func (Suite *EditSuite) TestDelete() {
+	B := Suite.marshal(Suite.session(true))
+
+	// Removing the first 'name' renumbers the string IDs of the rest.
+	out, err := Delete(B, `user`, `name`)
+	Suite.Nil(err)
+	expected := Suite.session(true)
+	expected[0].Value.(*Object).Props = expected[0].Value.(*Object).Props[:1]
+	Suite.Equal(Suite.marshal(expected), out)
+
+	_, err = Delete(B, `user`)
+	Suite.EqualError(err, `igbinary: Decode(reference id 1 points to a removed value)`)
+	_, err = Delete(B, `flags`, `missing`)
+	Suite.Equal(ErrNotFound, err)
+	_, err = Delete(B)
+	Suite.EqualError(err, `igbinary: Decode(cannot delete the root value)`)
+
+	// Removing an object renumbers the references to later ones.
+	a, b := &Object{Class: `A`}, &Object{Class: `B`}
+	out, err = Delete(Suite.marshal(Array{{Key: `a`, Value: a}, {Key: `b`, Value: b}, {Key: `c`, Value: b}}), `a`)
+	Suite.Nil(err)
+	Suite.Equal(Suite.marshal(Array{{Key: `b`, Value: b}, {Key: `c`, Value: b}}), out)
+}
+
+func (Suite *EditSuite) TestPreservesLiteralStrings() {
+	encode := func(v interface{}) []byte {
+		var buf bytes.Buffer
+		Encoder := NewEncoder(&buf)
+		Encoder.SetStringDedup(DedupNone)
+		Suite.Require().Nil(Encoder.EncodeHeader())
+		Suite.Require().Nil(Encoder.Encode(v))
+		return buf.Bytes()
+	}
+
+	out, err := Set(encode(Suite.session(true)), []interface{}{`flags`, `admin`}, false)
+	Suite.Nil(err)
+	Suite.Equal(encode(Suite.session(false)), out)
+}
+
+func (Suite *EditSuite) TestPreservesEncoding() {
+	// ['a' => 1 as PosInt32, 'b' => 'x' as String16, 'c' => [] as Array16]
+	B := []byte("\x14\x03" + "\x11\x01a\x0a\x00\x00\x00\x01" + "\x11\x01b\x12\x00\x01x" + "\x11\x01c\x15\x00\x00")
+	out, err := Set(B, []interface{}{`b`}, `y`)
+	Suite.Nil(err)
+	Suite.Equal(string(B[:13])+"\x11\x01y"+string(B[17:]), string(out))
+
+	// ['a' => 1, 'b' => 'x', 'c' => 'x' as StringID16 2]
+	B = []byte("\x14\x03" + "\x11\x01a\x06\x01" + "\x11\x01b\x11\x01x" + "\x11\x01c\x0f\x00\x02")
+	out, err = Delete(B, `a`)
+	Suite.Nil(err)
+	Suite.Equal("\x14\x02"+string(B[7:16])+"\x0f\x00\x01", string(out))
+
+	// A 16-bit array length stays 16-bit.
+	B = []byte("\x15\x00\x01\x11\x01a\x06\x01")
+	out, err = Set(B, []interface{}{`b`}, 2)
+	Suite.Nil(err)
+	Suite.Equal("\x15\x00\x02"+string(B[3:])+"\x11\x01b\x06\x02", string(out))
+}
+
+func (Suite *EditSuite) TestDuplicateKeys() {
+	// ['a' => &null, '000' => &null, 'a' => reference to the second null]
+	B := []byte("\x14\x03\x11\x01a%\x00\x11\x03000%\x00\x11\x01a\"\x02")
+	Suite.Require().Nil(Valid(B))
+
+	// Only the first 'a' is removed, the reference shifts to slot 1.
+	out, err := Delete(B, `a`)
+	Suite.Nil(err)
+	Suite.Nil(Valid(out))
+	Suite.Equal("\x14\x02\x11\x03000%\x00\x11\x01a\"\x01", string(out))
+
+	B = Suite.marshal(Array{{Key: `a`, Value: 1}, {Key: `a`, Value: 2}})
+	out, err = Set(B, []interface{}{`a`}, 3)
+	Suite.Nil(err)
+	Suite.Equal(Suite.marshal(Array{{Key: `a`, Value: 3}, {Key: `a`, Value: 2}}), out)
+}
+
+func TestEditSuite(t *testing.T) {
+	suite.Run(t, new(EditSuite))
+}
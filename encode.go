@@ -2,12 +2,12 @@ package igbinary
 
 import (
 	"bytes"
-	"errors"
 	"fmt"
 	"github.com/zarken-go/igbinary/igcode"
 	"io"
 	"math"
 	"reflect"
+	"time"
 )
 
 var headerBytes = []byte{0x00, 0x00, 0x00, 0x02}
@@ -61,6 +61,25 @@ type Encoder struct {
 	buf      []byte
 	strings  map[string]uint
 	stringID uint
+	objects  map[interface{}]uint
+	refID    uint
+	// refPending is set after WriteToken wrote a NewRefToken.
+	refPending bool
+	// tokens holds the number of keys and values still to be written by
+	// WriteToken for each array and object it opened.
+	tokens []int
+	// documents is set by SetDocumentMode, encoding while Encode encodes a
+	// top-level value.
+	documents bool
+	encoding  bool
+	// dedup, dedupMaxLen and maxStrings restrict which strings are stored
+	// in the string table.
+	dedup       StringDedup
+	dedupMaxLen int
+	maxStrings  int
+	sortMapKeys bool
+	// mapsAsStdClass is set by SetMapsAsStdClass.
+	mapsAsStdClass bool
 }
 
 // NewEncoder returns a new encoder that writes to w.
@@ -84,7 +103,38 @@ func (e *Encoder) EncodeHeader() error {
 	return e.write(headerBytes)
 }
 
+// SetDocumentMode makes every call to Encode write an independent document:
+// a version header followed by the value, encoded with fresh string and
+// reference tables. A Decoder reads such a stream document by document.
+func (e *Encoder) SetDocumentMode(on bool) {
+	e.documents = on
+}
+
+// SetSortMapKeys makes the Encoder write the entries of Go maps in
+// canonical order: integer keys before string keys, integers in numeric
+// order and strings bytewise.
+//
+// With sorted map keys the encoding is canonical: equal values always
+// encode to identical bytes, given the same string table options. Integers,
+// lengths, string IDs and references always take the smallest width that
+// holds them, struct fields follow their declaration order and Array
+// entries keep their order.
+func (e *Encoder) SetSortMapKeys(on bool) {
+	e.sortMapKeys = on
+}
+
+// SetMapsAsStdClass makes the Encoder write Go maps with string keys, such
+// as map[string]interface{}, as stdClass objects rather than arrays, the
+// way json_decode returns JSON objects. Maps decode from both.
+func (e *Encoder) SetMapsAsStdClass(on bool) {
+	e.mapsAsStdClass = on
+}
+
 func (e *Encoder) Encode(v interface{}) error {
+	if e.documents && !e.encoding {
+		return e.encodeDocument(v)
+	}
+
 	switch v := v.(type) {
 	case nil:
 		return e.EncodeNil()
@@ -101,26 +151,60 @@ func (e *Encoder) Encode(v interface{}) error {
 	case int32:
 		return e.EncodeInt64(int64(v))
 	case int64:
-	//	return e.EncodeInt64(v)
-	//case uint:
-	//	return e.EncodeUint(uint64(v))
-	//case uint64:
-	//	return e.encodeUint64Cond(v)
+		return e.EncodeInt64(v)
+	case uint:
+		return e.EncodeUint64(uint64(v))
+	case uint8:
+		return e.EncodeUint64(uint64(v))
+	case uint16:
+		return e.EncodeUint64(uint64(v))
+	case uint32:
+		return e.EncodeUint64(uint64(v))
+	case uint64:
+		return e.EncodeUint64(v)
 	case bool:
 		return e.EncodeBool(v)
-	//case float32:
-	//	return e.EncodeFloat64(float64(v))
+	case float32:
+		return e.EncodeFloat64(float64(v))
 	case float64:
 		return e.EncodeFloat64(v)
-		//	case time.Duration:
-		//		return e.encodeInt64Cond(int64(v))
-		//	case time.Time:
-		//		return e.EncodeTime(v)
+	case time.Duration:
+		return e.EncodeDuration(v)
+	case time.Time:
+		return e.EncodeTime(v)
+	case Array:
+		return e.EncodeArray(v)
+	case *Object:
+		return e.EncodeObject(v)
+	case *SerializedObject:
+		return e.EncodeSerializedObject(v)
 	}
 
-	return fmt.Errorf("igbinary: Encode(unsupported %s)", reflect.TypeOf(v))
+	return e.EncodeValue(reflect.ValueOf(v))
+}
 
-	// return e.EncodeValue(reflect.ValueOf(v))
+func (e *Encoder) encodeDocument(v interface{}) error {
+	e.resetTables()
+	if err := e.EncodeHeader(); err != nil {
+		return err
+	}
+	e.encoding = true
+	err := e.Encode(v)
+	e.encoding = false
+	return err
+}
+
+// resetTables empties the string and reference tables for a new document.
+func (e *Encoder) resetTables() {
+	e.strings = nil
+	e.stringID = 0
+	e.objects = nil
+	e.refID = 0
+}
+
+func (e *Encoder) EncodeValue(v reflect.Value) error {
+	fn := getEncoder(v.Type())
+	return fn(e, v)
 }
 
 func (e *Encoder) EncodeNil() error {
@@ -147,65 +231,23 @@ func (e *Encoder) EncodeInt64(v int64) error {
 		return e.write4(igcode.PosInt32, uint32(v))
 	} else if v < -0xffff && v >= -0xffffffff {
 		return e.write4(igcode.NegInt32, uint32(v*-1))
+	} else if v > 0 {
+		return e.write8(igcode.PosInt64, uint64(v))
 	}
+	return e.write8(igcode.NegInt64, uint64(-v))
+}
 
-	return errors.New(`igbinary: Encode(int out of range)`)
+func (e *Encoder) EncodeUint64(v uint64) error {
+	if v <= int64max {
+		return e.EncodeInt64(int64(v))
+	}
+	return e.write8(igcode.PosInt64, v)
 }
 
 func (e *Encoder) EncodeFloat64(v float64) error {
 	return e.write8(igcode.Double, math.Float64bits(v))
 }
 
-func (e *Encoder) EncodeString(v string) error {
-	return e.EncodeBytes([]byte(v))
-}
-
-func (e *Encoder) EncodeBytes(v []byte) error {
-	// TODO: unnecessary re-conversion back to string.
-	str := string(v)
-	if e.strings == nil {
-		e.strings = make(map[string]uint)
-	}
-	if ID, ok := e.strings[str]; ok {
-		// Encode as ID
-		if ID <= 0xff {
-			return e.write1(igcode.StringID8, uint8(ID))
-		}
-		if ID <= 0xffff {
-			return e.write2(igcode.StringID16, uint16(ID))
-		}
-		if ID <= 0xffffffff {
-			return e.write4(igcode.StringID32, uint32(ID))
-		}
-		return fmt.Errorf(`igbinary: Encode(string ID exceeds range)`)
-	}
-
-	e.strings[str] = e.stringID
-	e.stringID++
-
-	length := len(v)
-	if length <= 0xff {
-		if err := e.write1(igcode.String8, uint8(length)); err != nil {
-			return err
-		}
-		return e.write(v)
-	}
-	if length <= 0xffff {
-		if err := e.write2(igcode.String16, uint16(length)); err != nil {
-			return err
-		}
-		return e.write(v)
-	}
-	if length <= 0xffffffff {
-		if err := e.write4(igcode.String32, uint32(length)); err != nil {
-			return err
-		}
-		return e.write(v)
-	}
-
-	return errors.New(`igbinary: Encode([]byte exceeds capacity)`)
-}
-
 func (e *Encoder) write1(code byte, n uint8) error {
 	e.buf = e.buf[:2]
 	e.buf[0] = code
@@ -255,7 +297,26 @@ func (e *Encoder) writeBytes(b ...byte) error {
 	return err
 }
 
+// EncodeArrayLen encodes the header of an array of length entries. The
+// array is assigned the next slot of the reference table.
 func (e *Encoder) EncodeArrayLen(length int) error {
+	e.refID++
+	return e.arrayLen(length)
+}
+
+// EncodeObjectLen encodes the header of an object of class holding length
+// properties, which follow as key/value pairs like array entries.
+func (e *Encoder) EncodeObjectLen(class string, length int) error {
+	e.refID++
+	if err := e.encodeClassName(class); err != nil {
+		return err
+	}
+	return e.arrayLen(length)
+}
+
+// arrayLen encodes an array header without assigning it a reference, as
+// used for object properties.
+func (e *Encoder) arrayLen(length int) error {
 	if length <= 0xff {
 		return e.write1(igcode.Array8, uint8(length))
 	}
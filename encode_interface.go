@@ -0,0 +1,152 @@
+package igbinary
+
+import (
+	"fmt"
+	"github.com/zarken-go/igbinary/igcode"
+)
+
+// EncodeArray encodes a as a PHP array, keeping the order of its entries.
+func (e *Encoder) EncodeArray(a Array) error {
+	if err := e.EncodeArrayLen(len(a)); err != nil {
+		return err
+	}
+	return e.encodeEntries(a)
+}
+
+func (e *Encoder) encodeEntries(a Array) error {
+	for _, entry := range a {
+		if err := e.encodeKey(entry.Key); err != nil {
+			return err
+		}
+		if err := e.Encode(entry.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *Encoder) encodeKey(key interface{}) error {
+	switch k := key.(type) {
+	case int64:
+		return e.EncodeInt64(k)
+	case int:
+		return e.EncodeInt64(int64(k))
+	case string:
+		return e.EncodeStringKey(k)
+	}
+	return fmt.Errorf("igbinary: Encode(unsupported array key %T)", key)
+}
+
+// EncodeObject encodes o as a PHP object. An *Object encoded more than once
+// by the same Encoder is written as a reference to its first occurrence.
+func (e *Encoder) EncodeObject(o *Object) error {
+	if ok, err := e.encodeObjectRef(o); ok || err != nil {
+		return err
+	}
+	if err := e.encodeClassName(o.Class); err != nil {
+		return err
+	}
+	if err := e.arrayLen(len(o.Props)); err != nil {
+		return err
+	}
+	return e.encodeEntries(o.Props)
+}
+
+// EncodeSerializedObject encodes o as an object of a class implementing
+// PHP's Serializable interface.
+func (e *Encoder) EncodeSerializedObject(o *SerializedObject) error {
+	if ok, err := e.encodeObjectRef(o); ok || err != nil {
+		return err
+	}
+	if err := e.encodeClassName(o.Class); err != nil {
+		return err
+	}
+	return e.encodeSerializedData(o.Data)
+}
+
+func (e *Encoder) encodeSerializedData(data []byte) error {
+	length := len(data)
+	if length <= 0xff {
+		if err := e.write1(igcode.ObjectSer8, uint8(length)); err != nil {
+			return err
+		}
+		return e.write(data)
+	}
+	if length <= 0xffff {
+		if err := e.write2(igcode.ObjectSer16, uint16(length)); err != nil {
+			return err
+		}
+		return e.write(data)
+	}
+	if length <= 0xffffffff {
+		if err := e.write4(igcode.ObjectSer32, uint32(length)); err != nil {
+			return err
+		}
+		return e.write(data)
+	}
+
+	return fmt.Errorf(`igbinary: Encode(serialized object exceeds capacity)`)
+}
+
+// encodeObjectRef writes a reference when obj has been encoded before and
+// otherwise assigns obj the next slot of the reference table.
+func (e *Encoder) encodeObjectRef(obj interface{}) (bool, error) {
+	if e.objects == nil {
+		e.objects = make(map[interface{}]uint)
+	}
+	if ID, ok := e.objects[obj]; ok {
+		return true, e.encodeRef(igcode.ObjectRef8, int(ID))
+	}
+	e.objects[obj] = e.refID
+	e.refID++
+	return false, nil
+}
+
+// encodeRef writes a reference to entry ID of the reference table using
+// code8 or its 16 and 32-bit variants.
+func (e *Encoder) encodeRef(code8 byte, ID int) error {
+	if ID <= 0xff {
+		return e.write1(code8, uint8(ID))
+	}
+	if ID <= 0xffff {
+		return e.write2(code8+1, uint16(ID))
+	}
+	return e.write4(code8+2, uint32(ID))
+}
+
+func (e *Encoder) encodeClassName(name string) error {
+	if ID, ok := e.internString(name, true); ok {
+		if ID <= 0xff {
+			return e.write1(igcode.ObjectID8, uint8(ID))
+		}
+		if ID <= 0xffff {
+			return e.write2(igcode.ObjectID16, uint16(ID))
+		}
+		if ID <= 0xffffffff {
+			return e.write4(igcode.ObjectID32, uint32(ID))
+		}
+		return fmt.Errorf(`igbinary: Encode(string ID exceeds range)`)
+	}
+
+	length := len(name)
+	if length <= 0xff {
+		if err := e.write1(igcode.Object8, uint8(length)); err != nil {
+			return err
+		}
+		return e.write([]byte(name))
+	}
+	if length <= 0xffff {
+		if err := e.write2(igcode.Object16, uint16(length)); err != nil {
+			return err
+		}
+		return e.write([]byte(name))
+	}
+	if length <= 0xffffffff {
+		if err := e.write4(igcode.Object32, uint32(length)); err != nil {
+			return err
+		}
+		return e.write([]byte(name))
+	}
+
+	return fmt.Errorf(`igbinary: Encode(class name exceeds capacity)`)
+}
@@ -0,0 +1,97 @@
+package igbinary
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+func encodeMapValue(e *Encoder, v reflect.Value) error {
+	if v.IsNil() {
+		return e.EncodeNil()
+	}
+	var err error
+	if e.mapsAsStdClass && v.Type().Key().Kind() == reflect.String {
+		err = e.EncodeObjectLen(StdClass, v.Len())
+	} else {
+		err = e.EncodeArrayLen(v.Len())
+	}
+	if err != nil {
+		return err
+	}
+
+	keys := v.MapKeys()
+	if e.sortMapKeys {
+		sort.Slice(keys, func(i, j int) bool {
+			return mapKeyLess(keys[i], keys[j])
+		})
+	}
+	for _, k := range keys {
+		if err := e.encodeMapKey(k); err != nil {
+			return err
+		}
+		if err := e.EncodeValue(v.MapIndex(k)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *Encoder) encodeMapKey(k reflect.Value) error {
+	if k.Kind() == reflect.Interface {
+		k = k.Elem()
+	}
+	switch k.Kind() {
+	case reflect.String:
+		return e.EncodeStringKey(k.String())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return e.EncodeInt64(k.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return e.EncodeUint64(k.Uint())
+	}
+	return fmt.Errorf("igbinary: Encode(unsupported map key %s)", k.Type())
+}
+
+// mapKeyLess orders map keys canonically: integer keys before string keys,
+// integers numerically and strings bytewise.
+func mapKeyLess(a, b reflect.Value) bool {
+	if a.Kind() == reflect.Interface {
+		a = a.Elem()
+	}
+	if b.Kind() == reflect.Interface {
+		b = b.Elem()
+	}
+	aString, bString := a.Kind() == reflect.String, b.Kind() == reflect.String
+	if aString != bString {
+		return bString
+	}
+	if aString {
+		return a.String() < b.String()
+	}
+	aNeg, bNeg := isNegative(a), isNegative(b)
+	if aNeg != bNeg {
+		return aNeg
+	}
+	if aNeg {
+		return a.Int() < b.Int()
+	}
+	return uintValue(a) < uintValue(b)
+}
+
+func isNegative(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() < 0
+	}
+	return false
+}
+
+func uintValue(v reflect.Value) uint64 {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return uint64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return v.Uint()
+	}
+	return 0
+}
@@ -0,0 +1,126 @@
+package igbinary
+
+import (
+	"errors"
+	"fmt"
+	"github.com/zarken-go/igbinary/igcode"
+)
+
+// StringDedup selects which strings an Encoder deduplicates through the
+// string table.
+type StringDedup uint8
+
+const (
+	// DedupAll writes every repeated string as a reference to its first
+	// occurrence. This is the default.
+	DedupAll StringDedup = iota
+	// DedupKeys deduplicates array keys, property names and class names
+	// only, like PHP's igbinary with igbinary.compact_strings disabled.
+	DedupKeys
+	// DedupNone writes every string in full.
+	DedupNone
+)
+
+// SetStringDedup selects which strings are deduplicated.
+func (e *Encoder) SetStringDedup(mode StringDedup) {
+	e.dedup = mode
+}
+
+// SetStringDedupMaxLen excludes strings longer than n bytes from
+// deduplication, large one-off values then never enter the string table.
+// Zero removes the limit.
+func (e *Encoder) SetStringDedupMaxLen(n int) {
+	e.dedupMaxLen = n
+}
+
+// SetStringTableLimit caps the number of strings kept for deduplication.
+// Once the table is full, new strings are written in full, strings already
+// in the table are still deduplicated. Zero removes the limit.
+func (e *Encoder) SetStringTableLimit(n int) {
+	e.maxStrings = n
+}
+
+// internString looks str up in the string table and reports whether it was
+// found. Every other non-empty string consumes the next ID, as the Decoder
+// numbers every string it reads in full, and is stored if the options allow.
+func (e *Encoder) internString(str string, key bool) (uint, bool) {
+	if str == `` {
+		return 0, false
+	}
+	dedup := e.dedup == DedupAll || e.dedup == DedupKeys && key
+	if e.dedupMaxLen > 0 && len(str) > e.dedupMaxLen {
+		dedup = false
+	}
+
+	if dedup {
+		ID, ok := e.strings[str]
+		if ok {
+			return ID, true
+		}
+		if !ok && (e.maxStrings <= 0 || len(e.strings) < e.maxStrings) {
+			if e.strings == nil {
+				e.strings = make(map[string]uint)
+			}
+			e.strings[str] = e.stringID
+		}
+	}
+	e.stringID++
+	return 0, false
+}
+
+func (e *Encoder) EncodeString(v string) error {
+	return e.encodeString([]byte(v), false)
+}
+
+// EncodeStringKey encodes v as an array key or property name. Keys are
+// deduplicated under DedupKeys, other strings are not.
+func (e *Encoder) EncodeStringKey(v string) error {
+	return e.encodeString([]byte(v), true)
+}
+
+func (e *Encoder) EncodeBytes(v []byte) error {
+	return e.encodeString(v, false)
+}
+
+func (e *Encoder) encodeString(v []byte, key bool) error {
+	if len(v) == 0 {
+		return e.writeBytes(igcode.StringEmpty)
+	}
+
+	// TODO: unnecessary re-conversion back to string.
+	if ID, ok := e.internString(string(v), key); ok {
+		// Encode as ID
+		if ID <= 0xff {
+			return e.write1(igcode.StringID8, uint8(ID))
+		}
+		if ID <= 0xffff {
+			return e.write2(igcode.StringID16, uint16(ID))
+		}
+		if ID <= 0xffffffff {
+			return e.write4(igcode.StringID32, uint32(ID))
+		}
+		return fmt.Errorf(`igbinary: Encode(string ID exceeds range)`)
+	}
+
+	length := len(v)
+	if length <= 0xff {
+		if err := e.write1(igcode.String8, uint8(length)); err != nil {
+			return err
+		}
+		return e.write(v)
+	}
+	if length <= 0xffff {
+		if err := e.write2(igcode.String16, uint16(length)); err != nil {
+			return err
+		}
+		return e.write(v)
+	}
+	if length <= 0xffffffff {
+		if err := e.write4(igcode.String32, uint32(length)); err != nil {
+			return err
+		}
+		return e.write(v)
+	}
+
+	return errors.New(`igbinary: Encode([]byte exceeds capacity)`)
+}
@@ -0,0 +1,29 @@
+package igbinary
+
+import "reflect"
+
+// encodeStructValue encodes a struct as an array keyed by field name, in
+// the order the fields are declared, or as an object when the struct
+// declares a class.
+func encodeStructValue(e *Encoder, v reflect.Value) error {
+	fs := structs.Fields(v.Type(), defaultStructTag)
+	fields := fs.OmitEmpty(v)
+	var err error
+	if fs.Class != "" {
+		err = e.EncodeObjectLen(fs.Class, len(fields))
+	} else {
+		err = e.EncodeArrayLen(len(fields))
+	}
+	if err != nil {
+		return err
+	}
+	for _, f := range fields {
+		if err := e.EncodeStringKey(f.key); err != nil {
+			return err
+		}
+		if err := f.EncodeValue(e, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/hex"
 	"github.com/stretchr/testify/suite"
+	"reflect"
 	"testing"
 )
 
@@ -41,6 +42,125 @@ func (Suite *EncodeSuite) TestStrings() {
 	Suite.assertMarshal([]byte(`foobar`), `1106666f6f626172`)
 }
 
+func (Suite *EncodeSuite) TestInt64() {
+	Suite.assertMarshal(int64(0x100000000), `200000000100000000`)
+	Suite.assertMarshal(int64(-0x8000000000000000), `218000000000000000`)
+}
+
+func (Suite *EncodeSuite) TestArrays() {
+	foo := &Object{Class: `Foo`, Props: Array{{Key: `k`, Value: 1.5}}}
+	Suite.assertMarshal(Array{
+		{Key: int64(0), Value: true},
+		{Key: `k`, Value: foo},
+		{Key: `r`, Value: foo},
+		{Key: `a`, Value: 255},
+	}, `140406000511016b1703466f6f14010e000c3ff8000000000000110172220111016106ff`)
+	Suite.assertMarshal(&SerializedObject{Class: `Foo`, Data: []byte(`x`)}, `1703466f6f1d0178`)
+}
+
+func (Suite *EncodeSuite) TestMaps() {
+	type user struct {
+		Name    string `igbinary:"name"`
+		Age     uint8
+		Tags    []string
+		Parent  *user
+		private int
+	}
+
+	tests := []struct {
+		v   interface{}
+		hex string
+	}{
+		{map[string]int{`b`: 2, `a`: 1, `10`: -3}, `140311023130070311016106011101620602`},
+		{map[interface{}]bool{`x`: true, 2: false, int8(-1): true, uint(1): true}, `140407010506010506020411017805`},
+		{map[int]string(nil), `00`},
+		{user{Name: `bob`, Age: 7, Tags: []string{`a`}}, `140411046e616d651103626f6211034167650607110454616773140106001101611106506172656e7400`},
+		{&user{Name: `a`, Parent: &user{Name: `a`}}, `140411046e616d6511016111034167650600110454616773001106506172656e7414040e000e010e0206000e03000e0400`},
+	}
+	for _, test := range tests {
+		for i := 0; i < 5; i++ {
+			var buf bytes.Buffer
+			Encoder := NewEncoder(&buf)
+			Encoder.SetSortMapKeys(true)
+			Suite.Nil(Encoder.Encode(test.v))
+			Suite.Equal(test.hex, hex.EncodeToString(buf.Bytes()))
+		}
+	}
+
+	_, err := Marshal(map[float64]int{1: 1})
+	Suite.EqualError(err, `igbinary: Encode(unsupported map key float64)`)
+}
+
+func (Suite *EncodeSuite) TestReflectionRoundTrip() {
+	type raw []byte
+	type user struct {
+		Name   string `igbinary:"name"`
+		Tags   []string
+		Parent *user
+	}
+	name := `ann`
+
+	for _, v := range []interface{}{
+		true, int8(-8), int16(-16), int32(-32), int(-1 << 40), uint8(8), uint16(16), uint32(32), uint(1 << 40),
+		float32(1.5), 2.5, `string`, &name, (*string)(nil),
+		[]byte("\x00\xff"), raw("\x00\xff"), raw(nil), []byte{},
+		[]int{1, 2, 3}, []string{}, []*user{{Name: `a`}, nil}, [][]int64{{1}, {2, 3}}, [3]int{1, 2, 3}, [2]byte{1, 2},
+		[]interface{}{int64(1), `a`, nil}, map[string][]int{`a`: {1}}, map[string]string(nil), map[int]bool(nil),
+		user{Name: `bob`, Tags: []string{`a`, `b`}, Parent: &user{Name: `alice`}},
+	} {
+		B, err := Marshal(v)
+		if !Suite.Nil(err, `%T`, v) {
+			continue
+		}
+		Decoded := reflect.New(reflect.TypeOf(v))
+		if Suite.Nil(Unmarshal(B, Decoded.Interface()), `%T`, v) {
+			Suite.Equal(v, Decoded.Elem().Interface(), `%T`, v)
+		}
+	}
+
+	// Keys are ignored, Go arrays skip extra values and zero missing ones.
+	B, err := Marshal(Array{{Key: `b`, Value: 1}, {Key: int64(7), Value: 2}, {Key: `a`, Value: 3}})
+	Suite.Require().Nil(err)
+	var Ints []int
+	Suite.Nil(Unmarshal(B, &Ints))
+	Suite.Equal([]int{1, 2, 3}, Ints)
+	Short, Long := [2]int{}, [4]int{9, 9, 9, 9}
+	Suite.Nil(Unmarshal(B, &Short))
+	Suite.Equal([2]int{1, 2}, Short)
+	Suite.Nil(Unmarshal(B, &Long))
+	Suite.Equal([4]int{1, 2, 3, 0}, Long)
+}
+
+func (Suite *EncodeSuite) TestStringDedup() {
+	nested := Array{{Key: `a`, Value: Array{{Key: `a`, Value: `a`}}}, {Key: `e`, Value: ``}}
+	list := Array{
+		{Key: int64(0), Value: `xy`}, {Key: int64(1), Value: `xy`},
+		{Key: int64(2), Value: `z`}, {Key: int64(3), Value: `z`},
+	}
+	tests := []struct {
+		configure func(*Encoder)
+		v         Array
+		hex       string
+	}{
+		{func(*Encoder) {}, nested, `140211016114010e000e001101650d`},
+		{func(e *Encoder) { e.SetStringDedup(DedupKeys) }, nested, `140211016114010e001101611101650d`},
+		{func(e *Encoder) { e.SetStringDedup(DedupNone) }, nested, `140211016114011101611101611101650d`},
+		{func(e *Encoder) { e.SetStringDedupMaxLen(1) }, list, `1404060011027879060111027879060211017a06030e02`},
+		{func(e *Encoder) { e.SetStringTableLimit(1) }, list, `140406001102787906010e00060211017a060311017a`},
+	}
+	for _, test := range tests {
+		var buf bytes.Buffer
+		Encoder := NewEncoder(&buf)
+		test.configure(Encoder)
+		Suite.Nil(Encoder.Encode(test.v))
+		Suite.Equal(test.hex, hex.EncodeToString(buf.Bytes()))
+
+		var v interface{}
+		Suite.Nil(Unmarshal(buf.Bytes(), &v))
+		Suite.Equal(test.v, v)
+	}
+}
+
 func (Suite *EncodeSuite) TestEncodeArrayLen() {
 	b := &bytes.Buffer{}
 	Encoder := NewEncoder(b)
@@ -5,35 +5,41 @@ import (
 	"reflect"
 )
 
+var (
+	arrayType               = reflect.TypeOf(Array(nil))
+	objectPtrType           = reflect.TypeOf((*Object)(nil))
+	serializedObjectPtrType = reflect.TypeOf((*SerializedObject)(nil))
+)
+
 var valueEncoders []encoderFunc
 
 //nolint:gochecknoinits
 func init() {
 	valueEncoders = []encoderFunc{
-		reflect.Bool:          encodeUnsupportedValue, // encodeBoolValue,
-		reflect.Int:           encodeUnsupportedValue, // encodeIntValue,
-		reflect.Int8:          encodeUnsupportedValue, // encodeIntValue,
-		reflect.Int16:         encodeUnsupportedValue, // encodeIntValue,
-		reflect.Int32:         encodeUnsupportedValue, // encodeIntValue,
-		reflect.Int64:         encodeUnsupportedValue, // encodeIntValue,
-		reflect.Uint:          encodeUnsupportedValue, // encodeUintValue,
-		reflect.Uint8:         encodeUnsupportedValue, // encodeUintValue,
-		reflect.Uint16:        encodeUnsupportedValue, // encodeUintValue,
-		reflect.Uint32:        encodeUnsupportedValue, // encodeUintValue,
-		reflect.Uint64:        encodeUnsupportedValue, // encodeUintValue,
-		reflect.Float32:       encodeUnsupportedValue, // encodeFloat32Value,
-		reflect.Float64:       encodeUnsupportedValue, // encodeFloat64Value,
+		reflect.Bool:          encodeBoolValue,
+		reflect.Int:           encodeIntValue,
+		reflect.Int8:          encodeIntValue,
+		reflect.Int16:         encodeIntValue,
+		reflect.Int32:         encodeIntValue,
+		reflect.Int64:         encodeIntValue,
+		reflect.Uint:          encodeUintValue,
+		reflect.Uint8:         encodeUintValue,
+		reflect.Uint16:        encodeUintValue,
+		reflect.Uint32:        encodeUintValue,
+		reflect.Uint64:        encodeUintValue,
+		reflect.Float32:       encodeFloatValue,
+		reflect.Float64:       encodeFloatValue,
 		reflect.Complex64:     encodeUnsupportedValue,
 		reflect.Complex128:    encodeUnsupportedValue,
-		reflect.Array:         encodeUnsupportedValue, //encodeArrayValue,
+		reflect.Array:         encodeArrayValue,
 		reflect.Chan:          encodeUnsupportedValue,
 		reflect.Func:          encodeUnsupportedValue,
-		reflect.Interface:     encodeUnsupportedValue, // encodeInterfaceValue,
-		reflect.Map:           encodeUnsupportedValue, // encodeMapValue,
-		reflect.Ptr:           encodeUnsupportedValue, // encodeUnsupportedValue,
-		reflect.Slice:         encodeUnsupportedValue, // encodeSliceValue,
-		reflect.String:        encodeUnsupportedValue, // encodeStringValue,
-		reflect.Struct:        encodeUnsupportedValue, // encodeStructValue,
+		reflect.Interface:     encodeInterfaceValue,
+		reflect.Map:           encodeMapValue,
+		reflect.Ptr:           encodeUnsupportedValue,
+		reflect.Slice:         encodeSliceValue,
+		reflect.String:        encodeStringValue,
+		reflect.Struct:        encodeStructValue,
 		reflect.UnsafePointer: encodeUnsupportedValue,
 	}
 }
@@ -56,12 +62,32 @@ func _getEncoder(typ reflect.Type) encoderFunc {
 	//	}
 	//}
 
-	/*if typ.Implements(customEncoderType) {
-		return encodeCustomValue
-	}
 	if typ.Implements(marshalerType) {
 		return marshalValue
 	}
+	if kind != reflect.Ptr && reflect.PtrTo(typ).Implements(marshalerType) {
+		return marshalValueAddr
+	}
+	if typ.Implements(enumMarshalerType) {
+		return encodeEnumValue
+	}
+
+	// The generic value types are encoded as the PHP values they model.
+	switch typ {
+	case arrayType, objectPtrType, serializedObjectPtrType:
+		return encodeGenericValue
+	case timeType:
+		return encodeTimeValue
+	case durationType:
+		return encodeDurationValue
+	}
+	if isTime(typ) {
+		return encodeTimeValue
+	}
+
+	/*if typ.Implements(customEncoderType) {
+		return encodeCustomValue
+	}
 	if typ.Implements(binaryMarshalerType) {
 		return marshalBinaryValue
 	}
@@ -75,9 +101,6 @@ func _getEncoder(typ reflect.Type) encoderFunc {
 		if ptr.Implements(customEncoderType) {
 			return encodeCustomValuePtr
 		}
-		if ptr.Implements(marshalerType) {
-			return marshalValuePtr
-		}
 		if ptr.Implements(binaryMarshalerType) {
 			return marshalBinaryValueAddr
 		}
@@ -90,9 +113,11 @@ func _getEncoder(typ reflect.Type) encoderFunc {
 		return encodeErrorValue
 	}*/
 
+	if kind == reflect.Ptr {
+		return ptrEncoderFunc(typ)
+	}
+
 	//switch kind {
-	//case reflect.Ptr:
-	//	return ptrEncoderFunc(typ)
 	//	/*case reflect.Slice:
 	//		elem := typ.Elem()
 	//		if elem.Kind() == reflect.Uint8 {
@@ -119,6 +144,92 @@ func _getEncoder(typ reflect.Type) encoderFunc {
 	return valueEncoders[kind]
 }
 
+func ptrEncoderFunc(typ reflect.Type) encoderFunc {
+	encoder := getEncoder(typ.Elem())
+	return func(e *Encoder, v reflect.Value) error {
+		if v.IsNil() {
+			return e.EncodeNil()
+		}
+		return encoder(e, v.Elem())
+	}
+}
+
+func marshalValue(e *Encoder, v reflect.Value) error {
+	if v.Kind() == reflect.Ptr && v.IsNil() {
+		return e.EncodeNil()
+	}
+	return v.Interface().(Marshaler).MarshalIgbinary(e)
+}
+
+func marshalValueAddr(e *Encoder, v reflect.Value) error {
+	if !v.CanAddr() {
+		return fmt.Errorf("igbinary: Encode(nonaddressable %s)", v.Type())
+	}
+	return marshalValue(e, v.Addr())
+}
+
 func encodeUnsupportedValue(e *Encoder, v reflect.Value) error {
 	return fmt.Errorf("igbinary: Encode(unsupported %s)", v.Type())
 }
+
+func encodeBoolValue(e *Encoder, v reflect.Value) error {
+	return e.EncodeBool(v.Bool())
+}
+
+func encodeIntValue(e *Encoder, v reflect.Value) error {
+	return e.EncodeInt64(v.Int())
+}
+
+func encodeUintValue(e *Encoder, v reflect.Value) error {
+	return e.EncodeUint64(v.Uint())
+}
+
+func encodeFloatValue(e *Encoder, v reflect.Value) error {
+	return e.EncodeFloat64(v.Float())
+}
+
+func encodeStringValue(e *Encoder, v reflect.Value) error {
+	return e.EncodeString(v.String())
+}
+
+func encodeInterfaceValue(e *Encoder, v reflect.Value) error {
+	if v.IsNil() {
+		return e.EncodeNil()
+	}
+	return e.Encode(v.Interface())
+}
+
+func encodeGenericValue(e *Encoder, v reflect.Value) error {
+	if v.Kind() == reflect.Ptr && v.IsNil() {
+		return e.EncodeNil()
+	}
+	return e.Encode(v.Interface())
+}
+
+// encodeSliceValue encodes []byte as a string and other slices as lists.
+func encodeSliceValue(e *Encoder, v reflect.Value) error {
+	if v.IsNil() {
+		return e.EncodeNil()
+	}
+	if v.Type().Elem().Kind() == reflect.Uint8 {
+		return e.EncodeBytes(v.Bytes())
+	}
+	return encodeArrayValue(e, v)
+}
+
+// encodeArrayValue encodes a Go array or slice as a PHP list.
+func encodeArrayValue(e *Encoder, v reflect.Value) error {
+	n := v.Len()
+	if err := e.EncodeArrayLen(n); err != nil {
+		return err
+	}
+	for i := 0; i < n; i++ {
+		if err := e.EncodeInt64(int64(i)); err != nil {
+			return err
+		}
+		if err := e.EncodeValue(v.Index(i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package igbinary
+
+import (
+	"errors"
+	"github.com/zarken-go/igbinary/igcode"
+	"reflect"
+)
+
+// ErrUnknownEnumCase is returned by EnumUnmarshalers for cases they do not
+// model. Decode wraps it with the class and case name.
+var ErrUnknownEnumCase = errors.New(`unknown enum case`)
+
+// EnumMarshaler is implemented by Go types modelling a PHP 8.1 enum, such
+// as a named string or int. The value encodes as the case name of class,
+// the way igbinary serializes OrderStatus::Shipped.
+type EnumMarshaler interface {
+	MarshalEnum() (class, name string, err error)
+}
+
+// EnumUnmarshaler is implemented by Go types decoding PHP enum cases. Types
+// without a fallback for unknown cases return ErrUnknownEnumCase, types
+// with one set it and return nil.
+type EnumUnmarshaler interface {
+	UnmarshalEnum(class, name string) error
+}
+
+var (
+	enumMarshalerType   = reflect.TypeOf((*EnumMarshaler)(nil)).Elem()
+	enumUnmarshalerType = reflect.TypeOf((*EnumUnmarshaler)(nil)).Elem()
+)
+
+// EncodeEnum encodes the case name of the enum class. Enum cases are laid
+// out as objects holding a single null property named after the case.
+func (e *Encoder) EncodeEnum(class, name string) error {
+	if err := e.EncodeObjectLen(class, 1); err != nil {
+		return err
+	}
+	if err := e.EncodeStringKey(name); err != nil {
+		return err
+	}
+	return e.EncodeNil()
+}
+
+// DecodeEnum decodes an enum case and returns its class and case name.
+// Later occurrences of the same case, written as object references, resolve
+// to the case they point to.
+func (d *Decoder) DecodeEnum() (class, name string, err error) {
+	c, err := d.PeekCode()
+	if err != nil {
+		return ``, ``, err
+	}
+	if igcode.IsRef(c) {
+		v, err := d.decodeRef()
+		if err != nil {
+			return ``, ``, err
+		}
+		if obj, ok := v.(*Object); ok && len(obj.Props) == 1 && obj.Props[0].Value == nil {
+			if name, ok := obj.Props[0].Key.(string); ok {
+				return obj.Class, name, nil
+			}
+		}
+		return ``, ``, decodeErrorF(`reference to %T decoding enum`, v)
+	}
+	if !igcode.IsObject(c) {
+		return ``, ``, decodeErrorF(`invalid code=%x decoding enum`, c)
+	}
+
+	class, n, err := d.DecodeObjectLen()
+	if err != nil {
+		return ``, ``, err
+	}
+	slot := len(d.refs) - 1
+	if n != 1 {
+		return ``, ``, decodeErrorF(`%s is not an enum case`, class)
+	}
+	if name, err = d.DecodeString(); err != nil {
+		return ``, ``, err
+	}
+	if !d.hasNilCode() {
+		return ``, ``, decodeErrorF(`%s is not an enum case`, class)
+	}
+	if err := d.DecodeNil(); err != nil {
+		return ``, ``, err
+	}
+	d.refs[slot] = &Object{Class: class, Props: Array{{Key: name}}}
+	return class, name, nil
+}
+
+func encodeEnumValue(e *Encoder, v reflect.Value) error {
+	if v.Kind() == reflect.Ptr && v.IsNil() {
+		return e.EncodeNil()
+	}
+	class, name, err := v.Interface().(EnumMarshaler).MarshalEnum()
+	if err != nil {
+		return err
+	}
+	return e.EncodeEnum(class, name)
+}
+
+// decodeEnumValue decodes into a pointer implementing EnumUnmarshaler, null
+// sets it to nil.
+func decodeEnumValue(d *Decoder, v reflect.Value) error {
+	if d.hasNilCode() {
+		if !v.IsNil() {
+			v.Set(reflect.Zero(v.Type()))
+		}
+		return d.DecodeNil()
+	}
+	if v.IsNil() {
+		v.Set(reflect.New(v.Type().Elem()))
+	}
+	class, name, err := d.DecodeEnum()
+	if err != nil {
+		return err
+	}
+	if err := v.Interface().(EnumUnmarshaler).UnmarshalEnum(class, name); err != nil {
+		return decodeErrorF(`%s::%s: %w`, class, name, err)
+	}
+	return nil
+}
+
+func decodeEnumValueAddr(d *Decoder, v reflect.Value) error {
+	if !v.CanAddr() {
+		return decodeErrorF(`nonaddressable %s`, v.Type())
+	}
+	if d.hasNilCode() {
+		v.Set(reflect.Zero(v.Type()))
+		return d.DecodeNil()
+	}
+	return decodeEnumValue(d, v.Addr())
+}
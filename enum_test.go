@@ -0,0 +1,134 @@
+package igbinary
+
+import (
+	"encoding/hex"
+	"errors"
+	"github.com/stretchr/testify/suite"
+	"testing"
+)
+
+type EnumSuite struct {
+	suite.Suite
+}
+
+type testOrderStatus string
+
+const (
+	testPending testOrderStatus = `pending`
+	testShipped testOrderStatus = `shipped`
+)
+
+var testOrderStatusCases = map[testOrderStatus]string{testPending: `Pending`, testShipped: `Shipped`}
+
+func (s testOrderStatus) MarshalEnum() (string, string, error) {
+	if name, ok := testOrderStatusCases[s]; ok {
+		return `App\OrderStatus`, name, nil
+	}
+	return ``, ``, errors.New(`unknown order status ` + string(s))
+}
+
+func (s *testOrderStatus) UnmarshalEnum(class, name string) error {
+	for status, caseName := range testOrderStatusCases {
+		if caseName == name {
+			*s = status
+			return nil
+		}
+	}
+	return ErrUnknownEnumCase
+}
+
+// testPriority falls back to testPriorityNormal for unknown cases.
+type testPriority int
+
+const (
+	testPriorityNormal testPriority = iota
+	testPriorityHigh
+)
+
+func (p testPriority) MarshalEnum() (string, string, error) {
+	if p == testPriorityHigh {
+		return `Priority`, `High`, nil
+	}
+	return `Priority`, `Normal`, nil
+}
+
+func (p *testPriority) UnmarshalEnum(class, name string) error {
+	*p = testPriorityNormal
+	if name == `High` {
+		*p = testPriorityHigh
+	}
+	return nil
+}
+
+type testOrder struct {
+	Status   testOrderStatus  `igbinary:"status"`
+	Previous *testOrderStatus `igbinary:"previous"`
+	Priority testPriority     `igbinary:"priority"`
+}
+
+func (Suite *EnumSuite) TestEncode() {
+	// OrderStatus::Shipped as serialized by PHP 8.1
+	B, err := Marshal(testShipped)
+	Suite.Nil(err)
+	Suite.Equal(`00000002`+`170f4170705c4f72646572537461747573`+`1401`+`110753686970706564`+`00`, hex.EncodeToString(B))
+
+	_, err = Marshal(testOrderStatus(`lost`))
+	Suite.EqualError(err, `unknown order status lost`)
+}
+
+func (Suite *EnumSuite) TestRoundTrip() {
+	Previous := testPending
+	B, err := Marshal(testOrder{Status: testShipped, Previous: &Previous, Priority: testPriorityHigh})
+	Suite.Require().Nil(err)
+
+	var Order testOrder
+	Suite.Nil(Unmarshal(B, &Order))
+	Suite.Equal(testOrder{Status: testShipped, Previous: &Previous, Priority: testPriorityHigh}, Order)
+
+	B, err = Marshal(testOrder{Status: testPending})
+	Suite.Require().Nil(err)
+	Order = testOrder{Previous: &Previous}
+	Suite.Nil(Unmarshal(B, &Order))
+	Suite.Equal(testOrder{Status: testPending}, Order)
+
+	var Value interface{}
+	Suite.Require().Nil(Unmarshal(B, &Value))
+	Suite.Equal(&Object{Class: `App\OrderStatus`, Props: Array{{Key: `Pending`}}}, Value.(Array)[0].Value)
+}
+
+func (Suite *EnumSuite) TestObjectRef() {
+	// Enum cases are singletons, PHP references repeated occurrences.
+	Shipped := &Object{Class: `App\OrderStatus`, Props: Array{{Key: `Shipped`}}}
+	B, err := Marshal(Array{{Key: `status`, Value: Shipped}, {Key: `previous`, Value: Shipped}})
+	Suite.Require().Nil(err)
+
+	var Order testOrder
+	Suite.Nil(Unmarshal(B, &Order))
+	Suite.Equal(testShipped, Order.Status)
+	Suite.Equal(testShipped, *Order.Previous)
+}
+
+func (Suite *EnumSuite) TestUnknownCase() {
+	B, err := Marshal(Array{
+		{Key: `status`, Value: &Object{Class: `App\OrderStatus`, Props: Array{{Key: `Lost`}}}},
+	})
+	Suite.Require().Nil(err)
+	var Order testOrder
+	err = Unmarshal(B, &Order)
+	Suite.EqualError(err, `igbinary: Decode(App\OrderStatus::Lost: unknown enum case)`)
+	Suite.True(errors.Is(err, ErrUnknownEnumCase))
+
+	B, err = Marshal(&Object{Class: `Priority`, Props: Array{{Key: `Urgent`}}})
+	Suite.Require().Nil(err)
+	Priority := testPriorityHigh
+	Suite.Nil(Unmarshal(B, &Priority))
+	Suite.Equal(testPriorityNormal, Priority)
+
+	B, err = Marshal(&Object{Class: `Priority`, Props: Array{{Key: `a`, Value: int64(1)}}})
+	Suite.Require().Nil(err)
+	Suite.EqualError(Unmarshal(B, &Priority), `igbinary: Decode(Priority is not an enum case)`)
+}
+
+func TestEnumSuite(t *testing.T) {
+	suite.Run(t, new(EnumSuite))
+}
@@ -0,0 +1,146 @@
+package igbinary
+
+import (
+	"bytes"
+	"math"
+)
+
+// EqualOptions configures the comparison of Equal.
+type EqualOptions struct {
+	// IgnoreKeyOrder compares arrays and object properties as unordered
+	// maps. By default entries must appear in the same order, as PHP's ===
+	// requires.
+	IgnoreKeyOrder bool
+	// FloatTolerance is the largest absolute difference at which two floats
+	// are still equal.
+	FloatTolerance float64
+}
+
+// Equal reports whether the igbinary documents a and b hold the same value,
+// regardless of how it is encoded: string IDs, integer widths and headers
+// do not matter. It compares using the zero EqualOptions.
+func Equal(a, b []byte) (bool, error) {
+	return EqualOptions{}.Equal(a, b)
+}
+
+// Equal reports whether the igbinary documents a and b hold the same value
+// under the options of o. Data trailing either value is an error, as for
+// Valid.
+func (o EqualOptions) Equal(a, b []byte) (bool, error) {
+	x, err := unmarshalDocument(a)
+	if err != nil {
+		return false, err
+	}
+	y, err := unmarshalDocument(b)
+	if err != nil {
+		return false, err
+	}
+	return o.EqualValues(x, y), nil
+}
+
+func unmarshalDocument(data []byte) (interface{}, error) {
+	d := NewDecoder(bytes.NewReader(data))
+	var v interface{}
+	if err := d.Decode(&v); err != nil {
+		return nil, err
+	}
+	if d.More() {
+		return nil, decodeErrorF(`trailing data after value`)
+	}
+	return v, nil
+}
+
+// EqualValues compares two values as returned by Decoder.DecodeInterface.
+// Integers and floats never equal each other. Two NaNs are equal.
+func (o EqualOptions) EqualValues(x, y interface{}) bool {
+	c := comparison{EqualOptions: o, seen: make(map[comparedPair]bool)}
+	return c.equal(x, y)
+}
+
+type comparison struct {
+	EqualOptions
+	// seen holds the pairs of objects and arrays compared so far. Any
+	// difference ends the comparison, so a pair seen again is equal when
+	// the rest is: this stops at objects reaching themselves through their
+	// properties and compares arrays shared through references once.
+	seen map[comparedPair]bool
+}
+
+// comparedPair identifies two *Object, or two arrays by their first entry
+// and length.
+type comparedPair struct {
+	x, y interface{}
+	n    int
+}
+
+// visit reports whether the pair was seen before and marks it seen.
+func (c *comparison) visit(pair comparedPair) bool {
+	if c.seen[pair] {
+		return true
+	}
+	c.seen[pair] = true
+	return false
+}
+
+func (c *comparison) equal(x, y interface{}) bool {
+	switch x := x.(type) {
+	case float64:
+		y, ok := y.(float64)
+		if !ok {
+			return false
+		}
+		if math.IsNaN(x) || math.IsNaN(y) {
+			return math.IsNaN(x) && math.IsNaN(y)
+		}
+		return x == y || math.Abs(x-y) <= c.FloatTolerance
+	case Array:
+		y, ok := y.(Array)
+		return ok && c.equalArrays(x, y)
+	case *Object:
+		y, ok := y.(*Object)
+		if !ok || x.Class != y.Class {
+			return false
+		}
+		if c.visit(comparedPair{x: x, y: y}) {
+			return true
+		}
+		return c.equalArrays(x.Props, y.Props)
+	case *SerializedObject:
+		y, ok := y.(*SerializedObject)
+		return ok && x.Class == y.Class && bytes.Equal(x.Data, y.Data)
+	}
+	return x == y
+}
+
+func (c *comparison) equalArrays(x, y Array) bool {
+	if len(x) != len(y) {
+		return false
+	}
+	if len(x) > 0 && c.visit(comparedPair{x: &x[0], y: &y[0], n: len(x)}) {
+		return true
+	}
+	if !c.IgnoreKeyOrder {
+		for i := range x {
+			kx, _ := arrayKey(x[i].Key)
+			ky, _ := arrayKey(y[i].Key)
+			if kx != ky || !c.equal(x[i].Value, y[i].Value) {
+				return false
+			}
+		}
+		return true
+	}
+
+	index := make(map[interface{}]int, len(y))
+	for i, e := range y {
+		k, _ := arrayKey(e.Key)
+		index[k] = i
+	}
+	for _, e := range x {
+		k, _ := arrayKey(e.Key)
+		i, ok := index[k]
+		if !ok || !c.equal(e.Value, y[i].Value) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,111 @@
+package igbinary
+
+import (
+	"bytes"
+	"encoding/hex"
+	"github.com/stretchr/testify/suite"
+	"math"
+	"testing"
+)
+
+type EqualSuite struct {
+	suite.Suite
+}
+
+func (Suite *EqualSuite) hex(s string) []byte {
+	B, err := hex.DecodeString(s)
+	Suite.Require().Nil(err)
+	return B
+}
+
+func (Suite *EqualSuite) TestEncodings() {
+	v := Array{{Key: `a`, Value: `x`}, {Key: `b`, Value: `x`}, {Key: int64(1), Value: 300}}
+	B, err := Marshal(v)
+	Suite.Require().Nil(err)
+
+	var buf bytes.Buffer
+	Encoder := NewEncoder(&buf)
+	Encoder.SetStringDedup(DedupNone)
+	Suite.Require().Nil(Encoder.Encode(v))
+
+	equal, err := Equal(B, buf.Bytes())
+	Suite.Nil(err)
+	Suite.True(equal)
+
+	// [1 => 1] with a numeric string key and a 16-bit integer.
+	equal, err = Equal(Suite.hex(`140106010601`), Suite.hex(`1401110131080001`))
+	Suite.Nil(err)
+	Suite.True(equal)
+
+	equal, err = Equal(Suite.hex(`0601`), Suite.hex(`0c3ff0000000000000`))
+	Suite.Nil(err)
+	Suite.False(equal)
+
+	_, err = Equal(Suite.hex(`0601`), Suite.hex(`30`))
+	Suite.EqualError(err, `igbinary: Decode(invalid code=30 decoding interface)`)
+}
+
+func (Suite *EqualSuite) TestOptions() {
+	ab := Array{{Key: `a`, Value: 1.0}, {Key: `b`, Value: math.NaN()}}
+	ba := Array{{Key: `b`, Value: math.NaN()}, {Key: `a`, Value: 1.0 + 1e-9}}
+
+	Suite.False(EqualOptions{}.EqualValues(ab, ba))
+	Suite.False(EqualOptions{IgnoreKeyOrder: true}.EqualValues(ab, ba))
+	Suite.True(EqualOptions{IgnoreKeyOrder: true, FloatTolerance: 1e-6}.EqualValues(ab, ba))
+	Suite.False(EqualOptions{IgnoreKeyOrder: true}.EqualValues(ab, Array{{Key: `a`, Value: 1.0}, {Key: `c`, Value: 2.0}}))
+}
+
+func (Suite *EqualSuite) TestObjects() {
+	self := func(class string) []byte {
+		obj := &Object{Class: class}
+		obj.Props = Array{{Key: `self`, Value: obj}}
+		B, err := Marshal(obj)
+		Suite.Require().Nil(err)
+		return B
+	}
+
+	equal, err := Equal(self(`Node`), self(`Node`))
+	Suite.Nil(err)
+	Suite.True(equal)
+	equal, err = Equal(self(`Node`), self(`Leaf`))
+	Suite.Nil(err)
+	Suite.False(equal)
+
+	Suite.False(EqualOptions{}.EqualValues(&SerializedObject{Class: `A`, Data: []byte(`x`)},
+		&SerializedObject{Class: `A`, Data: []byte(`y`)}))
+}
+
+func (Suite *EqualSuite) TestSharedArrays() {
+	// Each level holds a reference to the level below twice:
+	// $a[0] = &$b; $a[1] = &$b; with 40 levels, each array is compared once.
+	const Levels = 40
+	var nested func(Level int) []byte
+	nested = func(Level int) []byte {
+		if Level == 0 {
+			return []byte{0x14, 0x01, 0x06, 0x00, 0x06, 0x01}
+		}
+		B := []byte{0x14, 0x02, 0x06, 0x00, 0x25}
+		B = append(B, nested(Level-1)...)
+		// The array below got reference slot Levels-Level+1.
+		return append(B, 0x06, 0x01, 0x01, byte(Levels-Level+1))
+	}
+	B := append([]byte{0, 0, 0, 2}, nested(Levels)...)
+	Suite.Require().Nil(Valid(B))
+
+	for _, Options := range []EqualOptions{{}, {IgnoreKeyOrder: true}} {
+		equal, err := Options.Equal(B, B)
+		Suite.Nil(err)
+		Suite.True(equal)
+	}
+}
+
+func (Suite *EqualSuite) TestTrailingData() {
+	_, err := Equal(Suite.hex(`0601`), Suite.hex(`060106`))
+	Suite.EqualError(err, `igbinary: Decode(trailing data after value)`)
+	_, err = Equal(Suite.hex(`06010601`), Suite.hex(`0601`))
+	Suite.EqualError(err, `igbinary: Decode(trailing data after value)`)
+}
+
+func TestEqualSuite(t *testing.T) {
+	suite.Run(t, new(EqualSuite))
+}
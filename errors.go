@@ -10,6 +10,11 @@ func (d DecodeError) Error() string {
 	return `igbinary: Decode(` + d.err.Error() + `)`
 }
 
+// Unwrap returns the underlying error, such as ErrUnknownEnumCase.
+func (d DecodeError) Unwrap() error {
+	return d.err
+}
+
 func decodeError(err error) error {
 	return &DecodeError{
 		err: err,
@@ -0,0 +1,86 @@
+package igbinary
+
+import (
+	"bytes"
+	"errors"
+)
+
+// ErrNotFound is returned by Find and Get when a key of the path does not
+// exist.
+var ErrNotFound = errors.New(`igbinary: path not found`)
+
+// Get returns the value at path within the igbinary document data as a
+// self-contained RawMessage. Path elements are array keys or property
+// names, compared like PHP compares array keys. Values off the path are
+// skipped without being decoded.
+func Get(data []byte, path ...interface{}) (RawMessage, error) {
+	d := NewDecoder(bytes.NewReader(data))
+	if err := d.Find(path...); err != nil {
+		return nil, err
+	}
+	var m RawMessage
+	if err := m.UnmarshalIgbinary(d); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Find advances the decoder to the value at path within the next value, so
+// that the following Decode decodes it. Entries before it are skipped while
+// keeping the string table, the rest of the enclosing arrays is left
+// unread. References to earlier values cannot be followed.
+func (d *Decoder) Find(path ...interface{}) error {
+	n, err := d.find(path)
+	if err == nil && n < len(path) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// find walks path as far as it exists and returns the number of path
+// elements found.
+func (d *Decoder) find(path []interface{}) (int, error) {
+	if d.flags&decodingFlag == 0 && d.peekHeader() {
+		if err := d.DecodeHeader(); err != nil {
+			return 0, err
+		}
+	}
+
+	for i, elem := range path {
+		want, ok := arrayKey(elem)
+		if !ok {
+			return i, decodeErrorF(`unsupported path element %T`, elem)
+		}
+		t, err := d.Token()
+		for err == nil && t.Kind == NewRefToken {
+			t, err = d.Token()
+		}
+		if err != nil {
+			return i, err
+		}
+		switch t.Kind {
+		case ArrayStartToken, ObjectStartToken:
+		case RefToken, ObjectRefToken:
+			return i, decodeErrorF(`path %v: cannot follow reference`, path[:i])
+		default:
+			return i, decodeErrorF(`path %v: %s is not an array`, path[:i], t.Kind)
+		}
+
+		found := false
+		for n := 0; n < t.Len && !found; n++ {
+			key, err := d.decodeKey()
+			if err != nil {
+				return i, err
+			}
+			if k, _ := arrayKey(key); k == want {
+				found = true
+			} else if err := d.Skip(); err != nil {
+				return i, err
+			}
+		}
+		if !found {
+			return i, nil
+		}
+	}
+	return len(path), nil
+}
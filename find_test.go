@@ -0,0 +1,65 @@
+package igbinary
+
+import (
+	"bytes"
+	"encoding/hex"
+	"github.com/stretchr/testify/suite"
+	"testing"
+)
+
+type FindSuite struct {
+	suite.Suite
+}
+
+func (Suite *FindSuite) marshalSession() []byte {
+	B, err := Marshal(Array{
+		{Key: `flash`, Value: Array{{Key: `id`, Value: `saved`}}},
+		{Key: `user`, Value: &Object{Class: `User`, Props: Array{
+			{Key: `id`, Value: 42},
+			{Key: `name`, Value: `saved`},
+		}}},
+		{Key: `list`, Value: Array{{Key: int64(0), Value: `a`}, {Key: int64(1), Value: `b`}}},
+	})
+	Suite.Require().Nil(err)
+	return B
+}
+
+func (Suite *FindSuite) TestGet() {
+	B := Suite.marshalSession()
+
+	tests := []struct {
+		path []interface{}
+		hex  string
+	}{
+		{[]interface{}{`user`, `id`}, `062a`},
+		// Stored as a string ID in the document, expanded in the result.
+		{[]interface{}{`user`, `name`}, `11057361766564`},
+		{[]interface{}{`list`, 1}, `110162`},
+		{[]interface{}{`list`, `1`}, `110162`},
+		{[]interface{}{`flash`}, `14011102696411057361766564`},
+	}
+	for _, test := range tests {
+		raw, err := Get(B, test.path...)
+		Suite.Nil(err, test.path)
+		Suite.Equal(test.hex, hex.EncodeToString(raw), test.path)
+	}
+
+	_, err := Get(B, `user`, `email`)
+	Suite.Equal(ErrNotFound, err)
+	_, err = Get(B, `user`, `id`, `x`)
+	Suite.EqualError(err, `igbinary: Decode(path [user id]: Int is not an array)`)
+	_, err = Get(B, 1.5)
+	Suite.EqualError(err, `igbinary: Decode(unsupported path element float64)`)
+}
+
+func (Suite *FindSuite) TestFind() {
+	Decoder := NewDecoder(bytes.NewReader(Suite.marshalSession()))
+	Suite.Require().Nil(Decoder.Find(`user`, `name`))
+	var name string
+	Suite.Nil(Decoder.Decode(&name))
+	Suite.Equal(`saved`, name)
+}
+
+func TestFindSuite(t *testing.T) {
+	suite.Run(t, new(FindSuite))
+}
@@ -0,0 +1,113 @@
+//go:build go1.18
+// +build go1.18
+
+package igbinary
+
+import (
+	"bytes"
+	"testing"
+)
+
+// fuzzSeeds adds documents exercising every kind of value to the corpus.
+func fuzzSeeds(f *testing.F) {
+	foo := &Object{Class: `Foo`, Props: Array{{Key: `a`, Value: 1.5}}}
+	for _, v := range []interface{}{
+		nil, true, int64(-300), 1.5, ``, `a`,
+		Array{{Key: `a`, Value: Array{{Key: int64(0), Value: `a`}}}, {Key: `b`, Value: foo}, {Key: `c`, Value: foo}},
+		&SerializedObject{Class: `Bar`, Data: []byte(`x`)},
+	} {
+		b, err := Marshal(v)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(b)
+	}
+	// ['a' => &$x, 'b' => $x]
+	f.Add([]byte("\x14\x02\x11\x01a\x25\x06\x01\x11\x01b\x01\x01"))
+}
+
+func FuzzUnmarshal(f *testing.F) {
+	fuzzSeeds(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var v interface{}
+		err := Unmarshal(data, &v)
+		if Valid(data) != nil {
+			return
+		}
+		if err != nil {
+			t.Fatalf(`valid document failed to decode: %s`, err)
+		}
+		b, err := Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if equal, err := Equal(data, b); err != nil || !equal {
+			t.Fatalf(`re-encoded document differs: %x, %v`, b, err)
+		}
+	})
+}
+
+type fuzzEmbedded struct {
+	E string
+}
+
+type fuzzStruct struct {
+	*fuzzEmbedded
+	S   string
+	I   int
+	I8  int8
+	U   uint
+	U8  uint8
+	F   float32
+	B   bool
+	M   map[string]string
+	MI  map[int]interface{}
+	P   *fuzzStruct
+	Any interface{}
+	Raw RawMessage
+}
+
+func FuzzDecodeStruct(f *testing.F) {
+	fuzzSeeds(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var v fuzzStruct
+		_ = Unmarshal(data, &v)
+		var m map[string]interface{}
+		_ = Unmarshal(data, &m)
+	})
+}
+
+func FuzzToken(f *testing.F) {
+	fuzzSeeds(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		d := NewDecoder(bytes.NewReader(data))
+		for {
+			if _, err := d.Token(); err != nil {
+				break
+			}
+		}
+		_ = NewDecoder(bytes.NewReader(data)).Skip()
+	})
+}
+
+func FuzzEdit(f *testing.F) {
+	fuzzSeeds(f)
+	// An array with the key 'a' twice.
+	f.Add([]byte("\x14\x03\x11\x01a%\x00\x11\x03000%\x00\x11\x01a\"\x02"))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		_, _ = Get(data, `a`, 0)
+		for _, edit := range []func() ([]byte, error){
+			func() ([]byte, error) { return Set(data, []interface{}{`a`, 0}, `x`) },
+			func() ([]byte, error) { return Delete(data, `a`) },
+			func() ([]byte, error) { return Compact(nil, data) },
+		} {
+			b, err := edit()
+			if err != nil || Valid(data) != nil {
+				continue
+			}
+			if err := Valid(b); err != nil {
+				t.Fatalf(`edit of a valid document is invalid: %s`, err)
+			}
+		}
+	})
+}
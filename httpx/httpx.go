@@ -0,0 +1,261 @@
+// Package httpx serves and consumes igbinary over HTTP under the
+// application/x-igbinary media type, next to JSON for clients that do not
+// ask for igbinary.
+package httpx
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/zarken-go/igbinary"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Media types of the formats.
+const (
+	ContentType     = `application/x-igbinary`
+	JSONContentType = `application/json`
+)
+
+// Errors of DecodeRequest.
+var (
+	ErrBodyTooLarge         = errors.New(`httpx: request body too large`)
+	ErrUnsupportedMediaType = errors.New(`httpx: unsupported media type`)
+)
+
+// Format is a body format.
+type Format int
+
+// Formats, JSON is the default.
+const (
+	JSON Format = iota
+	Igbinary
+)
+
+// ContentType returns the media type of f.
+func (f Format) ContentType() string {
+	if f == Igbinary {
+		return ContentType
+	}
+	return JSONContentType
+}
+
+func (f Format) marshal(v interface{}) ([]byte, error) {
+	if f == Igbinary {
+		return igbinary.Marshal(v)
+	}
+	return json.Marshal(v)
+}
+
+func (f Format) decode(r io.Reader, v interface{}) error {
+	if f == Igbinary {
+		return igbinary.NewDecoder(r).Decode(v)
+	}
+	return json.NewDecoder(r).Decode(v)
+}
+
+// formatOf returns the format of a Content-Type header.
+func formatOf(contentType string) (Format, bool) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return 0, false
+	}
+	switch mediaType {
+	case ContentType:
+		return Igbinary, true
+	case JSONContentType:
+		return JSON, true
+	}
+	return 0, false
+}
+
+// Write writes v encoded as igbinary with status. Nothing is written when v
+// does not encode.
+func Write(w http.ResponseWriter, status int, v interface{}) error {
+	return write(w, Igbinary, status, v)
+}
+
+// Respond writes v with status in the format negotiated by Negotiate for r,
+// or by the Accept header of r without the middleware.
+func Respond(w http.ResponseWriter, r *http.Request, status int, v interface{}) error {
+	return write(w, Negotiated(r), status, v)
+}
+
+func write(w http.ResponseWriter, f Format, status int, v interface{}) error {
+	b, err := f.marshal(v)
+	if err != nil {
+		return err
+	}
+	w.Header().Set(`Content-Type`, f.ContentType())
+	w.Header().Set(`Content-Length`, strconv.Itoa(len(b)))
+	w.WriteHeader(status)
+	_, err = w.Write(b)
+	return err
+}
+
+// DecodeRequest decodes the body of r into v, as igbinary when its
+// Content-Type is application/x-igbinary and as JSON when it is
+// application/json. Bodies longer than limit bytes return ErrBodyTooLarge,
+// a limit of 0 or less means none.
+func DecodeRequest(r *http.Request, v interface{}, limit int64) error {
+	f, ok := formatOf(r.Header.Get(`Content-Type`))
+	if !ok {
+		return ErrUnsupportedMediaType
+	}
+	var body io.Reader = r.Body
+	if limit > 0 {
+		if r.ContentLength > limit {
+			return ErrBodyTooLarge
+		}
+		body = &limitReader{r: r.Body, n: limit}
+	}
+	return f.decode(body, v)
+}
+
+// limitReader reads up to n bytes and fails with ErrBodyTooLarge after.
+type limitReader struct {
+	r io.Reader
+	n int64
+}
+
+func (l *limitReader) Read(p []byte) (int, error) {
+	if l.n < 0 {
+		return 0, ErrBodyTooLarge
+	}
+	// Read one byte past the limit to tell a body of exactly n bytes from
+	// a longer one.
+	if int64(len(p)) > l.n+1 {
+		p = p[:l.n+1]
+	}
+	n, err := l.r.Read(p)
+	l.n -= int64(n)
+	if l.n < 0 {
+		return n + int(l.n), ErrBodyTooLarge
+	}
+	return n, err
+}
+
+type contextKey struct{}
+
+// Negotiate is middleware choosing the response format of requests from
+// their Accept header, which Respond writes with. Igbinary is chosen when
+// the client prefers it to JSON, JSON otherwise.
+func Negotiate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add(`Vary`, `Accept`)
+		ctx := context.WithValue(r.Context(), contextKey{}, negotiate(r.Header.Get(`Accept`)))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// Negotiated returns the response format for r chosen by Negotiate, or
+// negotiated from its Accept header without the middleware.
+func Negotiated(r *http.Request) Format {
+	if f, ok := r.Context().Value(contextKey{}).(Format); ok {
+		return f
+	}
+	return negotiate(r.Header.Get(`Accept`))
+}
+
+// negotiate returns the format of an Accept header: igbinary when its
+// quality exceeds that of JSON, or equals it through a more specific media
+// range.
+func negotiate(accept string) Format {
+	igbinaryQ, igbinarySpecific := quality(accept, ContentType)
+	jsonQ, jsonSpecific := quality(accept, JSONContentType)
+	if igbinaryQ > jsonQ || igbinaryQ > 0 && igbinaryQ == jsonQ && igbinarySpecific && !jsonSpecific {
+		return Igbinary
+	}
+	return JSON
+}
+
+// quality returns the quality an Accept header gives mediaType, from its
+// most specific matching media range, and whether that range names
+// mediaType.
+func quality(accept, mediaType string) (float64, bool) {
+	q, specificity := 0.0, -1
+	for _, r := range strings.Split(accept, `,`) {
+		rangeType, params, err := mime.ParseMediaType(strings.TrimSpace(r))
+		if err != nil {
+			continue
+		}
+		var s int
+		switch {
+		case rangeType == mediaType:
+			s = 2
+		case rangeType == `*/*`:
+			s = 0
+		case strings.HasSuffix(rangeType, `/*`) && strings.HasPrefix(mediaType, strings.TrimSuffix(rangeType, `*`)):
+			s = 1
+		default:
+			continue
+		}
+		if s <= specificity {
+			continue
+		}
+		specificity = s
+		q = 1
+		if v, ok := params[`q`]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				q = 0
+			}
+		}
+	}
+	return q, specificity == 2
+}
+
+// NewRequest returns a request with v encoded as igbinary as its body,
+// accepting igbinary and JSON responses. A nil v sends no body.
+func NewRequest(method, url string, v interface{}) (*http.Request, error) {
+	var body io.Reader
+	if v != nil {
+		b, err := Igbinary.marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(b)
+	}
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	if v != nil {
+		req.Header.Set(`Content-Type`, ContentType)
+	}
+	req.Header.Set(`Accept`, ContentType+`, `+JSONContentType+`;q=0.9`)
+	return req, nil
+}
+
+// StatusError is returned by DecodeResponse for responses without a 2xx
+// status.
+type StatusError struct {
+	StatusCode int
+	Status     string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf(`httpx: unexpected status %s`, e.Status)
+}
+
+// DecodeResponse decodes the body of resp into v by its Content-Type,
+// igbinary or JSON, and closes it. Responses without a 2xx status return a
+// *StatusError.
+func DecodeResponse(resp *http.Response, v interface{}) error {
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		_, _ = io.Copy(ioutil.Discard, resp.Body)
+		return &StatusError{StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+	f, ok := formatOf(resp.Header.Get(`Content-Type`))
+	if !ok {
+		return fmt.Errorf(`httpx: unsupported response media type %q`, resp.Header.Get(`Content-Type`))
+	}
+	return f.decode(resp.Body, v)
+}
@@ -0,0 +1,149 @@
+package httpx
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/suite"
+	"github.com/zarken-go/igbinary"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type testUser struct {
+	ID   int64  `igbinary:"id" json:"id"`
+	Name string `igbinary:"name" json:"name"`
+}
+
+type HTTPSuite struct {
+	suite.Suite
+}
+
+func (Suite *HTTPSuite) TestWrite() {
+	Recorder := httptest.NewRecorder()
+	Suite.Nil(Write(Recorder, http.StatusCreated, testUser{ID: 7, Name: `Ann`}))
+	Suite.Equal(http.StatusCreated, Recorder.Code)
+	Suite.Equal(ContentType, Recorder.Header().Get(`Content-Type`))
+
+	B, err := igbinary.Marshal(testUser{ID: 7, Name: `Ann`})
+	Suite.Require().Nil(err)
+	Suite.Equal(B, Recorder.Body.Bytes())
+
+	Recorder = httptest.NewRecorder()
+	Suite.NotNil(Write(Recorder, http.StatusOK, make(chan int)))
+	Suite.Empty(Recorder.Header().Get(`Content-Type`))
+	Suite.Empty(Recorder.Body.Bytes())
+}
+
+func (Suite *HTTPSuite) TestDecodeRequest() {
+	B, err := igbinary.Marshal(testUser{ID: 7, Name: `Ann`})
+	Suite.Require().Nil(err)
+
+	Request := httptest.NewRequest(http.MethodPost, `/users`, bytes.NewReader(B))
+	Request.Header.Set(`Content-Type`, ContentType)
+	var User testUser
+	Suite.Nil(DecodeRequest(Request, &User, int64(len(B))))
+	Suite.Equal(testUser{ID: 7, Name: `Ann`}, User)
+
+	Request = httptest.NewRequest(http.MethodPost, `/users`, strings.NewReader(`{"id":8,"name":"Bob"}`))
+	Request.Header.Set(`Content-Type`, `application/json; charset=utf-8`)
+	Suite.Nil(DecodeRequest(Request, &User, 0))
+	Suite.Equal(testUser{ID: 8, Name: `Bob`}, User)
+
+	Request = httptest.NewRequest(http.MethodPost, `/users`, bytes.NewReader(B))
+	Request.Header.Set(`Content-Type`, ContentType)
+	Suite.Equal(ErrBodyTooLarge, DecodeRequest(Request, &User, int64(len(B)-1)))
+
+	// Bodies of unknown length are cut at the limit.
+	Request = httptest.NewRequest(http.MethodPost, `/users`, bytes.NewReader(B))
+	Request.Header.Set(`Content-Type`, ContentType)
+	Request.ContentLength = -1
+	Suite.Equal(ErrBodyTooLarge, DecodeRequest(Request, &User, 4))
+
+	Request = httptest.NewRequest(http.MethodPost, `/users`, bytes.NewReader(B))
+	Request.Header.Set(`Content-Type`, `text/plain`)
+	Suite.Equal(ErrUnsupportedMediaType, DecodeRequest(Request, &User, 0))
+}
+
+func (Suite *HTTPSuite) TestNegotiate() {
+	for _, Test := range []struct {
+		Accept string
+		Format Format
+	}{
+		{``, JSON},
+		{`*/*`, JSON},
+		{`application/json`, JSON},
+		{`application/x-igbinary`, Igbinary},
+		{`application/x-igbinary, application/json;q=0.9`, Igbinary},
+		{`application/x-igbinary;q=0.5, application/json`, JSON},
+		{`application/x-igbinary, */*`, Igbinary},
+		{`application/*`, JSON},
+		{`application/x-igbinary;q=0, */*`, JSON},
+		{`text/html, application/x-igbinary;q=0.1`, Igbinary},
+	} {
+		Suite.Equal(Test.Format, negotiate(Test.Accept), Test.Accept)
+	}
+
+	Handler := Negotiate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Suite.Nil(Respond(w, r, http.StatusOK, testUser{ID: 7, Name: `Ann`}))
+	}))
+	Request := httptest.NewRequest(http.MethodGet, `/users/7`, nil)
+	Request.Header.Set(`Accept`, ContentType)
+	Recorder := httptest.NewRecorder()
+	Handler.ServeHTTP(Recorder, Request)
+	Suite.Equal(ContentType, Recorder.Header().Get(`Content-Type`))
+	Suite.Equal(`Accept`, Recorder.Header().Get(`Vary`))
+
+	Request.Header.Set(`Accept`, `application/json`)
+	Recorder = httptest.NewRecorder()
+	Handler.ServeHTTP(Recorder, Request)
+	Suite.Equal(JSONContentType, Recorder.Header().Get(`Content-Type`))
+	Suite.Equal(`{"id":7,"name":"Ann"}`, Recorder.Body.String())
+}
+
+func (Suite *HTTPSuite) TestClient() {
+	Server := httptest.NewServer(Negotiate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == `/missing` {
+			http.NotFound(w, r)
+			return
+		}
+		var User testUser
+		if err := DecodeRequest(r, &User, 1<<10); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		User.ID++
+		_ = Respond(w, r, http.StatusOK, User)
+	})))
+	defer Server.Close()
+
+	Request, err := NewRequest(http.MethodPost, Server.URL+`/users`, testUser{ID: 7, Name: `Ann`})
+	Suite.Require().Nil(err)
+	Response, err := Server.Client().Do(Request)
+	Suite.Require().Nil(err)
+	Suite.Equal(ContentType, Response.Header.Get(`Content-Type`))
+	var User testUser
+	Suite.Nil(DecodeResponse(Response, &User))
+	Suite.Equal(testUser{ID: 8, Name: `Ann`}, User)
+
+	// JSON responses decode too.
+	Request, err = NewRequest(http.MethodPost, Server.URL+`/users`, testUser{ID: 1, Name: `Bob`})
+	Suite.Require().Nil(err)
+	Request.Header.Set(`Accept`, JSONContentType)
+	Response, err = Server.Client().Do(Request)
+	Suite.Require().Nil(err)
+	Suite.Nil(DecodeResponse(Response, &User))
+	Suite.Equal(testUser{ID: 2, Name: `Bob`}, User)
+
+	Request, err = NewRequest(http.MethodGet, Server.URL+`/missing`, nil)
+	Suite.Require().Nil(err)
+	Response, err = Server.Client().Do(Request)
+	Suite.Require().Nil(err)
+	err = DecodeResponse(Response, &User)
+	Suite.EqualError(err, `httpx: unexpected status 404 Not Found`)
+	Suite.Equal(http.StatusNotFound, err.(*StatusError).StatusCode)
+}
+
+func TestHTTPSuite(t *testing.T) {
+	suite.Run(t, new(HTTPSuite))
+}
@@ -58,3 +58,57 @@ func IsStringID(c byte) bool {
 		return false
 	}
 }
+
+func IsInteger(c byte) bool {
+	switch c {
+	case PosInt8, NegInt8, PosInt16, NegInt16, PosInt32, NegInt32, PosInt64, NegInt64:
+		return true
+	default:
+		return false
+	}
+}
+
+func IsString(c byte) bool {
+	switch c {
+	case StringEmpty, String8, String16, String32:
+		return true
+	default:
+		return IsStringID(c)
+	}
+}
+
+func IsArray(c byte) bool {
+	switch c {
+	case Array8, Array16, Array32:
+		return true
+	default:
+		return false
+	}
+}
+
+func IsObject(c byte) bool {
+	switch c {
+	case Object8, Object16, Object32, ObjectID8, ObjectID16, ObjectID32:
+		return true
+	default:
+		return false
+	}
+}
+
+func IsObjectSer(c byte) bool {
+	switch c {
+	case ObjectSer8, ObjectSer16, ObjectSer32:
+		return true
+	default:
+		return false
+	}
+}
+
+func IsRef(c byte) bool {
+	switch c {
+	case ArrayRef8, ArrayRef16, ArrayRef32, ObjectRef8, ObjectRef16, ObjectRef32:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,183 @@
+// Package lz implements the LZ77 block formats PHP extensions compress
+// values with: FastLZ, LZF and LZ4.
+package lz
+
+import (
+	"errors"
+)
+
+// ErrCorrupt is returned for data that does not decompress.
+var ErrCorrupt = errors.New(`lz: corrupt data`)
+
+// FastLZ limits of level 1 streams, which LZF shares.
+const (
+	fastlzMaxCopy     = 32
+	fastlzMaxLen      = 264
+	fastlzMaxDistance = 8192
+	fastlzHashLog     = 13
+)
+
+// FastLZCompress compresses src as a level 1 FastLZ stream, the level
+// PHP's memcached extension uses for short values.
+func FastLZCompress(src []byte) []byte {
+	dst := make([]byte, 0, len(src)+len(src)/32+1)
+	var table [1 << fastlzHashLog]int
+	hash := func(i int) uint32 {
+		v := uint32(src[i]) | uint32(src[i+1])<<8 | uint32(src[i+2])<<16
+		return (v * 2654435761) >> (32 - fastlzHashLog)
+	}
+
+	literals := 0
+	flush := func(end int) {
+		for start := end - literals; start < end; start += fastlzMaxCopy {
+			n := min(end-start, fastlzMaxCopy)
+			dst = append(dst, byte(n-1))
+			dst = append(dst, src[start:start+n]...)
+		}
+		literals = 0
+	}
+
+	for i := 0; i < len(src); {
+		// The first instruction of a stream is always a literal run.
+		if i+3 > len(src) || i == 0 {
+			literals++
+			if i+3 <= len(src) {
+				table[hash(i)] = i + 1
+			}
+			i++
+			continue
+		}
+		h := hash(i)
+		ref := table[h] - 1
+		table[h] = i + 1
+		distance := i - ref - 1
+		if ref < 0 || distance >= fastlzMaxDistance ||
+			src[ref] != src[i] || src[ref+1] != src[i+1] || src[ref+2] != src[i+2] {
+			literals++
+			i++
+			continue
+		}
+
+		n := 3
+		for i+n < len(src) && n < fastlzMaxLen && src[ref+n] == src[i+n] {
+			n++
+		}
+		flush(i)
+		l := n - 2
+		if l < 7 {
+			dst = append(dst, byte(l<<5|distance>>8))
+		} else {
+			dst = append(dst, byte(7<<5|distance>>8), byte(l-7))
+		}
+		dst = append(dst, byte(distance))
+		i += n
+	}
+	flush(len(src))
+	return dst
+}
+
+// FastLZDecompress decompresses a level 1 or level 2 FastLZ stream holding
+// size bytes.
+func FastLZDecompress(src []byte, size int) ([]byte, error) {
+	if len(src) == 0 {
+		return nil, ErrCorrupt
+	}
+	level := src[0]>>5 + 1
+	if level > 2 {
+		return nil, ErrCorrupt
+	}
+	// size comes with the data, matches expand at most 264 bytes from 2.
+	dst, err := fastlzDecompress(src, level, size, min(size, fastlzMaxLen*len(src)))
+	if err != nil {
+		return nil, err
+	}
+	if len(dst) != size {
+		return nil, ErrCorrupt
+	}
+	return dst, nil
+}
+
+// LZFCompress compresses src as LZF, the format of liblzf. A level 1
+// FastLZ stream starts with a literal run and is a valid LZF stream.
+func LZFCompress(src []byte) []byte {
+	return FastLZCompress(src)
+}
+
+// LZFDecompress decompresses an LZF stream, whose size is not stored.
+func LZFDecompress(src []byte) ([]byte, error) {
+	if len(src) == 0 || src[0] >= fastlzMaxCopy {
+		return nil, ErrCorrupt
+	}
+	return fastlzDecompress(src, 1, fastlzMaxLen*len(src), 2*len(src))
+}
+
+// fastlzDecompress decompresses a FastLZ stream of level into at most size
+// bytes, allocating capacity bytes up front.
+//
+//nolint:gocyclo
+func fastlzDecompress(src []byte, level byte, size, capacity int) ([]byte, error) {
+	dst := make([]byte, 0, capacity)
+	ip := 1
+	ctrl := int(src[0] & 31)
+	for {
+		if ctrl >= 32 {
+			n := ctrl>>5 - 1
+			offset := (ctrl & 31) << 8
+			if n == 6 {
+				for {
+					if ip >= len(src) {
+						return nil, ErrCorrupt
+					}
+					code := int(src[ip])
+					ip++
+					n += code
+					if level == 1 || code != 255 {
+						break
+					}
+				}
+			}
+			if ip >= len(src) {
+				return nil, ErrCorrupt
+			}
+			code := int(src[ip])
+			ip++
+			offset += code
+			if level == 2 && code == 255 && offset-code == 31<<8 {
+				if ip+2 > len(src) {
+					return nil, ErrCorrupt
+				}
+				offset = int(src[ip])<<8 | int(src[ip+1]) + fastlzMaxDistance - 1
+				ip += 2
+			}
+			n += 3
+			ref := len(dst) - offset - 1
+			if ref < 0 || len(dst)+n > size {
+				return nil, ErrCorrupt
+			}
+			// Matches may overlap the bytes they produce.
+			for i := 0; i < n; i++ {
+				dst = append(dst, dst[ref+i])
+			}
+		} else {
+			n := ctrl + 1
+			if ip+n > len(src) || len(dst)+n > size {
+				return nil, ErrCorrupt
+			}
+			dst = append(dst, src[ip:ip+n]...)
+			ip += n
+		}
+		if ip >= len(src) {
+			break
+		}
+		ctrl = int(src[ip])
+		ip++
+	}
+	return dst, nil
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,95 @@
+package lz
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/suite"
+	"math/rand"
+	"testing"
+)
+
+type FastLZSuite struct {
+	suite.Suite
+}
+
+func (Suite *FastLZSuite) TestDecompress() {
+	// "abc" followed by a match of 9 bytes at distance 3.
+	for _, Level := range []byte{0x00, 0x20} {
+		Decompressed, err := FastLZDecompress([]byte{Level | 0x02, 'a', 'b', 'c', 0xe0, 0x00, 0x02}, 12)
+		Suite.Nil(err)
+		Suite.Equal(`abcabcabcabc`, string(Decompressed))
+	}
+
+	// Level 2 encodes lengths past 264 with more bytes and distances past
+	// 8191 with an escape.
+	Src := make([]byte, 9000)
+	rand.New(rand.NewSource(1)).Read(Src[:8200])
+	copy(Src[8200:], Src[:800])
+	var Stream []byte
+	for i := 0; i < 8200; i += 32 {
+		n := min(8200-i, 32)
+		Stream = append(Stream, byte(n-1))
+		Stream = append(Stream, Src[i:i+n]...)
+	}
+	// 800 bytes at distance 8200: 3+6+255+255+255+26 and 8192+8.
+	Stream = append(Stream, 0xff, 255, 255, 255, 26, 0xff, 0, 8)
+	Stream[0] |= 0x20
+	Decompressed, err := FastLZDecompress(Stream, 9000)
+	Suite.Nil(err)
+	Suite.True(bytes.Equal(Src, Decompressed))
+}
+
+func (Suite *FastLZSuite) TestRoundTrip() {
+	Random := make([]byte, 5000)
+	rand.New(rand.NewSource(1)).Read(Random)
+	for _, Src := range [][]byte{
+		[]byte(`a`),
+		[]byte(`abcabcabcabc`),
+		bytes.Repeat([]byte(`igbinary`), 1000),
+		bytes.Repeat([]byte{0}, 100000),
+		Random,
+		append(Random, Random...),
+	} {
+		Compressed := FastLZCompress(Src)
+		Suite.Equal(byte(0), Compressed[0]>>5)
+		Decompressed, err := FastLZDecompress(Compressed, len(Src))
+		Suite.Nil(err)
+		Suite.True(bytes.Equal(Src, Decompressed))
+	}
+	Suite.Less(len(FastLZCompress(bytes.Repeat([]byte(`igbinary`), 1000))), 500)
+}
+
+func (Suite *FastLZSuite) TestCorrupt() {
+	for _, Src := range [][]byte{
+		nil,
+		{0x40},
+		{0x02, 'a'},
+		{0x00, 'a', 0x20, 0x05},
+		{0x00, 'a', 0xe0},
+	} {
+		_, err := FastLZDecompress(Src, 10)
+		Suite.Equal(ErrCorrupt, err)
+	}
+	_, err := FastLZDecompress([]byte{0x00, 'a'}, 2)
+	Suite.Equal(ErrCorrupt, err)
+}
+
+func (Suite *FastLZSuite) TestLZF() {
+	// lzf_compress('abcabcabcabc')
+	Decompressed, err := LZFDecompress([]byte{0x02, 'a', 'b', 'c', 0xe0, 0x00, 0x02})
+	Suite.Nil(err)
+	Suite.Equal(`abcabcabcabc`, string(Decompressed))
+
+	Src := bytes.Repeat([]byte(`igbinary`), 1000)
+	Decompressed, err = LZFDecompress(LZFCompress(Src))
+	Suite.Nil(err)
+	Suite.Equal(Src, Decompressed)
+
+	for _, Src := range [][]byte{nil, {0x40}, {0x02, 'a'}, {0x00, 'a', 0x20, 0x05}} {
+		_, err := LZFDecompress(Src)
+		Suite.Equal(ErrCorrupt, err)
+	}
+}
+
+func TestFastLZSuite(t *testing.T) {
+	suite.Run(t, new(FastLZSuite))
+}
@@ -0,0 +1,131 @@
+package lz
+
+import (
+	"encoding/binary"
+)
+
+// LZ4 block format limits.
+const (
+	lz4MinMatch     = 4
+	lz4LastLiterals = 5
+	lz4MatchLimit   = 12
+	lz4MaxDistance  = 65535
+	lz4HashLog      = 14
+)
+
+// LZ4Compress compresses src as an LZ4 block, without the frame format.
+func LZ4Compress(src []byte) []byte {
+	dst := make([]byte, 0, len(src)+len(src)/255+16)
+	table := make([]int, 1<<lz4HashLog)
+	anchor := 0
+	// The last match starts 12 bytes before the end at the latest and the
+	// last 5 bytes are literals.
+	for i := 0; i+lz4MatchLimit < len(src); {
+		v := binary.LittleEndian.Uint32(src[i:])
+		h := (v * 2654435761) >> (32 - lz4HashLog)
+		ref := table[h] - 1
+		table[h] = i + 1
+		if ref < 0 || i-ref > lz4MaxDistance || binary.LittleEndian.Uint32(src[ref:]) != v {
+			i++
+			continue
+		}
+		n := lz4MinMatch
+		for i+n < len(src)-lz4LastLiterals && src[ref+n] == src[i+n] {
+			n++
+		}
+		dst = lz4Sequence(dst, src[anchor:i], i-ref, n)
+		i += n
+		anchor = i
+	}
+	return lz4Sequence(dst, src[anchor:], 0, 0)
+}
+
+// lz4Sequence appends literals followed by a match of n bytes at offset, the
+// last sequence of a block has no match.
+func lz4Sequence(dst, literals []byte, offset, n int) []byte {
+	token := byte(min(len(literals), 15)) << 4
+	if n > 0 {
+		token |= byte(min(n-lz4MinMatch, 15))
+	}
+	dst = append(dst, token)
+	dst = lz4Length(dst, len(literals))
+	dst = append(dst, literals...)
+	if n == 0 {
+		return dst
+	}
+	dst = append(dst, byte(offset), byte(offset>>8))
+	return lz4Length(dst, n-lz4MinMatch)
+}
+
+// lz4Length appends the bytes extending a length of 15 or more.
+func lz4Length(dst []byte, n int) []byte {
+	if n < 15 {
+		return dst
+	}
+	for n -= 15; n >= 255; n -= 255 {
+		dst = append(dst, 255)
+	}
+	return append(dst, byte(n))
+}
+
+// LZ4Decompress decompresses an LZ4 block holding size bytes.
+func LZ4Decompress(src []byte, size int) ([]byte, error) {
+	dst := make([]byte, 0, min(size, 255*len(src)))
+	readLength := func(ip, n int) (int, int, error) {
+		if n < 15 {
+			return ip, n, nil
+		}
+		for {
+			if ip >= len(src) {
+				return 0, 0, ErrCorrupt
+			}
+			code := int(src[ip])
+			ip++
+			n += code
+			if code != 255 {
+				return ip, n, nil
+			}
+		}
+	}
+
+	for ip := 0; ; {
+		if ip >= len(src) {
+			return nil, ErrCorrupt
+		}
+		token := src[ip]
+		var n int
+		var err error
+		if ip, n, err = readLength(ip+1, int(token>>4)); err != nil {
+			return nil, err
+		}
+		if ip+n > len(src) || len(dst)+n > size {
+			return nil, ErrCorrupt
+		}
+		dst = append(dst, src[ip:ip+n]...)
+		ip += n
+		if ip == len(src) {
+			break
+		}
+
+		if ip+2 > len(src) {
+			return nil, ErrCorrupt
+		}
+		offset := int(src[ip]) | int(src[ip+1])<<8
+		if ip, n, err = readLength(ip+2, int(token&15)); err != nil {
+			return nil, err
+		}
+		n += lz4MinMatch
+		ref := len(dst) - offset
+		if offset == 0 || ref < 0 || len(dst)+n > size {
+			return nil, ErrCorrupt
+		}
+		// Matches may overlap the bytes they produce.
+		for i := 0; i < n; i++ {
+			dst = append(dst, dst[ref+i])
+		}
+	}
+	if len(dst) != size {
+		return nil, ErrCorrupt
+	}
+	return dst, nil
+}
@@ -0,0 +1,67 @@
+package lz
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/suite"
+	"math/rand"
+	"testing"
+)
+
+type LZ4Suite struct {
+	suite.Suite
+}
+
+func (Suite *LZ4Suite) TestDecompress() {
+	// "abcd" followed by a match of 16 bytes at offset 4, then 5 literals.
+	Decompressed, err := LZ4Decompress([]byte{0x4c, 'a', 'b', 'c', 'd', 0x04, 0x00, 0x50, 'v', 'w', 'x', 'y', 'z'}, 25)
+	Suite.Nil(err)
+	Suite.Equal(`abcdabcdabcdabcdabcdvwxyz`, string(Decompressed))
+
+	// Literal and match lengths from 15 take extra bytes.
+	Src := append(bytes.Repeat([]byte(`x`), 300), `tail.`...)
+	Stream := []byte{0x1f, 'x', 0x01, 0x00, 255, 25, 0x50}
+	Stream = append(Stream, `tail.`...)
+	Decompressed, err = LZ4Decompress(Stream, len(Src))
+	Suite.Nil(err)
+	Suite.Equal(Src, Decompressed)
+}
+
+func (Suite *LZ4Suite) TestRoundTrip() {
+	Random := make([]byte, 70000)
+	rand.New(rand.NewSource(1)).Read(Random)
+	for _, Src := range [][]byte{
+		nil,
+		[]byte(`a`),
+		[]byte(`abcdabcdabcdabcd`),
+		bytes.Repeat([]byte(`igbinary`), 1000),
+		bytes.Repeat([]byte{0}, 100000),
+		Random,
+		append(Random[:1000:1000], Random[:1000]...),
+	} {
+		Compressed := LZ4Compress(Src)
+		Decompressed, err := LZ4Decompress(Compressed, len(Src))
+		Suite.Nil(err)
+		Suite.True(bytes.Equal(Src, Decompressed))
+	}
+	Suite.Less(len(LZ4Compress(bytes.Repeat([]byte(`igbinary`), 1000))), 100)
+}
+
+func (Suite *LZ4Suite) TestCorrupt() {
+	for _, Src := range [][]byte{
+		nil,
+		{0x10},
+		{0x10, 'a', 0x00},
+		{0x10, 'a', 0x00, 0x00},
+		{0x10, 'a', 0x02, 0x00},
+		{0xf0, 255},
+	} {
+		_, err := LZ4Decompress(Src, 10)
+		Suite.Equal(ErrCorrupt, err)
+	}
+	_, err := LZ4Decompress([]byte{0x10, 'a'}, 2)
+	Suite.Equal(ErrCorrupt, err)
+}
+
+func TestLZ4Suite(t *testing.T) {
+	suite.Run(t, new(LZ4Suite))
+}
@@ -0,0 +1,92 @@
+// Package php holds the conversions the packages writing PHP extension
+// formats share: generic values and floats as PHP writes them in strings.
+package php
+
+import (
+	"github.com/zarken-go/igbinary"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Assign stores the generic value in v through an igbinary round trip, so v
+// may be of any type Unmarshal decodes into.
+func Assign(value, v interface{}) error {
+	b, err := igbinary.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return igbinary.Unmarshal(b, v)
+}
+
+// Generic returns v built from the generic igbinary types, which
+// phpserialize writes. Values of those types are returned as they are, so
+// an *igbinary.Object shared between values stays shared.
+func Generic(v interface{}) (interface{}, error) {
+	switch v.(type) {
+	case nil, bool, int64, int, float64, string, igbinary.Array, *igbinary.Object, *igbinary.SerializedObject:
+		return v, nil
+	}
+	var value interface{}
+	if err := Assign(v, &value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// FormatFloat formats f the way PHP converts floats to strings with
+// serialize_precision -1, as serialize and var_export do: the shortest
+// digits reading back as f, in exponential form such as 1.0E+25 from 1e17
+// on and below 1e-4.
+func FormatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return `INF`
+	case math.IsInf(f, -1):
+		return `-INF`
+	case math.IsNaN(f):
+		return `NAN`
+	}
+
+	s := strconv.FormatFloat(f, 'e', -1, 64)
+	var sign string
+	if s[0] == '-' {
+		sign, s = `-`, s[1:]
+	}
+	e := strings.IndexByte(s, 'e')
+	digits := strings.Replace(s[:e], `.`, ``, 1)
+	exp, _ := strconv.Atoi(s[e+1:])
+
+	// decpt is the position of the decimal point within digits.
+	decpt := exp + 1
+	switch {
+	case decpt < -3 || decpt > 17:
+		frac := digits[1:]
+		if frac == `` {
+			frac = `0`
+		}
+		expSign := `+`
+		if exp < 0 {
+			expSign, exp = `-`, -exp
+		}
+		return sign + digits[:1] + `.` + frac + `E` + expSign + strconv.Itoa(exp)
+	case decpt <= 0:
+		return sign + `0.` + strings.Repeat(`0`, -decpt) + digits
+	case decpt >= len(digits):
+		return sign + digits + strings.Repeat(`0`, decpt-len(digits))
+	}
+	return sign + digits[:decpt] + `.` + digits[decpt:]
+}
+
+// ParseFloat parses a float written by FormatFloat.
+func ParseFloat(s string) (float64, error) {
+	switch s {
+	case `INF`:
+		return math.Inf(1), nil
+	case `-INF`:
+		return math.Inf(-1), nil
+	case `NAN`:
+		return math.NaN(), nil
+	}
+	return strconv.ParseFloat(s, 64)
+}
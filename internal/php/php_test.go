@@ -0,0 +1,67 @@
+package php
+
+import (
+	"github.com/stretchr/testify/suite"
+	"github.com/zarken-go/igbinary"
+	"math"
+	"testing"
+)
+
+type PHPSuite struct {
+	suite.Suite
+}
+
+func (Suite *PHPSuite) TestFormatFloat() {
+	for F, Expected := range map[float64]string{
+		0:                   `0`,
+		1:                   `1`,
+		-1.5:                `-1.5`,
+		0.1:                 `0.1`,
+		1000000:             `1000000`,
+		1e15:                `1000000000000000`,
+		1e16:                `10000000000000000`,
+		1e17:                `1.0E+17`,
+		1e18:                `1.0E+18`,
+		1.5e25:              `1.5E+25`,
+		0.0001:              `0.0001`,
+		0.00012:             `0.00012`,
+		1e-5:                `1.0E-5`,
+		-2.5e-7:             `-2.5E-7`,
+		0.30000000000000004: `0.30000000000000004`,
+		math.MaxFloat64:     `1.7976931348623157E+308`,
+		math.Inf(1):         `INF`,
+		math.Inf(-1):        `-INF`,
+	} {
+		Suite.Equal(Expected, FormatFloat(F), Expected)
+		Parsed, err := ParseFloat(Expected)
+		Suite.Nil(err)
+		Suite.Equal(F, Parsed, Expected)
+	}
+	Suite.Equal(`-0`, FormatFloat(math.Copysign(0, -1)))
+	Suite.Equal(`NAN`, FormatFloat(math.NaN()))
+	Parsed, err := ParseFloat(`NAN`)
+	Suite.Nil(err)
+	Suite.True(math.IsNaN(Parsed))
+}
+
+func (Suite *PHPSuite) TestAssign() {
+	var Bytes []byte
+	Suite.Nil(Assign(`abc`, &Bytes))
+	Suite.Equal([]byte(`abc`), Bytes)
+
+	var Values map[string]int
+	Suite.Nil(Assign(igbinary.Array{{Key: `a`, Value: int64(1)}}, &Values))
+	Suite.Equal(map[string]int{`a`: 1}, Values)
+
+	Value, err := Generic(map[string]int{`a`: 1})
+	Suite.Nil(err)
+	Suite.Equal(igbinary.Array{{Key: `a`, Value: int64(1)}}, Value)
+	Object := &igbinary.Object{Class: `Foo`}
+	Value, err = Generic(Object)
+	Suite.Nil(err)
+	Suite.Same(Object, Value)
+}
+
+func TestPHPSuite(t *testing.T) {
+	suite.Run(t, new(PHPSuite))
+}
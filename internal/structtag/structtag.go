@@ -0,0 +1,27 @@
+// Package structtag reads the options of igbinary struct tags, shared by the
+// reflection based codec and igbinarygen so that both read a tag the same
+// way.
+package structtag
+
+import (
+	"github.com/vmihailenco/tagparser"
+	"strings"
+)
+
+// Option returns the value of the tag option name, given either as
+// name:value or name=value. The latter keeps backslashes and colons, as in
+// private=App\User or format=15:04.
+func Option(tag *tagparser.Tag, name string) (string, bool) {
+	if value, ok := tag.Options[name]; ok {
+		return value, true
+	}
+	for option, value := range tag.Options {
+		if strings.HasPrefix(option, name+"=") {
+			if value != "" {
+				return option[len(name)+1:] + ":" + value, true
+			}
+			return option[len(name)+1:], true
+		}
+	}
+	return "", false
+}
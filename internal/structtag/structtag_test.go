@@ -0,0 +1,32 @@
+package structtag
+
+import (
+	"github.com/stretchr/testify/suite"
+	"github.com/vmihailenco/tagparser"
+	"testing"
+)
+
+type TagSuite struct {
+	suite.Suite
+}
+
+func (Suite *TagSuite) TestOption() {
+	for _, Test := range []struct {
+		Tag, Name, Expected string
+	}{
+		{`t,format:15`, `format`, `15`},
+		{`t,format=15:04`, `format`, `15:04`},
+		{`t,format=2006-01-02T15:04:05Z07:00`, `format`, `2006-01-02T15:04:05Z07:00`},
+		{`t,private=App\User`, `private`, `App\User`},
+	} {
+		Value, ok := Option(tagparser.Parse(Test.Tag), Test.Name)
+		Suite.True(ok, Test.Tag)
+		Suite.Equal(Test.Expected, Value, Test.Tag)
+	}
+	_, ok := Option(tagparser.Parse(`t,protected`), `private`)
+	Suite.False(ok)
+}
+
+func TestTagSuite(t *testing.T) {
+	suite.Run(t, new(TagSuite))
+}
@@ -0,0 +1,349 @@
+// Package laravel decrypts and encrypts the payloads of Laravel's
+// Encrypter, which protects encrypted cookies and the data of encrypted
+// sessions, and decodes the igbinary or serialize() values inside.
+//
+// A payload is base64 encoded JSON {iv, value, mac, tag}: value is the
+// base64 encoded ciphertext, mac an HMAC-SHA256 of iv and value for the CBC
+// ciphers and tag the authentication tag of the GCM ciphers.
+package laravel
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/zarken-go/igbinary"
+	"github.com/zarken-go/igbinary/internal/php"
+	"github.com/zarken-go/igbinary/phpserialize"
+	"net/url"
+	"strings"
+)
+
+// Ciphers of config/app.php.
+const (
+	AES128CBC = `aes-128-cbc`
+	AES256CBC = `aes-256-cbc`
+	AES128GCM = `aes-128-gcm`
+	AES256GCM = `aes-256-gcm`
+)
+
+// Errors of payloads that do not decrypt, named after Laravel's
+// DecryptException messages.
+var (
+	ErrInvalidPayload = errors.New(`laravel: the payload is invalid`)
+	ErrInvalidMAC     = errors.New(`laravel: the MAC is invalid`)
+	ErrDecrypt        = errors.New(`laravel: could not decrypt the data`)
+)
+
+// cookiePrefixLen is the length of the prefix EncryptCookies puts in front
+// of cookie values, a hex HMAC-SHA1 and a '|'.
+const cookiePrefixLen = 2*sha1.Size + 1
+
+type payload struct {
+	IV    string `json:"iv"`
+	Value string `json:"value"`
+	MAC   string `json:"mac"`
+	Tag   string `json:"tag"`
+}
+
+// Encrypter encrypts and decrypts payloads like Laravel's Encrypter with
+// the same key and cipher.
+type Encrypter struct {
+	key          []byte
+	cipher       string
+	previousKeys [][]byte
+}
+
+// ParseKey returns the key of an APP_KEY setting, base64 encoded after a
+// "base64:" prefix or raw otherwise.
+func ParseKey(appKey string) ([]byte, error) {
+	if !strings.HasPrefix(appKey, `base64:`) {
+		return []byte(appKey), nil
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(appKey, `base64:`))
+	if err != nil {
+		return nil, fmt.Errorf(`laravel: invalid base64 key: %w`, err)
+	}
+	return key, nil
+}
+
+// NewEncrypter returns an Encrypter for key and cipher, one of the cipher
+// constants. The key must be 16 bytes long for the AES-128 ciphers and 32
+// for the AES-256 ones.
+func NewEncrypter(key []byte, cipher string) (*Encrypter, error) {
+	if err := checkKey(key, cipher); err != nil {
+		return nil, err
+	}
+	return &Encrypter{key: key, cipher: strings.ToLower(cipher)}, nil
+}
+
+func checkKey(key []byte, cipher string) error {
+	var size int
+	switch strings.ToLower(cipher) {
+	case AES128CBC, AES128GCM:
+		size = 16
+	case AES256CBC, AES256GCM:
+		size = 32
+	default:
+		return fmt.Errorf(`laravel: unsupported cipher %q`, cipher)
+	}
+	if len(key) != size {
+		return fmt.Errorf(`laravel: %s needs a key of %d bytes, got %d`, cipher, size, len(key))
+	}
+	return nil
+}
+
+// SetPreviousKeys sets the keys of APP_PREVIOUS_KEYS, which payloads are
+// also decrypted with after a key rotation. Payloads are always encrypted
+// with the current key.
+func (e *Encrypter) SetPreviousKeys(keys ...[]byte) error {
+	for _, key := range keys {
+		if err := checkKey(key, e.cipher); err != nil {
+			return err
+		}
+	}
+	e.previousKeys = keys
+	return nil
+}
+
+func (e *Encrypter) aead() bool {
+	return e.cipher == AES128GCM || e.cipher == AES256GCM
+}
+
+// EncryptString encrypts value as is, like encryptString.
+func (e *Encrypter) EncryptString(value []byte) (string, error) {
+	block, err := aes.NewCipher(e.key)
+	if err != nil {
+		return ``, err
+	}
+
+	var p payload
+	if e.aead() {
+		gcm, err := cipher.NewGCM(block)
+		if err != nil {
+			return ``, err
+		}
+		iv := make([]byte, gcm.NonceSize())
+		if _, err := rand.Read(iv); err != nil {
+			return ``, err
+		}
+		sealed := gcm.Seal(nil, iv, value, nil)
+		tagAt := len(sealed) - gcm.Overhead()
+		p.IV = base64.StdEncoding.EncodeToString(iv)
+		p.Value = base64.StdEncoding.EncodeToString(sealed[:tagAt])
+		p.Tag = base64.StdEncoding.EncodeToString(sealed[tagAt:])
+	} else {
+		iv := make([]byte, aes.BlockSize)
+		if _, err := rand.Read(iv); err != nil {
+			return ``, err
+		}
+		padding := aes.BlockSize - len(value)%aes.BlockSize
+		ciphertext := append(append([]byte(nil), value...), bytes.Repeat([]byte{byte(padding)}, padding)...)
+		cipher.NewCBCEncrypter(block, iv).CryptBlocks(ciphertext, ciphertext)
+		p.IV = base64.StdEncoding.EncodeToString(iv)
+		p.Value = base64.StdEncoding.EncodeToString(ciphertext)
+		p.MAC = hex.EncodeToString(mac(e.key, p.IV, p.Value))
+	}
+
+	b, err := json.Marshal(p)
+	if err != nil {
+		return ``, err
+	}
+	return base64.StdEncoding.EncodeToString(b), nil
+}
+
+// DecryptString verifies and decrypts a payload, like decryptString.
+func (e *Encrypter) DecryptString(s string) ([]byte, error) {
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidPayload
+	}
+	var p payload
+	if err := json.Unmarshal(b, &p); err != nil || p.IV == `` || p.Value == `` {
+		return nil, ErrInvalidPayload
+	}
+	iv, err := base64.StdEncoding.DecodeString(p.IV)
+	if err != nil {
+		return nil, ErrInvalidPayload
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(p.Value)
+	if err != nil {
+		return nil, ErrInvalidPayload
+	}
+
+	err = ErrDecrypt
+	for _, key := range append([][]byte{e.key}, e.previousKeys...) {
+		var value []byte
+		if e.aead() {
+			value, err = decryptGCM(key, iv, ciphertext, p.Tag)
+		} else {
+			value, err = decryptCBC(key, iv, ciphertext, p)
+		}
+		if err == nil || err == ErrInvalidPayload {
+			return value, err
+		}
+	}
+	return nil, err
+}
+
+func decryptCBC(key, iv, ciphertext []byte, p payload) ([]byte, error) {
+	if len(iv) != aes.BlockSize || len(ciphertext) == 0 || len(ciphertext)%aes.BlockSize != 0 {
+		return nil, ErrInvalidPayload
+	}
+	expected, err := hex.DecodeString(p.MAC)
+	if err != nil || !hmac.Equal(expected, mac(key, p.IV, p.Value)) {
+		return nil, ErrInvalidMAC
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	value := make([]byte, len(ciphertext))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(value, ciphertext)
+	padding := int(value[len(value)-1])
+	if padding == 0 || padding > aes.BlockSize ||
+		!bytes.Equal(value[len(value)-padding:], bytes.Repeat([]byte{byte(padding)}, padding)) {
+		return nil, ErrDecrypt
+	}
+	return value[:len(value)-padding], nil
+}
+
+func decryptGCM(key, iv, ciphertext []byte, tag string) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	t, err := base64.StdEncoding.DecodeString(tag)
+	if err != nil || len(iv) != gcm.NonceSize() || len(t) != gcm.Overhead() {
+		return nil, ErrInvalidPayload
+	}
+	value, err := gcm.Open(nil, iv, append(append([]byte(nil), ciphertext...), t...), nil)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return value, nil
+}
+
+// mac returns the HMAC-SHA256 Laravel keeps of the base64 encoded iv and
+// value.
+func mac(key []byte, iv, value string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(iv))
+	h.Write([]byte(value))
+	return h.Sum(nil)
+}
+
+// Encrypt serializes v with PHP's serialize format and encrypts it, like
+// encrypt.
+func (e *Encrypter) Encrypt(v interface{}) (string, error) {
+	b, err := serialize(v)
+	if err != nil {
+		return ``, err
+	}
+	return e.EncryptString(b)
+}
+
+// Decrypt decrypts a payload of encrypt and decodes its value into v, as
+// igbinary.Unmarshal does. The value is read as igbinary when it starts
+// with an igbinary header and as PHP's serialize format otherwise.
+func (e *Encrypter) Decrypt(s string, v interface{}) error {
+	b, err := e.DecryptString(s)
+	if err != nil {
+		return err
+	}
+	return decode(b, v)
+}
+
+// EncryptSession encrypts session attributes v the way an encrypted
+// session store writes them: serialized, then encrypted as a serialized
+// string.
+func (e *Encrypter) EncryptSession(v interface{}) (string, error) {
+	b, err := serialize(v)
+	if err != nil {
+		return ``, err
+	}
+	return e.Encrypt(string(b))
+}
+
+// DecryptSession decrypts the data of an encrypted session store and
+// decodes its attributes into v. The attributes may be serialized with
+// igbinary, serialize or, for the json session serialization, JSON.
+func (e *Encrypter) DecryptSession(s string, v interface{}) error {
+	var data string
+	if err := e.Decrypt(s, &data); err != nil {
+		return err
+	}
+	b := []byte(data)
+	if len(b) > 0 && (b[0] == '{' || b[0] == '[') {
+		return json.Unmarshal(b, v)
+	}
+	return decode(b, v)
+}
+
+// EncryptCookie encrypts the value of cookie name like the EncryptCookies
+// middleware: prefixed with an HMAC of the name and not serialized.
+func (e *Encrypter) EncryptCookie(name, value string) (string, error) {
+	return e.EncryptString([]byte(cookiePrefix(e.key, name) + value))
+}
+
+// DecryptCookie decrypts the value of cookie name set by the
+// EncryptCookies middleware and checks that it was set for name. URL
+// escaped values are unescaped first.
+func (e *Encrypter) DecryptCookie(name, value string) (string, error) {
+	if strings.Contains(value, `%`) {
+		unescaped, err := url.QueryUnescape(value)
+		if err != nil {
+			return ``, ErrInvalidPayload
+		}
+		value = unescaped
+	}
+	b, err := e.DecryptString(value)
+	if err != nil {
+		return ``, err
+	}
+	for _, key := range append([][]byte{e.key}, e.previousKeys...) {
+		prefix := cookiePrefix(key, name)
+		if len(b) >= cookiePrefixLen && hmac.Equal(b[:cookiePrefixLen], []byte(prefix)) {
+			return string(b[cookiePrefixLen:]), nil
+		}
+	}
+	return ``, fmt.Errorf(`laravel: cookie value was not set for %q`, name)
+}
+
+// cookiePrefix returns the prefix of CookieValuePrefix::create.
+func cookiePrefix(key []byte, name string) string {
+	h := hmac.New(sha1.New, key)
+	h.Write([]byte(name + `v2`))
+	return hex.EncodeToString(h.Sum(nil)) + `|`
+}
+
+func serialize(v interface{}) ([]byte, error) {
+	value, err := php.Generic(v)
+	if err != nil {
+		return nil, err
+	}
+	return phpserialize.Marshal(value)
+}
+
+func decode(b []byte, v interface{}) error {
+	if igbinary.HasHeader(b) {
+		return igbinary.Unmarshal(b, v)
+	}
+	value, err := phpserialize.Unmarshal(b)
+	if err != nil {
+		return err
+	}
+	return php.Assign(value, v)
+}
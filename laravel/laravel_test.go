@@ -0,0 +1,197 @@
+package laravel
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"github.com/stretchr/testify/suite"
+	"github.com/zarken-go/igbinary"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// testKey is APP_KEY=base64:AAECAwQFBgcICQoLDA0ODxAREhMUFRYXGBkaGxwdHh8=
+const testKey = `base64:AAECAwQFBgcICQoLDA0ODxAREhMUFRYXGBkaGxwdHh8=`
+
+// testPayload is encrypt('hello') with testKey and AES-256-CBC.
+const testPayload = `eyJpdiI6Im9LR2lvNlNscHFlb3FhcXJySzJ1cnc9PSIsInZhbHVlIjoiUkgyazAxeUR5MTZBdXBNaVBHVG1yUT09IiwibWFjIjoiOWVjNjk2ZmNlYTBlOTA0OWU5YmFmMmY5MzAxMWRjZTI2MjQwNmQzZDU3NDViMzAyMmQ3ZDljN2Q5MzRjZWQ2MCIsInRhZyI6IiJ9`
+
+type testSession struct {
+	Token  string `igbinary:"_token" json:"_token"`
+	UserID int64  `igbinary:"user_id" json:"user_id"`
+}
+
+type LaravelSuite struct {
+	suite.Suite
+	Encrypter *Encrypter
+}
+
+func (Suite *LaravelSuite) SetupTest() {
+	Key, err := ParseKey(testKey)
+	Suite.Require().Nil(err)
+	Suite.Encrypter, err = NewEncrypter(Key, AES256CBC)
+	Suite.Require().Nil(err)
+}
+
+// edit decodes payload s, applies fn to it and encodes it again.
+func (Suite *LaravelSuite) edit(s string, fn func(p *payload)) string {
+	b, err := base64.StdEncoding.DecodeString(s)
+	Suite.Require().Nil(err)
+	var p payload
+	Suite.Require().Nil(json.Unmarshal(b, &p))
+	fn(&p)
+	b, err = json.Marshal(p)
+	Suite.Require().Nil(err)
+	return base64.StdEncoding.EncodeToString(b)
+}
+
+func (Suite *LaravelSuite) TestDecrypt() {
+	var S string
+	Suite.Nil(Suite.Encrypter.Decrypt(testPayload, &S))
+	Suite.Equal(`hello`, S)
+
+	B, err := Suite.Encrypter.DecryptString(testPayload)
+	Suite.Nil(err)
+	Suite.Equal(`s:5:"hello";`, string(B))
+
+	_, err = Suite.Encrypter.DecryptString(Suite.edit(testPayload, func(p *payload) {
+		p.MAC = strings.Repeat(`0`, 64)
+	}))
+	Suite.Equal(ErrInvalidMAC, err)
+	_, err = Suite.Encrypter.DecryptString(Suite.edit(testPayload, func(p *payload) {
+		p.IV = `AAAA`
+	}))
+	Suite.Equal(ErrInvalidPayload, err)
+	_, err = Suite.Encrypter.DecryptString(`not base64!`)
+	Suite.Equal(ErrInvalidPayload, err)
+	_, err = Suite.Encrypter.DecryptString(base64.StdEncoding.EncodeToString([]byte(`{"iv":""}`)))
+	Suite.Equal(ErrInvalidPayload, err)
+
+	Other, err := NewEncrypter(make([]byte, 32), AES256CBC)
+	Suite.Require().Nil(err)
+	_, err = Other.DecryptString(testPayload)
+	Suite.Equal(ErrInvalidMAC, err)
+}
+
+func (Suite *LaravelSuite) TestRoundTrip() {
+	for _, Test := range []struct {
+		Cipher string
+		Key    int
+	}{
+		{AES128CBC, 16},
+		{AES256CBC, 32},
+		{AES128GCM, 16},
+		{AES256GCM, 32},
+	} {
+		Encrypter, err := NewEncrypter([]byte(strings.Repeat(`k`, Test.Key)), Test.Cipher)
+		Suite.Require().Nil(err)
+		Session := testSession{Token: `abc`, UserID: 7}
+		S, err := Encrypter.Encrypt(Session)
+		Suite.Require().Nil(err)
+
+		var Decoded testSession
+		Suite.Nil(Encrypter.Decrypt(S, &Decoded))
+		Suite.Equal(Session, Decoded)
+
+		B, err := base64.StdEncoding.DecodeString(S)
+		Suite.Require().Nil(err)
+		var P payload
+		Suite.Require().Nil(json.Unmarshal(B, &P))
+		if Test.Cipher == AES128GCM || Test.Cipher == AES256GCM {
+			Suite.Empty(P.MAC)
+			Suite.NotEmpty(P.Tag)
+			_, err = Encrypter.DecryptString(Suite.edit(S, func(p *payload) {
+				p.Tag = base64.StdEncoding.EncodeToString(make([]byte, 16))
+			}))
+			Suite.Equal(ErrDecrypt, err)
+		} else {
+			Suite.Len(P.MAC, 64)
+			Suite.Empty(P.Tag)
+		}
+	}
+
+	_, err := NewEncrypter(make([]byte, 16), AES256CBC)
+	Suite.EqualError(err, `laravel: aes-256-cbc needs a key of 32 bytes, got 16`)
+	_, err = NewEncrypter(make([]byte, 16), `des`)
+	Suite.EqualError(err, `laravel: unsupported cipher "des"`)
+}
+
+func (Suite *LaravelSuite) TestIgbinaryValue() {
+	B, err := igbinary.Marshal(testSession{Token: `abc`, UserID: 7})
+	Suite.Require().Nil(err)
+	S, err := Suite.Encrypter.EncryptString(B)
+	Suite.Require().Nil(err)
+
+	var Decoded testSession
+	Suite.Nil(Suite.Encrypter.Decrypt(S, &Decoded))
+	Suite.Equal(testSession{Token: `abc`, UserID: 7}, Decoded)
+}
+
+func (Suite *LaravelSuite) TestSession() {
+	Session := testSession{Token: `abc`, UserID: 7}
+	S, err := Suite.Encrypter.EncryptSession(Session)
+	Suite.Require().Nil(err)
+
+	B, err := Suite.Encrypter.DecryptString(S)
+	Suite.Require().Nil(err)
+	Suite.Equal(`s:47:"a:2:{s:6:"_token";s:3:"abc";s:7:"user_id";i:7;}";`, string(B))
+
+	var Decoded testSession
+	Suite.Nil(Suite.Encrypter.DecryptSession(S, &Decoded))
+	Suite.Equal(Session, Decoded)
+
+	// 'serialization' => 'json'
+	S, err = Suite.Encrypter.Encrypt(`{"_token":"abc","user_id":7}`)
+	Suite.Require().Nil(err)
+	Decoded = testSession{}
+	Suite.Nil(Suite.Encrypter.DecryptSession(S, &Decoded))
+	Suite.Equal(Session, Decoded)
+}
+
+func (Suite *LaravelSuite) TestCookie() {
+	S, err := Suite.Encrypter.EncryptCookie(`laravel_session`, `session-id`)
+	Suite.Require().Nil(err)
+
+	B, err := Suite.Encrypter.DecryptString(S)
+	Suite.Require().Nil(err)
+	Suite.Equal(`7198b0a1a7564e003b468667d6241918ce65aafa|session-id`, string(B))
+
+	Value, err := Suite.Encrypter.DecryptCookie(`laravel_session`, url.QueryEscape(S))
+	Suite.Nil(err)
+	Suite.Equal(`session-id`, Value)
+
+	_, err = Suite.Encrypter.DecryptCookie(`XSRF-TOKEN`, S)
+	Suite.EqualError(err, `laravel: cookie value was not set for "XSRF-TOKEN"`)
+}
+
+func (Suite *LaravelSuite) TestPreviousKeys() {
+	Encrypter, err := NewEncrypter([]byte(strings.Repeat(`n`, 32)), AES256CBC)
+	Suite.Require().Nil(err)
+	Key, err := ParseKey(testKey)
+	Suite.Require().Nil(err)
+	Suite.Nil(Encrypter.SetPreviousKeys(Key))
+
+	var S string
+	Suite.Nil(Encrypter.Decrypt(testPayload, &S))
+	Suite.Equal(`hello`, S)
+
+	Cookie, err := Suite.Encrypter.EncryptCookie(`name`, `value`)
+	Suite.Require().Nil(err)
+	Value, err := Encrypter.DecryptCookie(`name`, Cookie)
+	Suite.Nil(err)
+	Suite.Equal(`value`, Value)
+
+	Suite.EqualError(Encrypter.SetPreviousKeys([]byte(`short`)), `laravel: aes-256-cbc needs a key of 32 bytes, got 5`)
+}
+
+func (Suite *LaravelSuite) TestParseKey() {
+	Key, err := ParseKey(`0123456789abcdef`)
+	Suite.Nil(err)
+	Suite.Equal([]byte(`0123456789abcdef`), Key)
+	_, err = ParseKey(`base64:!`)
+	Suite.Error(err)
+}
+
+func TestLaravelSuite(t *testing.T) {
+	suite.Run(t, new(LaravelSuite))
+}
@@ -0,0 +1,27 @@
+package igbinary
+
+import (
+	"strings"
+)
+
+// MangleProperty returns the key PHP serializes a property under. class is
+// empty for public properties, "*" for protected ones and the declaring
+// class for private ones, which PHP writes as "\0*\0name" and
+// "\0Class\0name".
+func MangleProperty(class, name string) string {
+	if class == `` {
+		return name
+	}
+	return "\x00" + class + "\x00" + name
+}
+
+// DemangleProperty splits a serialized property key into the visibility
+// class as taken by MangleProperty and the property name.
+func DemangleProperty(key string) (class, name string) {
+	if len(key) > 0 && key[0] == 0 {
+		if i := strings.IndexByte(key[1:], 0); i >= 0 {
+			return key[1 : i+1], key[i+2:]
+		}
+	}
+	return ``, key
+}
@@ -0,0 +1,98 @@
+package igbinary
+
+import (
+	"github.com/stretchr/testify/suite"
+	"testing"
+)
+
+type MangleSuite struct {
+	suite.Suite
+}
+
+type testAccount struct {
+	_       struct{} `igbinary:",class=App\\Account"`
+	ID      int64    `igbinary:"id"`
+	Balance int64    `igbinary:"balance,protected"`
+	Token   string   `igbinary:"token,private"`
+	Owner   string   `igbinary:"owner,private=App\\Model"`
+}
+
+type testProfile struct {
+	Name  string `igbinary:"name"`
+	Email string `igbinary:"email"`
+}
+
+func (Suite *MangleSuite) TestMangleProperty() {
+	Suite.Equal(`name`, MangleProperty(``, `name`))
+	Suite.Equal("\x00*\x00name", MangleProperty(`*`, `name`))
+	Suite.Equal("\x00App\\User\x00name", MangleProperty(`App\User`, `name`))
+
+	for _, Key := range []string{`name`, "\x00*\x00name", "\x00App\\User\x00name", "\x00broken"} {
+		Class, Name := DemangleProperty(Key)
+		if Key != "\x00broken" {
+			Suite.Equal(Key, MangleProperty(Class, Name))
+		} else {
+			Suite.Equal(``, Class)
+			Suite.Equal(Key, Name)
+		}
+	}
+}
+
+func (Suite *MangleSuite) TestEncodeObject() {
+	B, err := Marshal(testAccount{ID: 1, Balance: 2, Token: `t`, Owner: `o`})
+	Suite.Require().Nil(err)
+
+	var Value interface{}
+	Suite.Require().Nil(Unmarshal(B, &Value))
+	Suite.Require().IsType(&Object{}, Value)
+	Object := Value.(*Object)
+	Suite.Equal(`App\Account`, Object.Class)
+	Suite.Equal(Array{
+		{Key: `id`, Value: int64(1)},
+		{Key: "\x00*\x00balance", Value: int64(2)},
+		{Key: "\x00App\\Account\x00token", Value: `t`},
+		{Key: "\x00App\\Model\x00owner", Value: `o`},
+	}, Object.Props)
+
+	var Account testAccount
+	Suite.Nil(Unmarshal(B, &Account))
+	Suite.Equal(testAccount{ID: 1, Balance: 2, Token: `t`, Owner: `o`}, Account)
+}
+
+func (Suite *MangleSuite) TestDecodeVisibility() {
+	// Fields with a visibility only match keys carrying it.
+	B, err := Marshal(&Object{Class: `App\Account`, Props: Array{
+		{Key: "\x00*\x00id", Value: int64(1)},
+		{Key: "balance", Value: int64(2)},
+		{Key: "\x00Other\x00token", Value: `t`},
+		{Key: "\x00App\\Model\x00owner", Value: `o`},
+	}})
+	Suite.Require().Nil(err)
+
+	var Account testAccount
+	Suite.Nil(Unmarshal(B, &Account))
+	Suite.Equal(testAccount{ID: 1, Owner: `o`}, Account)
+}
+
+func (Suite *MangleSuite) TestDecodeDemangled() {
+	// Plain structs decode objects and arrays, whatever the visibility.
+	B, err := Marshal(&Object{Class: `User`, Props: Array{
+		{Key: "\x00User\x00name", Value: `Ann`},
+		{Key: "\x00*\x00email", Value: `ann@example.com`},
+	}})
+	Suite.Require().Nil(err)
+
+	var Profile testProfile
+	Suite.Nil(Unmarshal(B, &Profile))
+	Suite.Equal(testProfile{Name: `Ann`, Email: `ann@example.com`}, Profile)
+
+	B, err = Marshal(testProfile{Name: `Bob`})
+	Suite.Require().Nil(err)
+	Profile = testProfile{}
+	Suite.Nil(Unmarshal(B, &Profile))
+	Suite.Equal(testProfile{Name: `Bob`}, Profile)
+}
+
+func TestMangleSuite(t *testing.T) {
+	suite.Run(t, new(MangleSuite))
+}
@@ -0,0 +1,256 @@
+// Package memcached encodes values the way PHP's memcached extension stores
+// them: a payload along with item flags naming its type, serializer and
+// compression. Values written by either side read on the other.
+//
+// The flags and payload are what a memcached client stores as an item,
+// this package does not talk to memcached itself.
+package memcached
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"github.com/zarken-go/igbinary"
+	"github.com/zarken-go/igbinary/internal/lz"
+	"github.com/zarken-go/igbinary/internal/php"
+	"github.com/zarken-go/igbinary/phpserialize"
+	"io/ioutil"
+	"math"
+	"reflect"
+	"strconv"
+)
+
+// Value types of the low 4 bits of the flags.
+const (
+	typeMask       = 0xf
+	typeString     = 0
+	typeLong       = 1
+	typeDouble     = 2
+	typeBool       = 3
+	typeSerialized = 4
+	typeIgbinary   = 5
+	typeJSON       = 6
+	typeMsgpack    = 7
+)
+
+// Internal flags, stored in bits 4 to 15.
+const (
+	flagCompressed        = 1 << 4
+	flagCompressionZlib   = 2 << 4
+	flagCompressionFastLZ = 4 << 4
+)
+
+// Serializer selects how values other than strings, integers, floats and
+// booleans are stored.
+type Serializer uint8
+
+const (
+	// Igbinary is the extension's default when built with igbinary.
+	Igbinary Serializer = iota
+	// PHP stores values with serialize().
+	PHP
+	// JSON stores values as JSON, decoding to arrays on the PHP side.
+	JSON
+)
+
+// Compression selects the algorithm payloads are compressed with.
+type Compression uint8
+
+const (
+	NoCompression Compression = iota
+	// FastLZ is the extension's default compression.
+	FastLZ
+	// Zlib compresses better at a higher cost.
+	Zlib
+)
+
+// Codec converts Go values to memcached items and back. The zero Codec
+// stores values with igbinary, uncompressed.
+type Codec struct {
+	Serializer  Serializer
+	Compression Compression
+	// CompressionThreshold is the payload size from which payloads are
+	// compressed, CompressionFactor the ratio by which compression must
+	// shrink them to be stored compressed.
+	CompressionThreshold int
+	CompressionFactor    float64
+	// UserFlags are stored in the high 16 bits of the flags, as set by
+	// Memcached::OPT_USER_FLAGS.
+	UserFlags uint16
+}
+
+// NewCodec returns a Codec using the defaults of the memcached extension:
+// igbinary, FastLZ compression from 2000 bytes and a factor of 1.3.
+func NewCodec() Codec {
+	return Codec{
+		Serializer:           Igbinary,
+		Compression:          FastLZ,
+		CompressionThreshold: 2000,
+		CompressionFactor:    1.3,
+	}
+}
+
+// UserFlags returns the user flags stored in flags.
+func UserFlags(flags uint32) uint16 {
+	return uint16(flags >> 16)
+}
+
+// Encode returns the flags and payload of v. Strings and []byte, integers,
+// floats and booleans are stored as text like the extension does, other
+// values go through the Serializer.
+func (c Codec) Encode(v interface{}) (uint32, []byte, error) {
+	flags, payload, err := c.payload(v)
+	if err != nil {
+		return 0, nil, err
+	}
+	flags |= uint32(c.UserFlags) << 16
+	if c.Compression == NoCompression || len(payload) < c.CompressionThreshold {
+		return flags, payload, nil
+	}
+
+	var compressed bytes.Buffer
+	var size [4]byte
+	binary.LittleEndian.PutUint32(size[:], uint32(len(payload)))
+	compressed.Write(size[:])
+	if c.Compression == FastLZ {
+		compressed.Write(lz.FastLZCompress(payload))
+	} else {
+		w := zlib.NewWriter(&compressed)
+		if _, err := w.Write(payload); err != nil {
+			return 0, nil, err
+		}
+		if err := w.Close(); err != nil {
+			return 0, nil, err
+		}
+	}
+
+	factor := c.CompressionFactor
+	if factor == 0 {
+		factor = 1
+	}
+	if float64(compressed.Len()-len(size))*factor >= float64(len(payload)) {
+		return flags, payload, nil
+	}
+	flags |= flagCompressed
+	if c.Compression == FastLZ {
+		flags |= flagCompressionFastLZ
+	} else {
+		flags |= flagCompressionZlib
+	}
+	return flags, compressed.Bytes(), nil
+}
+
+func (c Codec) payload(v interface{}) (uint32, []byte, error) {
+	switch v := v.(type) {
+	case string:
+		return typeString, []byte(v), nil
+	case []byte:
+		return typeString, v, nil
+	case bool:
+		if v {
+			return typeBool, []byte(`1`), nil
+		}
+		return typeBool, []byte{}, nil
+	}
+
+	switch rv := reflect.ValueOf(v); rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return typeLong, strconv.AppendInt(nil, rv.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if rv.Uint() <= math.MaxInt64 {
+			return typeLong, strconv.AppendUint(nil, rv.Uint(), 10), nil
+		}
+	case reflect.Float32, reflect.Float64:
+		return typeDouble, []byte(php.FormatFloat(rv.Float())), nil
+	}
+
+	switch c.Serializer {
+	case PHP:
+		value, err := php.Generic(v)
+		if err != nil {
+			return 0, nil, err
+		}
+		b, err := phpserialize.Marshal(value)
+		return typeSerialized, b, err
+	case JSON:
+		b, err := json.Marshal(v)
+		return typeJSON, b, err
+	}
+	b, err := igbinary.Marshal(v)
+	return typeIgbinary, b, err
+}
+
+// Decode decodes the payload of an item with flags into v, as
+// igbinary.Unmarshal does.
+func (c Codec) Decode(flags uint32, data []byte, v interface{}) error {
+	payload, err := decompress(flags, data)
+	if err != nil {
+		return err
+	}
+
+	switch flags & typeMask {
+	case typeIgbinary:
+		return igbinary.Unmarshal(payload, v)
+	case typeJSON:
+		return json.Unmarshal(payload, v)
+	case typeSerialized:
+		value, err := phpserialize.Unmarshal(payload)
+		if err != nil {
+			return err
+		}
+		return php.Assign(value, v)
+	case typeString:
+		if b, ok := v.(*[]byte); ok {
+			*b = append([]byte(nil), payload...)
+			return nil
+		}
+		return php.Assign(string(payload), v)
+	case typeLong:
+		n, err := strconv.ParseInt(string(payload), 10, 64)
+		if err != nil {
+			return fmt.Errorf(`memcached: invalid integer %q`, payload)
+		}
+		return php.Assign(n, v)
+	case typeDouble:
+		f, err := php.ParseFloat(string(payload))
+		if err != nil {
+			return fmt.Errorf(`memcached: invalid float %q`, payload)
+		}
+		return php.Assign(f, v)
+	case typeBool:
+		return php.Assign(len(payload) > 0 && string(payload) != `0`, v)
+	case typeMsgpack:
+		return fmt.Errorf(`memcached: msgpack values are not supported`)
+	}
+	return fmt.Errorf(`memcached: unsupported value type %d`, flags&typeMask)
+}
+
+func decompress(flags uint32, data []byte) ([]byte, error) {
+	if flags&flagCompressed == 0 {
+		return data, nil
+	}
+	if len(data) < 4 {
+		return nil, fmt.Errorf(`memcached: compressed value of %d bytes`, len(data))
+	}
+	size := binary.LittleEndian.Uint32(data)
+	switch {
+	case flags&flagCompressionFastLZ != 0:
+		return lz.FastLZDecompress(data[4:], int(size))
+	case flags&flagCompressionZlib != 0:
+		r, err := zlib.NewReader(bytes.NewReader(data[4:]))
+		if err != nil {
+			return nil, err
+		}
+		b, err := ioutil.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		if len(b) != int(size) {
+			return nil, fmt.Errorf(`memcached: decompressed %d bytes, expected %d`, len(b), size)
+		}
+		return b, nil
+	}
+	return nil, fmt.Errorf(`memcached: unknown compression in flags %#x`, flags)
+}
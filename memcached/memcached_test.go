@@ -0,0 +1,318 @@
+package memcached
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"github.com/stretchr/testify/suite"
+	"github.com/zarken-go/igbinary"
+	"github.com/zarken-go/igbinary/internal/lz"
+	"io"
+	"math"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type item struct {
+	flags uint32
+	data  []byte
+}
+
+// fakeServer speaks enough of the memcached text protocol to set and get
+// items, storing flags and data as the extension sends them.
+type fakeServer struct {
+	listener net.Listener
+	mu       sync.Mutex
+	items    map[string]item
+}
+
+func newFakeServer() (*fakeServer, error) {
+	l, err := net.Listen(`tcp`, `127.0.0.1:0`)
+	if err != nil {
+		return nil, err
+	}
+	s := &fakeServer{listener: l, items: map[string]item{}}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s, nil
+}
+
+func (s *fakeServer) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		var key string
+		var it item
+		var exptime, n int
+		switch fields := strings.Fields(line); {
+		case len(fields) == 2 && fields[0] == `get`:
+			s.mu.Lock()
+			it, ok := s.items[fields[1]]
+			s.mu.Unlock()
+			if ok {
+				fmt.Fprintf(conn, "VALUE %s %d %d\r\n%s\r\n", fields[1], it.flags, len(it.data), it.data)
+			}
+			io.WriteString(conn, "END\r\n")
+		case len(fields) == 5 && fields[0] == `set`:
+			if _, err := fmt.Sscanf(line, "set %s %d %d %d\r\n", &key, &it.flags, &exptime, &n); err != nil {
+				io.WriteString(conn, "CLIENT_ERROR bad command line format\r\n")
+				return
+			}
+			it.data = make([]byte, n+2)
+			if _, err := io.ReadFull(r, it.data); err != nil {
+				return
+			}
+			it.data = it.data[:n]
+			s.mu.Lock()
+			s.items[key] = it
+			s.mu.Unlock()
+			io.WriteString(conn, "STORED\r\n")
+		default:
+			io.WriteString(conn, "ERROR\r\n")
+		}
+	}
+}
+
+type MemcachedSuite struct {
+	suite.Suite
+	Server *fakeServer
+	Conn   net.Conn
+	Reader *bufio.Reader
+}
+
+func (Suite *MemcachedSuite) SetupSuite() {
+	var err error
+	Suite.Server, err = newFakeServer()
+	Suite.Require().Nil(err)
+	Suite.Conn, err = net.Dial(`tcp`, Suite.Server.listener.Addr().String())
+	Suite.Require().Nil(err)
+	Suite.Reader = bufio.NewReader(Suite.Conn)
+}
+
+func (Suite *MemcachedSuite) TearDownSuite() {
+	Suite.Conn.Close()
+	Suite.Server.listener.Close()
+}
+
+// set encodes v with c and stores it under key.
+func (Suite *MemcachedSuite) set(c Codec, key string, v interface{}) {
+	flags, data, err := c.Encode(v)
+	Suite.Require().Nil(err)
+	_, err = fmt.Fprintf(Suite.Conn, "set %s %d 0 %d\r\n%s\r\n", key, flags, len(data), data)
+	Suite.Require().Nil(err)
+	line, err := Suite.Reader.ReadString('\n')
+	Suite.Require().Nil(err)
+	Suite.Require().Equal("STORED\r\n", line)
+}
+
+// get reads the item key back.
+func (Suite *MemcachedSuite) get(key string) (uint32, []byte) {
+	_, err := fmt.Fprintf(Suite.Conn, "get %s\r\n", key)
+	Suite.Require().Nil(err)
+	line, err := Suite.Reader.ReadString('\n')
+	Suite.Require().Nil(err)
+	var name string
+	var flags uint32
+	var n int
+	_, err = fmt.Sscanf(line, "VALUE %s %d %d\r\n", &name, &flags, &n)
+	Suite.Require().Nil(err, line)
+	data := make([]byte, n+2)
+	_, err = io.ReadFull(Suite.Reader, data)
+	Suite.Require().Nil(err)
+	line, err = Suite.Reader.ReadString('\n')
+	Suite.Require().Nil(err)
+	Suite.Require().Equal("END\r\n", line)
+	return flags, data[:n]
+}
+
+// store writes flags and data under key as the extension would.
+func (Suite *MemcachedSuite) store(key string, flags uint32, data []byte) {
+	Suite.Server.mu.Lock()
+	Suite.Server.items[key] = item{flags: flags, data: data}
+	Suite.Server.mu.Unlock()
+}
+
+type testUser struct {
+	ID    int64  `igbinary:"id" json:"id"`
+	Name  string `igbinary:"name" json:"name"`
+	Admin bool   `igbinary:"admin" json:"admin"`
+}
+
+func (Suite *MemcachedSuite) TestScalars() {
+	c := NewCodec()
+	for _, Test := range []struct {
+		Value interface{}
+		Flags uint32
+		Data  string
+	}{
+		{`hello`, typeString, `hello`},
+		{[]byte(`raw`), typeString, `raw`},
+		{42, typeLong, `42`},
+		{int8(-7), typeLong, `-7`},
+		{uint32(7), typeLong, `7`},
+		{1.5, typeDouble, `1.5`},
+		{1000000.0, typeDouble, `1000000`},
+		{math.Inf(-1), typeDouble, `-INF`},
+		{true, typeBool, `1`},
+		{false, typeBool, ``},
+	} {
+		Suite.set(c, `scalar`, Test.Value)
+		Flags, Data := Suite.get(`scalar`)
+		Suite.Equal(Test.Flags, Flags)
+		Suite.Equal(Test.Data, string(Data))
+
+		var Decoded interface{}
+		Suite.Nil(c.Decode(Flags, Data, &Decoded))
+		switch Test.Value.(type) {
+		case string, []byte:
+			Suite.Equal(Test.Data, Decoded)
+		case bool:
+			Suite.Equal(Test.Value, Decoded)
+		}
+	}
+
+	var N int
+	Suite.Nil(c.Decode(typeLong, []byte(`42`), &N))
+	Suite.Equal(42, N)
+	var F float64
+	Suite.Nil(c.Decode(typeDouble, []byte(`NAN`), &F))
+	Suite.True(math.IsNaN(F))
+	var B []byte
+	Suite.Nil(c.Decode(typeString, []byte(`bytes`), &B))
+	Suite.Equal([]byte(`bytes`), B)
+	var Bool bool
+	Suite.Nil(c.Decode(typeBool, []byte(`1`), &Bool))
+	Suite.True(Bool)
+
+	Suite.EqualError(c.Decode(typeLong, []byte(`4x`), &N), `memcached: invalid integer "4x"`)
+	Suite.EqualError(c.Decode(typeDouble, []byte(`x`), &F), `memcached: invalid float "x"`)
+}
+
+func (Suite *MemcachedSuite) TestSerializers() {
+	User := testUser{ID: 7, Name: `Ann`, Admin: true}
+	for _, Test := range []struct {
+		Serializer Serializer
+		Flags      uint32
+	}{
+		{Igbinary, typeIgbinary},
+		{PHP, typeSerialized},
+		{JSON, typeJSON},
+	} {
+		c := NewCodec()
+		c.Serializer = Test.Serializer
+		Suite.set(c, `user`, User)
+		Flags, Data := Suite.get(`user`)
+		Suite.Equal(Test.Flags, Flags)
+
+		var Decoded testUser
+		Suite.Nil(c.Decode(Flags, Data, &Decoded))
+		Suite.Equal(User, Decoded)
+	}
+
+	// $m->set('user', ['id' => 7, 'name' => 'Ann', 'admin' => true])
+	Suite.store(`php`, typeSerialized, []byte(`a:3:{s:2:"id";i:7;s:4:"name";s:3:"Ann";s:5:"admin";b:1;}`))
+	Flags, Data := Suite.get(`php`)
+	var Decoded testUser
+	Suite.Nil(NewCodec().Decode(Flags, Data, &Decoded))
+	Suite.Equal(User, Decoded)
+}
+
+func (Suite *MemcachedSuite) TestCompression() {
+	Big := strings.Repeat(`igbinary `, 500)
+	for _, Test := range []struct {
+		Compression Compression
+		Flags       uint32
+	}{
+		{FastLZ, flagCompressed | flagCompressionFastLZ},
+		{Zlib, flagCompressed | flagCompressionZlib},
+	} {
+		c := NewCodec()
+		c.Compression = Test.Compression
+		Suite.set(c, `big`, Big)
+		Flags, Data := Suite.get(`big`)
+		Suite.Equal(Test.Flags|typeString, Flags)
+		Suite.Less(len(Data), len(Big)/10)
+		Suite.Equal([]byte{0x94, 0x11, 0, 0}, Data[:4])
+
+		var Decoded string
+		Suite.Nil(c.Decode(Flags, Data, &Decoded))
+		Suite.Equal(Big, Decoded)
+
+		// Payloads below the threshold stay uncompressed.
+		Suite.set(c, `small`, Big[:100])
+		Flags, _ = Suite.get(`small`)
+		Suite.Equal(uint32(typeString), Flags)
+	}
+
+	// Compression that does not pay off is dropped.
+	c := NewCodec()
+	c.CompressionThreshold = 0
+	Suite.set(c, `short`, `abcdefgh`)
+	Flags, Data := Suite.get(`short`)
+	Suite.Equal(uint32(typeString), Flags)
+	Suite.Equal(`abcdefgh`, string(Data))
+
+	var Decoded interface{}
+	Suite.EqualError(c.Decode(flagCompressed, []byte{1, 0, 0, 0, 'a'}, &Decoded), `memcached: unknown compression in flags 0x10`)
+	Suite.EqualError(c.Decode(flagCompressed|flagCompressionZlib, []byte{1}, &Decoded), `memcached: compressed value of 1 bytes`)
+	Suite.Equal(lz.ErrCorrupt, c.Decode(flagCompressed|flagCompressionFastLZ, []byte{9, 0, 0, 0, 0, 'a'}, &Decoded))
+}
+
+func (Suite *MemcachedSuite) TestUserFlags() {
+	c := NewCodec()
+	c.UserFlags = 0xbeef
+	Suite.set(c, `flagged`, map[string]int{`a`: 1})
+	Flags, Data := Suite.get(`flagged`)
+	Suite.Equal(uint16(0xbeef), UserFlags(Flags))
+	Suite.Equal(uint32(0xbeef0000|typeIgbinary), Flags)
+
+	var Decoded map[string]int
+	Suite.Nil(c.Decode(Flags, Data, &Decoded))
+	Suite.Equal(map[string]int{`a`: 1}, Decoded)
+}
+
+func (Suite *MemcachedSuite) TestExtensionItems() {
+	c := NewCodec()
+	var Decoded interface{}
+
+	// $m->set('n', 42) with igbinary as the serializer.
+	Suite.store(`n`, typeLong, []byte(`42`))
+	Flags, Data := Suite.get(`n`)
+	Suite.Nil(c.Decode(Flags, Data, &Decoded))
+	Suite.Equal(int64(42), Decoded)
+
+	// $m->set('a', [1, 2])
+	Suite.store(`a`, typeIgbinary, []byte("\x00\x00\x00\x02\x14\x02\x06\x00\x06\x01\x06\x01\x06\x02"))
+	Flags, Data = Suite.get(`a`)
+	Decoded = nil
+	Suite.Nil(c.Decode(Flags, Data, &Decoded))
+	Suite.Equal(igbinary.Array{{Key: int64(0), Value: int64(1)}, {Key: int64(1), Value: int64(2)}}, Decoded)
+
+	Suite.EqualError(c.Decode(typeMsgpack, nil, &Decoded), `memcached: msgpack values are not supported`)
+	Suite.EqualError(c.Decode(9, nil, &Decoded), `memcached: unsupported value type 9`)
+}
+
+func (Suite *MemcachedSuite) TestBinaryData() {
+	// The text protocol carries any bytes given the length.
+	Data := bytes.Repeat([]byte("\r\n\x00\xff"), 4)
+	Suite.set(NewCodec(), `binary`, Data)
+	_, Stored := Suite.get(`binary`)
+	Suite.Equal(Data, Stored)
+}
+
+func TestMemcachedSuite(t *testing.T) {
+	suite.Run(t, new(MemcachedSuite))
+}
@@ -0,0 +1,264 @@
+// Package phpredis encodes values the way the phpredis extension stores
+// them, serialized with Redis::OPT_SERIALIZER and then compressed with
+// Redis::OPT_COMPRESSION. Values written by either side read on the other.
+//
+// The package works on the bytes a Redis client gets and sets, Codec.Get and
+// Codec.Set use any client behind the small Client interface.
+package phpredis
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"github.com/zarken-go/igbinary"
+	"github.com/zarken-go/igbinary/internal/lz"
+	"github.com/zarken-go/igbinary/internal/php"
+	"github.com/zarken-go/igbinary/phpserialize"
+	"math"
+	"reflect"
+	"strconv"
+)
+
+// Serializer is a Redis::OPT_SERIALIZER value.
+type Serializer uint8
+
+// Serializers, numbered as the Redis::SERIALIZER_* constants.
+const (
+	SerializerNone     Serializer = 0
+	SerializerPHP      Serializer = 1
+	SerializerIgbinary Serializer = 2
+	SerializerJSON     Serializer = 4
+)
+
+// Compression is a Redis::OPT_COMPRESSION value.
+type Compression uint8
+
+// Compressions, numbered as the Redis::COMPRESSION_* constants.
+const (
+	CompressionNone Compression = 0
+	CompressionLZF  Compression = 1
+	CompressionZSTD Compression = 2
+	CompressionLZ4  Compression = 3
+)
+
+// lz4HeaderLen is the size of the header phpredis writes before LZ4 blocks:
+// a CRC-8 of the length followed by the uncompressed length.
+const lz4HeaderLen = 5
+
+var zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+
+// Compressor compresses and decompresses zstd frames, for example with
+// github.com/klauspost/compress/zstd. No zstd implementation is built in.
+type Compressor interface {
+	Compress(src []byte) ([]byte, error)
+	Decompress(src []byte) ([]byte, error)
+}
+
+// Client is the byte level access to Redis the codec needs, a thin adapter
+// around any Redis client implements it.
+type Client interface {
+	Get(key string) ([]byte, error)
+	Set(key string, value []byte) error
+}
+
+// Codec converts Go values to phpredis values and back. The zero Codec
+// matches a Redis object without options: values are stored as strings.
+//
+// Reading detects what it can: igbinary data by its header, zstd frames by
+// their magic number and LZ4 blocks by their header, after trying the
+// configured Compression. LZF data carries no marker and is only
+// decompressed when Compression is CompressionLZF. As in
+// phpredis, data that does not decompress or unserialize is read as is.
+type Codec struct {
+	Serializer  Serializer
+	Compression Compression
+	// Zstd compresses values for CompressionZSTD and reads zstd frames.
+	Zstd Compressor
+}
+
+// Get reads key with client and decodes its value into v.
+func (c Codec) Get(client Client, key string, v interface{}) error {
+	data, err := client.Get(key)
+	if err != nil {
+		return err
+	}
+	return c.Unmarshal(data, v)
+}
+
+// Set encodes v and writes it to key with client.
+func (c Codec) Set(client Client, key string, v interface{}) error {
+	data, err := c.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return client.Set(key, data)
+}
+
+// Marshal returns the phpredis value of v.
+func (c Codec) Marshal(v interface{}) ([]byte, error) {
+	b, err := c.serialize(v)
+	if err != nil || len(b) == 0 {
+		return b, err
+	}
+
+	switch c.Compression {
+	case CompressionNone:
+		return b, nil
+	case CompressionLZF:
+		// lzf_compress fails for data it cannot shrink, which phpredis
+		// then stores uncompressed.
+		if compressed := lz.LZFCompress(b); len(compressed) < len(b) {
+			return compressed, nil
+		}
+		return b, nil
+	case CompressionZSTD:
+		if c.Zstd == nil {
+			return nil, fmt.Errorf(`phpredis: zstd compression without a Zstd compressor`)
+		}
+		return c.Zstd.Compress(b)
+	case CompressionLZ4:
+		if len(b) > math.MaxInt32 {
+			return b, nil
+		}
+		compressed := make([]byte, lz4HeaderLen, lz4HeaderLen+len(b))
+		binary.LittleEndian.PutUint32(compressed[1:], uint32(len(b)))
+		compressed[0] = crc8(compressed[1:lz4HeaderLen])
+		return append(compressed, lz.LZ4Compress(b)...), nil
+	}
+	return nil, fmt.Errorf(`phpredis: unsupported compression %d`, c.Compression)
+}
+
+func (c Codec) serialize(v interface{}) ([]byte, error) {
+	switch c.Serializer {
+	case SerializerNone:
+		return stringValue(v)
+	case SerializerPHP:
+		value, err := php.Generic(v)
+		if err != nil {
+			return nil, err
+		}
+		return phpserialize.Marshal(value)
+	case SerializerIgbinary:
+		return igbinary.Marshal(v)
+	case SerializerJSON:
+		return json.Marshal(v)
+	}
+	return nil, fmt.Errorf(`phpredis: unsupported serializer %d`, c.Serializer)
+}
+
+// stringValue converts v to a string as PHP does for values stored without
+// a serializer.
+func stringValue(v interface{}) ([]byte, error) {
+	switch v := v.(type) {
+	case nil:
+		return []byte{}, nil
+	case string:
+		return []byte(v), nil
+	case []byte:
+		return v, nil
+	case bool:
+		if v {
+			return []byte(`1`), nil
+		}
+		return []byte{}, nil
+	}
+
+	switch rv := reflect.ValueOf(v); rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.AppendInt(nil, rv.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.AppendUint(nil, rv.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return []byte(php.FormatFloat(rv.Float())), nil
+	}
+	return nil, fmt.Errorf(`phpredis: cannot store %T without a serializer`, v)
+}
+
+// Unmarshal decodes the phpredis value data into v, as igbinary.Unmarshal
+// does.
+func (c Codec) Unmarshal(data []byte, v interface{}) error {
+	b, err := c.decompress(data)
+	if err != nil {
+		return err
+	}
+
+	if igbinary.HasHeader(b) {
+		return igbinary.Unmarshal(b, v)
+	}
+	switch c.Serializer {
+	case SerializerPHP:
+		if value, err := phpserialize.Unmarshal(b); err == nil {
+			return php.Assign(value, v)
+		}
+	case SerializerJSON:
+		if json.Valid(b) {
+			return json.Unmarshal(b, v)
+		}
+	}
+	return php.Assign(string(b), v)
+}
+
+func (c Codec) decompress(data []byte) ([]byte, error) {
+	// The configured compression is tried first: LZF data matches the
+	// markers of the others by chance.
+	if b, ok, err := c.decompressAs(c.Compression, data); ok || err != nil {
+		return b, err
+	}
+	for _, compression := range []Compression{CompressionZSTD, CompressionLZ4} {
+		if compression == c.Compression {
+			continue
+		}
+		if b, ok, err := c.decompressAs(compression, data); ok || err != nil {
+			return b, err
+		}
+	}
+	return data, nil
+}
+
+// decompressAs decompresses data with compression, it reports false when
+// data is not compressed that way.
+func (c Codec) decompressAs(compression Compression, data []byte) ([]byte, bool, error) {
+	switch compression {
+	case CompressionZSTD:
+		if len(data) < len(zstdMagic) || string(data[:len(zstdMagic)]) != string(zstdMagic) {
+			return nil, false, nil
+		}
+		if c.Zstd == nil {
+			return nil, false, fmt.Errorf(`phpredis: zstd compressed value without a Zstd compressor`)
+		}
+		if b, err := c.Zstd.Decompress(data); err == nil {
+			return b, true, nil
+		}
+	case CompressionLZ4:
+		if len(data) <= lz4HeaderLen || data[0] != crc8(data[1:lz4HeaderLen]) {
+			return nil, false, nil
+		}
+		if size := int32(binary.LittleEndian.Uint32(data[1:])); size > 0 {
+			if b, err := lz.LZ4Decompress(data[lz4HeaderLen:], int(size)); err == nil {
+				return b, true, nil
+			}
+		}
+	case CompressionLZF:
+		if b, err := lz.LZFDecompress(data); err == nil {
+			return b, true, nil
+		}
+	}
+	return nil, false, nil
+}
+
+// crc8 is the checksum phpredis keeps of LZ4 lengths, CRC-8 with the
+// polynomial 0x31 and 0xff as initial value.
+func crc8(b []byte) byte {
+	crc := byte(0xff)
+	for _, c := range b {
+		crc ^= c
+		for i := 0; i < 8; i++ {
+			if crc&0x80 != 0 {
+				crc = crc<<1 ^ 0x31
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
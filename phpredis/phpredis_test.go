@@ -0,0 +1,214 @@
+package phpredis
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"github.com/stretchr/testify/suite"
+	"github.com/zarken-go/igbinary"
+	"math/rand"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+var errNil = errors.New(`redis: nil`)
+
+// memoryClient stands in for Redis.
+type memoryClient map[string][]byte
+
+func (m memoryClient) Get(key string) ([]byte, error) {
+	v, ok := m[key]
+	if !ok {
+		return nil, errNil
+	}
+	return v, nil
+}
+
+func (m memoryClient) Set(key string, value []byte) error {
+	m[key] = append([]byte(nil), value...)
+	return nil
+}
+
+// storedZstd writes zstd frames of one raw block, enough to test the
+// Compressor plumbing without a zstd implementation.
+type storedZstd struct{}
+
+func (storedZstd) Compress(src []byte) ([]byte, error) {
+	// Single segment with an 8 byte content size, then the last block, raw.
+	b := append([]byte(nil), zstdMagic...)
+	b = append(b, 0xe0, 0, 0, 0, 0, 0, 0, 0, 0)
+	binary.LittleEndian.PutUint64(b[5:], uint64(len(src)))
+	header := uint32(len(src))<<3 | 1
+	b = append(b, byte(header), byte(header>>8), byte(header>>16))
+	return append(b, src...), nil
+}
+
+func (storedZstd) Decompress(src []byte) ([]byte, error) {
+	if len(src) < 16 || src[4] != 0xe0 || src[13]&7 != 1 {
+		return nil, errors.New(`unsupported zstd frame`)
+	}
+	return src[16:], nil
+}
+
+type testUser struct {
+	ID    int64  `igbinary:"id" json:"id"`
+	Name  string `igbinary:"name" json:"name"`
+	Admin bool   `igbinary:"admin" json:"admin"`
+}
+
+type PhpRedisSuite struct {
+	suite.Suite
+	Client memoryClient
+}
+
+func (Suite *PhpRedisSuite) SetupTest() {
+	Suite.Client = memoryClient{}
+}
+
+func (Suite *PhpRedisSuite) TestRoundTrip() {
+	User := testUser{ID: 7, Name: strings.Repeat(`Ann`, 100), Admin: true}
+	for _, Serializer := range []Serializer{SerializerPHP, SerializerIgbinary, SerializerJSON} {
+		for _, Compression := range []Compression{CompressionNone, CompressionLZF, CompressionZSTD, CompressionLZ4} {
+			c := Codec{Serializer: Serializer, Compression: Compression, Zstd: storedZstd{}}
+			Suite.Require().Nil(c.Set(Suite.Client, `user`, User))
+
+			var Decoded testUser
+			Suite.Nil(c.Get(Suite.Client, `user`, &Decoded), `%d %d`, Serializer, Compression)
+			Suite.Equal(User, Decoded)
+
+			// The format is detected whatever the reading codec uses,
+			// except for LZF which has no marker.
+			if Compression != CompressionLZF && Serializer == SerializerIgbinary {
+				Decoded = testUser{}
+				Suite.Nil(Codec{Zstd: storedZstd{}}.Get(Suite.Client, `user`, &Decoded))
+				Suite.Equal(User, Decoded)
+			}
+		}
+	}
+
+	var Decoded testUser
+	Suite.Equal(errNil, Codec{}.Get(Suite.Client, `missing`, &Decoded))
+}
+
+func (Suite *PhpRedisSuite) TestRoundTripPayloads() {
+	Random := rand.New(rand.NewSource(1))
+	for _, Compression := range []Compression{CompressionNone, CompressionLZF, CompressionZSTD, CompressionLZ4} {
+		c := Codec{Compression: Compression, Zstd: storedZstd{}}
+		for I := 0; I < 2000; I++ {
+			// Repeated random bytes, so that most payloads compress.
+			Part := make([]byte, 1+Random.Intn(16))
+			Random.Read(Part)
+			Value := strconv.Itoa(I) + `-` + strings.Repeat(string(Part), 1+Random.Intn(8))
+			B, err := c.Marshal(Value)
+			Suite.Require().Nil(err)
+			var Decoded string
+			Suite.Require().Nil(c.Unmarshal(B, &Decoded))
+			Suite.Require().Equal(Value, Decoded, `compression %d, payload %d`, Compression, I)
+		}
+	}
+}
+
+func (Suite *PhpRedisSuite) TestSerializerNone() {
+	c := Codec{}
+	for _, Test := range []struct {
+		Value interface{}
+		Data  string
+	}{
+		{`hello`, `hello`},
+		{[]byte(`raw`), `raw`},
+		{42, `42`},
+		{uint8(7), `7`},
+		{1.5, `1.5`},
+		{1000000.0, `1000000`},
+		{true, `1`},
+		{false, ``},
+		{nil, ``},
+	} {
+		B, err := c.Marshal(Test.Value)
+		Suite.Nil(err)
+		Suite.Equal(Test.Data, string(B))
+	}
+
+	_, err := c.Marshal(map[string]int{})
+	Suite.EqualError(err, `phpredis: cannot store map[string]int without a serializer`)
+
+	var S string
+	Suite.Nil(c.Unmarshal([]byte(`hello`), &S))
+	Suite.Equal(`hello`, S)
+	var B []byte
+	Suite.Nil(c.Unmarshal([]byte(`raw`), &B))
+	Suite.Equal([]byte(`raw`), B)
+}
+
+func (Suite *PhpRedisSuite) TestPhpRedisValues() {
+	var Value interface{}
+
+	// $redis->setOption(Redis::OPT_COMPRESSION, Redis::COMPRESSION_LZF);
+	// $redis->set('k', 'abcabcabcabc');
+	c := Codec{Compression: CompressionLZF}
+	Suite.Nil(c.Unmarshal([]byte{0x02, 'a', 'b', 'c', 0xe0, 0x00, 0x02}, &Value))
+	Suite.Equal(`abcabcabcabc`, Value)
+	B, err := c.Marshal(`abcabcabcabc`)
+	Suite.Nil(err)
+	Suite.Equal([]byte{0x02, 'a', 'b', 'c', 0xe0, 0x00, 0x02}, B)
+
+	// Data LZF cannot shrink is stored as is.
+	B, err = c.Marshal(`abc`)
+	Suite.Nil(err)
+	Suite.Equal(`abc`, string(B))
+	Value = nil
+	Suite.Nil(c.Unmarshal(B, &Value))
+	Suite.Equal(`abc`, Value)
+
+	// Redis::COMPRESSION_LZ4 prefixes the block with the CRC-8 of the length
+	// and the length.
+	c = Codec{Serializer: SerializerIgbinary, Compression: CompressionLZ4}
+	B, err = c.Marshal([]int{1, 2})
+	Suite.Require().Nil(err)
+	Suite.Equal([]byte{crc8([]byte{14, 0, 0, 0}), 14, 0, 0, 0}, B[:5])
+	Value = nil
+	Suite.Nil(c.Unmarshal(B, &Value))
+	Suite.Equal(igbinary.Array{{Key: int64(0), Value: int64(1)}, {Key: int64(1), Value: int64(2)}}, Value)
+
+	// Redis::SERIALIZER_PHP values that do not unserialize read as strings.
+	c = Codec{Serializer: SerializerPHP}
+	Value = nil
+	Suite.Nil(c.Unmarshal([]byte(`a:1:{i:0;b:1;}`), &Value))
+	Suite.Equal(igbinary.Array{{Key: int64(0), Value: true}}, Value)
+	Value = nil
+	Suite.Nil(c.Unmarshal([]byte(`plain`), &Value))
+	Suite.Equal(`plain`, Value)
+
+	// A JSON codec reads plain strings too.
+	Value = nil
+	Suite.Nil(Codec{Serializer: SerializerJSON}.Unmarshal([]byte(`not json`), &Value))
+	Suite.Equal(`not json`, Value)
+}
+
+func (Suite *PhpRedisSuite) TestZstd() {
+	B, err := Codec{Compression: CompressionZSTD, Zstd: storedZstd{}}.Marshal(`value`)
+	Suite.Require().Nil(err)
+	Suite.True(bytes.HasPrefix(B, zstdMagic))
+
+	var S string
+	Suite.EqualError(Codec{}.Unmarshal(B, &S), `phpredis: zstd compressed value without a Zstd compressor`)
+	_, err = Codec{Compression: CompressionZSTD}.Marshal(`value`)
+	Suite.EqualError(err, `phpredis: zstd compression without a Zstd compressor`)
+}
+
+func (Suite *PhpRedisSuite) TestUnsupported() {
+	_, err := Codec{Serializer: 3}.Marshal(1)
+	Suite.EqualError(err, `phpredis: unsupported serializer 3`)
+	_, err = Codec{Compression: 9}.Marshal(1)
+	Suite.EqualError(err, `phpredis: unsupported compression 9`)
+}
+
+func (Suite *PhpRedisSuite) TestCRC8() {
+	// The check value of CRC-8 with these parameters.
+	Suite.Equal(byte(0xf7), crc8([]byte(`123456789`)))
+}
+
+func TestPhpRedisSuite(t *testing.T) {
+	suite.Run(t, new(PhpRedisSuite))
+}
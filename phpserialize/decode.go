@@ -0,0 +1,273 @@
+// Package phpserialize reads and writes the format produced by PHP's
+// serialize() function using the generic value tree of package igbinary.
+package phpserialize
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/zarken-go/igbinary"
+	"github.com/zarken-go/igbinary/internal/php"
+	"strconv"
+)
+
+// Unmarshal parses a complete serialize() representation. Values decode to
+// the same types as igbinary.Decoder.DecodeInterface.
+func Unmarshal(data []byte) (interface{}, error) {
+	v, n, err := Decode(data)
+	if err != nil {
+		return nil, err
+	}
+	if n != len(data) {
+		return nil, fmt.Errorf(`phpserialize: trailing data at offset %d`, n)
+	}
+	return v, nil
+}
+
+// Decode parses the first value of data and returns it along with the
+// number of bytes consumed.
+func Decode(data []byte) (interface{}, int, error) {
+	return NewDecoder(data).DecodeAt(0)
+}
+
+// Decoder decodes consecutive values sharing one reference table, the way
+// PHP's session handlers write the variables of a session.
+type Decoder struct {
+	d decoder
+}
+
+// NewDecoder returns a Decoder reading data.
+func NewDecoder(data []byte) *Decoder {
+	return &Decoder{d: decoder{data: data}}
+}
+
+// DecodeAt parses the value starting at offset and returns it along with
+// the offset following it. References may point into values decoded by
+// earlier calls.
+func (d *Decoder) DecodeAt(offset int) (interface{}, int, error) {
+	d.d.pos = offset
+	v, err := d.d.value()
+	if err != nil {
+		return nil, d.d.pos, err
+	}
+	return v, d.d.pos, nil
+}
+
+type decoder struct {
+	data []byte
+	pos  int
+	// refs holds every decoded value but array keys, R: and r: index it
+	// starting from 1.
+	refs []interface{}
+}
+
+func (d *decoder) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf(`phpserialize: offset %d: %s`, d.pos, fmt.Sprintf(format, args...))
+}
+
+func (d *decoder) expect(c byte) error {
+	if d.pos >= len(d.data) {
+		return d.errorf(`expected '%c' found EOF`, c)
+	}
+	if d.data[d.pos] != c {
+		return d.errorf(`expected '%c' found '%c'`, c, d.data[d.pos])
+	}
+	d.pos++
+	return nil
+}
+
+// until returns the bytes up to the next occurrence of c and skips c.
+func (d *decoder) until(c byte) ([]byte, error) {
+	i := bytes.IndexByte(d.data[d.pos:], c)
+	if i < 0 {
+		return nil, d.errorf(`expected '%c' found EOF`, c)
+	}
+	b := d.data[d.pos : d.pos+i]
+	d.pos += i + 1
+	return b, nil
+}
+
+func (d *decoder) int(end byte) (int64, error) {
+	b, err := d.until(end)
+	if err != nil {
+		return 0, err
+	}
+	n, err := strconv.ParseInt(string(b), 10, 64)
+	if err != nil {
+		return 0, d.errorf(`invalid integer %q`, b)
+	}
+	return n, nil
+}
+
+func (d *decoder) length(end byte) (int, error) {
+	n, err := d.int(end)
+	if err != nil {
+		return 0, err
+	}
+	if n < 0 || n > int64(len(d.data)) {
+		return 0, d.errorf(`invalid length %d`, n)
+	}
+	return int(n), nil
+}
+
+// quoted reads a length prefixed, double quoted byte string.
+func (d *decoder) quoted(end byte) (string, error) {
+	n, err := d.length(':')
+	if err != nil {
+		return ``, err
+	}
+	if err := d.expect('"'); err != nil {
+		return ``, err
+	}
+	if d.pos+n > len(d.data) {
+		return ``, d.errorf(`string of length %d exceeds input`, n)
+	}
+	s := string(d.data[d.pos : d.pos+n])
+	d.pos += n
+	if err := d.expect('"'); err != nil {
+		return ``, err
+	}
+	return s, d.expect(end)
+}
+
+func (d *decoder) key() (interface{}, error) {
+	if d.pos+1 >= len(d.data) {
+		return nil, d.errorf(`unexpected EOF`)
+	}
+	t := d.data[d.pos]
+	d.pos += 2
+	switch t {
+	case 'i':
+		return d.int(';')
+	case 's':
+		return d.quoted(';')
+	}
+	d.pos -= 2
+	return nil, d.errorf(`invalid array key type '%c'`, t)
+}
+
+func (d *decoder) entries(end byte) (igbinary.Array, error) {
+	n, err := d.length(':')
+	if err != nil {
+		return nil, err
+	}
+	if err := d.expect('{'); err != nil {
+		return nil, err
+	}
+	arr := make(igbinary.Array, 0, n)
+	for i := 0; i < n; i++ {
+		k, err := d.key()
+		if err != nil {
+			return nil, err
+		}
+		v, err := d.value()
+		if err != nil {
+			return nil, err
+		}
+		arr = append(arr, igbinary.Entry{Key: k, Value: v})
+	}
+	return arr, d.expect(end)
+}
+
+func (d *decoder) ref() (interface{}, error) {
+	n, err := d.int(';')
+	if err != nil {
+		return nil, err
+	}
+	if n < 1 || n > int64(len(d.refs)) {
+		return nil, d.errorf(`reference %d not found`, n)
+	}
+	return d.refs[n-1], nil
+}
+
+//nolint:gocyclo
+func (d *decoder) value() (interface{}, error) {
+	if d.pos >= len(d.data) {
+		return nil, d.errorf(`unexpected EOF`)
+	}
+	t := d.data[d.pos]
+	if t == 'N' {
+		d.pos++
+		d.refs = append(d.refs, nil)
+		return nil, d.expect(';')
+	}
+	d.pos++
+	if err := d.expect(':'); err != nil {
+		return nil, err
+	}
+
+	slot := len(d.refs)
+	if t != 'R' {
+		d.refs = append(d.refs, nil)
+	}
+
+	var v interface{}
+	var err error
+	switch t {
+	case 'b':
+		var n int64
+		if n, err = d.int(';'); err == nil {
+			if n != 0 && n != 1 {
+				return nil, d.errorf(`invalid bool %d`, n)
+			}
+			v = n == 1
+		}
+	case 'i':
+		v, err = d.int(';')
+	case 'd':
+		var b []byte
+		if b, err = d.until(';'); err == nil {
+			v, err = parseFloat(string(b))
+		}
+	case 's':
+		v, err = d.quoted(';')
+	case 'a':
+		arr := igbinary.Array(nil)
+		if arr, err = d.entries('}'); err == nil {
+			v = arr
+		}
+	case 'O':
+		obj := &igbinary.Object{}
+		d.refs[slot] = obj
+		if obj.Class, err = d.quoted(':'); err == nil {
+			obj.Props, err = d.entries('}')
+		}
+		v = obj
+	case 'C':
+		obj := &igbinary.SerializedObject{}
+		d.refs[slot] = obj
+		if obj.Class, err = d.quoted(':'); err == nil {
+			var n int
+			if n, err = d.length(':'); err == nil {
+				if err = d.expect('{'); err == nil {
+					if d.pos+n > len(d.data) {
+						return nil, d.errorf(`serialized data of length %d exceeds input`, n)
+					}
+					obj.Data = append([]byte(nil), d.data[d.pos:d.pos+n]...)
+					d.pos += n
+					err = d.expect('}')
+				}
+			}
+		}
+		v = obj
+	case 'r', 'R':
+		v, err = d.ref()
+	default:
+		d.pos -= 2
+		return nil, d.errorf(`unsupported type '%c'`, t)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if t != 'R' {
+		d.refs[slot] = v
+	}
+	return v, nil
+}
+
+func parseFloat(s string) (float64, error) {
+	f, err := php.ParseFloat(s)
+	if err != nil {
+		return 0, fmt.Errorf(`phpserialize: invalid float %q`, s)
+	}
+	return f, nil
+}
@@ -0,0 +1,140 @@
+package phpserialize
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/zarken-go/igbinary"
+	"github.com/zarken-go/igbinary/internal/php"
+	"strconv"
+)
+
+// Marshal returns the serialize() representation of v, which must be built
+// from the types returned by Unmarshal. An *igbinary.Object that occurs more
+// than once is written as a reference to its first occurrence.
+func Marshal(v interface{}) ([]byte, error) {
+	return NewEncoder().Append(nil, v)
+}
+
+// Encoder encodes consecutive values sharing one reference table, the
+// mirror of Decoder.
+type Encoder struct {
+	e encoder
+}
+
+// NewEncoder returns a new Encoder.
+func NewEncoder() *Encoder {
+	return &Encoder{e: encoder{objects: make(map[interface{}]int)}}
+}
+
+// Append appends the serialize() representation of v to dst. Objects
+// written by earlier calls are written as references.
+func (e *Encoder) Append(dst []byte, v interface{}) ([]byte, error) {
+	e.e.buf.Reset()
+	if err := e.e.value(v); err != nil {
+		return dst, err
+	}
+	return append(dst, e.e.buf.Bytes()...), nil
+}
+
+type encoder struct {
+	buf bytes.Buffer
+	// n counts the values written so far, mirroring the decoder's refs.
+	n       int
+	objects map[interface{}]int
+}
+
+func (e *encoder) quoted(s string) {
+	e.buf.WriteString(strconv.Itoa(len(s)))
+	e.buf.WriteString(`:"`)
+	e.buf.WriteString(s)
+	e.buf.WriteByte('"')
+}
+
+func (e *encoder) key(k interface{}) error {
+	switch k := k.(type) {
+	case int64:
+		e.buf.WriteString(`i:` + strconv.FormatInt(k, 10) + `;`)
+	case int:
+		e.buf.WriteString(`i:` + strconv.Itoa(k) + `;`)
+	case string:
+		e.buf.WriteString(`s:`)
+		e.quoted(k)
+		e.buf.WriteByte(';')
+	default:
+		return fmt.Errorf(`phpserialize: unsupported array key %T`, k)
+	}
+	return nil
+}
+
+func (e *encoder) entries(a igbinary.Array) error {
+	e.buf.WriteString(strconv.Itoa(len(a)))
+	e.buf.WriteString(`:{`)
+	for _, entry := range a {
+		if err := e.key(entry.Key); err != nil {
+			return err
+		}
+		if err := e.value(entry.Value); err != nil {
+			return err
+		}
+	}
+	e.buf.WriteByte('}')
+	return nil
+}
+
+// objectRef writes a reference when obj was written before and reports
+// whether it did so.
+func (e *encoder) objectRef(obj interface{}) bool {
+	if n, ok := e.objects[obj]; ok {
+		e.buf.WriteString(`r:` + strconv.Itoa(n) + `;`)
+		return true
+	}
+	e.objects[obj] = e.n
+	return false
+}
+
+func (e *encoder) value(v interface{}) error {
+	e.n++
+	switch v := v.(type) {
+	case nil:
+		e.buf.WriteString(`N;`)
+	case bool:
+		if v {
+			e.buf.WriteString(`b:1;`)
+		} else {
+			e.buf.WriteString(`b:0;`)
+		}
+	case int64:
+		e.buf.WriteString(`i:` + strconv.FormatInt(v, 10) + `;`)
+	case int:
+		e.buf.WriteString(`i:` + strconv.Itoa(v) + `;`)
+	case float64:
+		e.buf.WriteString(`d:` + php.FormatFloat(v) + `;`)
+	case string:
+		e.buf.WriteString(`s:`)
+		e.quoted(v)
+		e.buf.WriteByte(';')
+	case igbinary.Array:
+		e.buf.WriteString(`a:`)
+		return e.entries(v)
+	case *igbinary.Object:
+		if e.objectRef(v) {
+			return nil
+		}
+		e.buf.WriteString(`O:`)
+		e.quoted(v.Class)
+		e.buf.WriteByte(':')
+		return e.entries(v.Props)
+	case *igbinary.SerializedObject:
+		if e.objectRef(v) {
+			return nil
+		}
+		e.buf.WriteString(`C:`)
+		e.quoted(v.Class)
+		e.buf.WriteString(`:` + strconv.Itoa(len(v.Data)) + `:{`)
+		e.buf.Write(v.Data)
+		e.buf.WriteByte('}')
+	default:
+		return fmt.Errorf(`phpserialize: unsupported type %T`, v)
+	}
+	return nil
+}
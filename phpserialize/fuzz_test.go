@@ -0,0 +1,28 @@
+//go:build go1.18
+// +build go1.18
+
+package phpserialize
+
+import (
+	"testing"
+)
+
+func FuzzUnmarshal(f *testing.F) {
+	for _, seed := range []string{
+		`N;`, `b:1;`, `i:-42;`, `d:-INF;`, `s:3:"foo";`,
+		`a:2:{i:0;s:1:"a";s:1:"k";a:0:{}}`,
+		`O:3:"Foo":1:{s:1:"a";r:1;}`, `C:3:"Foo":1:{x}`,
+		`a:2:{i:0;i:1;i:1;R:2;}`,
+	} {
+		f.Add([]byte(seed))
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		v, err := Unmarshal(data)
+		if err != nil {
+			return
+		}
+		if _, err := Marshal(v); err != nil {
+			t.Fatalf(`decoded value failed to encode: %s`, err)
+		}
+	})
+}
@@ -0,0 +1,110 @@
+package phpserialize
+
+import (
+	"github.com/stretchr/testify/suite"
+	"github.com/zarken-go/igbinary"
+	"math"
+	"testing"
+)
+
+type SerializeSuite struct {
+	suite.Suite
+}
+
+func (Suite *SerializeSuite) TestScalars() {
+	Suite.assertRoundTrip(nil, `N;`)
+	Suite.assertRoundTrip(true, `b:1;`)
+	Suite.assertRoundTrip(false, `b:0;`)
+	Suite.assertRoundTrip(int64(-42), `i:-42;`)
+	Suite.assertRoundTrip(1.5, `d:1.5;`)
+	Suite.assertRoundTrip(math.Inf(-1), `d:-INF;`)
+	Suite.assertRoundTrip(1000000.0, `d:1000000;`)
+	Suite.assertRoundTrip(1e25, `d:1.0E+25;`)
+	Suite.assertRoundTrip(`foo"bar`, `s:7:"foo"bar";`)
+}
+
+func (Suite *SerializeSuite) TestArray() {
+	Suite.assertRoundTrip(igbinary.Array{
+		{Key: int64(0), Value: `a`},
+		{Key: `k`, Value: igbinary.Array{}},
+	}, `a:2:{i:0;s:1:"a";s:1:"k";a:0:{}}`)
+}
+
+func (Suite *SerializeSuite) TestObjects() {
+	Suite.assertRoundTrip(&igbinary.Object{
+		Class: `Foo`,
+		Props: igbinary.Array{{Key: `a`, Value: int64(1)}},
+	}, `O:3:"Foo":1:{s:1:"a";i:1;}`)
+	Suite.assertRoundTrip(&igbinary.SerializedObject{
+		Class: `Bar`,
+		Data:  []byte(`x:i:1;`),
+	}, `C:3:"Bar":6:{x:i:1;}`)
+}
+
+func (Suite *SerializeSuite) TestReferences() {
+	v, err := Unmarshal([]byte(`a:2:{i:0;O:3:"Foo":0:{}i:1;r:2;}`))
+	if Suite.Nil(err) {
+		arr := v.(igbinary.Array)
+		Suite.Same(arr[0].Value, arr[1].Value)
+
+		b, err := Marshal(v)
+		Suite.Nil(err)
+		Suite.Equal(`a:2:{i:0;O:3:"Foo":0:{}i:1;r:2;}`, string(b))
+	}
+
+	v, err = Unmarshal([]byte(`a:2:{i:0;i:5;i:1;R:2;}`))
+	if Suite.Nil(err) {
+		Suite.Equal(igbinary.Array{
+			{Key: int64(0), Value: int64(5)},
+			{Key: int64(1), Value: int64(5)},
+		}, v)
+	}
+}
+
+func (Suite *SerializeSuite) TestSharedReferences() {
+	Data := []byte(`O:3:"Foo":0:{}|r:1;`)
+	Decoder := NewDecoder(Data)
+	Foo, n, err := Decoder.DecodeAt(0)
+	Suite.Require().Nil(err)
+	Suite.Equal(14, n)
+	Ref, n, err := Decoder.DecodeAt(n + 1)
+	Suite.Require().Nil(err)
+	Suite.Equal(len(Data), n)
+	Suite.Same(Foo, Ref)
+
+	Encoder := NewEncoder()
+	B, err := Encoder.Append(nil, Foo)
+	Suite.Require().Nil(err)
+	B, err = Encoder.Append(append(B, '|'), Ref)
+	Suite.Nil(err)
+	Suite.Equal(string(Data), string(B))
+}
+
+func (Suite *SerializeSuite) TestErrors() {
+	_, err := Unmarshal([]byte(`i:1;i:2;`))
+	Suite.EqualError(err, `phpserialize: trailing data at offset 4`)
+	_, err = Unmarshal([]byte(`s:10:"foo";`))
+	Suite.EqualError(err, `phpserialize: offset 6: string of length 10 exceeds input`)
+	_, err = Unmarshal([]byte(`a:1:{i:0;r:9;}`))
+	Suite.EqualError(err, `phpserialize: offset 13: reference 9 not found`)
+
+	v, n, err := Decode([]byte(`i:1;i:2;`))
+	Suite.Nil(err)
+	Suite.Equal(int64(1), v)
+	Suite.Equal(4, n)
+}
+
+func (Suite *SerializeSuite) assertRoundTrip(v interface{}, serialized string) {
+	decoded, err := Unmarshal([]byte(serialized))
+	if Suite.Nil(err) {
+		Suite.Equal(v, decoded)
+	}
+	b, err := Marshal(v)
+	if Suite.Nil(err) {
+		Suite.Equal(serialized, string(b))
+	}
+}
+
+func TestSerializeSuite(t *testing.T) {
+	suite.Run(t, new(SerializeSuite))
+}
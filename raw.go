@@ -0,0 +1,90 @@
+package igbinary
+
+import (
+	"bytes"
+	"errors"
+)
+
+// RawMessage is a raw encoded igbinary value. It can be used as a struct
+// field to delay decoding or to pass a value through unchanged.
+//
+// String IDs and references of an igbinary value point into the tables of
+// the enclosing document, so a RawMessage is not a verbatim copy of the
+// input. Decoding rewrites the value into a self-contained form which
+// Unmarshal decodes on its own, encoding translates it back into the tables
+// of the enclosing document.
+type RawMessage []byte
+
+var _ Marshaler = RawMessage(nil)
+var _ Unmarshaler = (*RawMessage)(nil)
+
+// MarshalIgbinary implements Marshaler. A nil RawMessage encodes as null.
+func (m RawMessage) MarshalIgbinary(e *Encoder) error {
+	if m == nil {
+		return e.EncodeNil()
+	}
+	d := NewDecoder(bytes.NewReader(m))
+	if d.peekHeader() {
+		if err := d.DecodeHeader(); err != nil {
+			return err
+		}
+	}
+	base := int(e.refID)
+	err := copyValue(d, e, func(ID int) (int, error) {
+		return ID + base, nil
+	})
+	if err != nil {
+		return err
+	}
+	if d.More() {
+		return errors.New(`igbinary: Encode(RawMessage holds more than one value)`)
+	}
+	return nil
+}
+
+// UnmarshalIgbinary implements Unmarshaler, storing a self-contained copy
+// of the next value. References to values outside of it cannot be
+// expressed and are an error.
+func (m *RawMessage) UnmarshalIgbinary(d *Decoder) error {
+	if m == nil {
+		return decodeErrorF(`RawMessage: UnmarshalIgbinary on nil pointer`)
+	}
+	var buf bytes.Buffer
+	base := len(d.refs)
+	err := copyValue(d, NewEncoder(&buf), func(ID int) (int, error) {
+		if ID < base {
+			return 0, decodeErrorF(`reference id %d outside of RawMessage`, ID)
+		}
+		return ID - base, nil
+	})
+	if err != nil {
+		return err
+	}
+	*m = append((*m)[:0], buf.Bytes()...)
+	return nil
+}
+
+// copyValue copies the tokens of a single value from r to w, mapping the
+// IDs of references with ref. Strings are re-deduplicated by w.
+func copyValue(r TokenReader, w TokenWriter, ref func(int) (int, error)) error {
+	for n := 1; n > 0; n-- {
+		t, err := r.Token()
+		if err != nil {
+			return err
+		}
+		switch t.Kind {
+		case ArrayStartToken, ObjectStartToken:
+			n += 2 * t.Len
+		case NewRefToken:
+			n++
+		case RefToken, ObjectRefToken:
+			if t.Ref, err = ref(t.Ref); err != nil {
+				return err
+			}
+		}
+		if err := w.WriteToken(t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
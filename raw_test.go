@@ -0,0 +1,60 @@
+package igbinary
+
+import (
+	"encoding/hex"
+	"github.com/stretchr/testify/suite"
+	"testing"
+)
+
+type RawSuite struct {
+	suite.Suite
+}
+
+type rawContainer struct {
+	K    string     `igbinary:"k"`
+	Data RawMessage `igbinary:"data"`
+	Tail string     `igbinary:"tail"`
+}
+
+func (Suite *RawSuite) TestRoundTrip() {
+	foo := &Object{Class: `Foo`, Props: Array{{Key: `k`, Value: `v`}}}
+	inner := Array{{Key: `k`, Value: `v`}, {Key: `o`, Value: foo}, {Key: `p`, Value: foo}}
+	B, err := Marshal(Array{{Key: `k`, Value: `v`}, {Key: `data`, Value: inner}, {Key: `tail`, Value: `k`}})
+	Suite.Require().Nil(err)
+
+	var container rawContainer
+	Suite.Require().Nil(Unmarshal(B, &container))
+	Suite.Equal(`v`, container.K)
+	Suite.Equal(`k`, container.Tail)
+	// The string IDs and the object reference are renumbered from zero.
+	Suite.Equal(`140311016b11017611016f1703466f6f14010e000e011101702201`, hex.EncodeToString(container.Data))
+
+	var v interface{}
+	Suite.Require().Nil(Unmarshal(container.Data, &v))
+	Suite.Equal(inner, v)
+
+	out, err := Marshal(Array{
+		{Key: `k`, Value: container.K}, {Key: `data`, Value: container.Data}, {Key: `tail`, Value: container.Tail},
+	})
+	Suite.Nil(err)
+	Suite.Equal(B, out)
+}
+
+func (Suite *RawSuite) TestErrors() {
+	foo := &Object{Class: `Foo`}
+	B, err := Marshal(Array{{Key: `x`, Value: foo}, {Key: `data`, Value: foo}})
+	Suite.Require().Nil(err)
+	var container rawContainer
+	Suite.EqualError(Unmarshal(B, &container), `igbinary: Decode(reference id 1 outside of RawMessage)`)
+
+	_, err = Marshal(RawMessage{0x05, 0x05})
+	Suite.EqualError(err, `igbinary: Encode(RawMessage holds more than one value)`)
+
+	B, err = Marshal(RawMessage(nil))
+	Suite.Nil(err)
+	Suite.Equal([]byte{0, 0, 0, 2, 0}, B)
+}
+
+func TestRawSuite(t *testing.T) {
+	suite.Run(t, new(RawSuite))
+}
@@ -3,8 +3,9 @@ package igbinary
 import "io"
 
 const (
-	bytesAllocLimit = 1e6 // 1mb
-	//sliceAllocLimit = 1e4
+	bytesAllocLimit = 1e6   // 1mb
+	sliceAllocLimit = 1e4   // initial capacity of arrays and maps of untrusted length
+	maxDepth        = 10000 // nesting of arrays, objects and references a Decoder accepts
 	//maxMapSize      = 1e6
 )
 
@@ -0,0 +1,216 @@
+// Package rpc implements the codecs of net/rpc with igbinary.
+//
+// Each message is a header followed by a body, each an igbinary document
+// with its own version header and string table, written after its length as
+// a big-endian uint32. A PHP client frames them with
+// pack('N', strlen($s)).$s for $s = igbinary_serialize($value).
+//
+// Request headers are arrays ['method' => 'Service.Method', 'seq' => n] and
+// response headers add 'error', the empty string or null on success. The
+// body of a response with an error is null.
+package rpc
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"github.com/zarken-go/igbinary"
+	"io"
+	"net"
+	"net/rpc"
+)
+
+// MaxMessageSize is the size of the largest header or body a codec reads.
+const MaxMessageSize = 64 << 20
+
+type requestHeader struct {
+	Method string `igbinary:"method"`
+	Seq    uint64 `igbinary:"seq"`
+}
+
+type responseHeader struct {
+	Method string `igbinary:"method"`
+	Seq    uint64 `igbinary:"seq"`
+	Error  string `igbinary:"error"`
+}
+
+// conn reads and writes the length prefixed documents of a connection.
+type conn struct {
+	rwc io.ReadWriteCloser
+	r   *bufio.Reader
+	w   *bufio.Writer
+	// body is the body read along with the last header.
+	body []byte
+}
+
+func newConn(rwc io.ReadWriteCloser) *conn {
+	return &conn{rwc: rwc, r: bufio.NewReader(rwc), w: bufio.NewWriter(rwc)}
+}
+
+func (c *conn) readMessage() ([]byte, error) {
+	var size [4]byte
+	if _, err := io.ReadFull(c.r, size[:]); err != nil {
+		return nil, err
+	}
+	n := binary.BigEndian.Uint32(size[:])
+	if n > MaxMessageSize {
+		return nil, fmt.Errorf(`rpc: message of %d bytes exceeds MaxMessageSize`, n)
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(c.r, b); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return b, nil
+}
+
+// readHeader reads a header into h along with the body that follows it.
+func (c *conn) readHeader(h interface{}) error {
+	b, err := c.readMessage()
+	if err != nil {
+		return err
+	}
+	if err := igbinary.Unmarshal(b, h); err != nil {
+		return err
+	}
+	if c.body, err = c.readMessage(); err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// readBody decodes the body read with the last header into v, a nil v
+// discards it.
+func (c *conn) readBody(v interface{}) error {
+	b := c.body
+	c.body = nil
+	if v == nil {
+		return nil
+	}
+	return igbinary.Unmarshal(b, v)
+}
+
+// write writes the header and the marshalled body as two documents, each
+// with fresh string and reference tables. Both are marshalled before
+// anything is written, a header alone would desynchronise the stream.
+func (c *conn) write(header interface{}, body []byte) error {
+	h, err := igbinary.Marshal(header)
+	if err != nil {
+		return err
+	}
+	for _, b := range [][]byte{h, body} {
+		var size [4]byte
+		binary.BigEndian.PutUint32(size[:], uint32(len(b)))
+		if _, err := c.w.Write(size[:]); err != nil {
+			return err
+		}
+		if _, err := c.w.Write(b); err != nil {
+			return err
+		}
+	}
+	return c.w.Flush()
+}
+
+type clientCodec struct {
+	c    *conn
+	resp responseHeader
+}
+
+// NewClientCodec returns a new rpc.ClientCodec using igbinary on conn.
+func NewClientCodec(conn io.ReadWriteCloser) rpc.ClientCodec {
+	return &clientCodec{c: newConn(conn)}
+}
+
+func (c *clientCodec) WriteRequest(r *rpc.Request, body interface{}) error {
+	b, err := igbinary.Marshal(body)
+	if err != nil {
+		return err
+	}
+	return c.c.write(requestHeader{Method: r.ServiceMethod, Seq: r.Seq}, b)
+}
+
+func (c *clientCodec) ReadResponseHeader(r *rpc.Response) error {
+	c.resp = responseHeader{}
+	if err := c.c.readHeader(&c.resp); err != nil {
+		return err
+	}
+	r.ServiceMethod = c.resp.Method
+	r.Seq = c.resp.Seq
+	r.Error = c.resp.Error
+	return nil
+}
+
+func (c *clientCodec) ReadResponseBody(body interface{}) error {
+	return c.c.readBody(body)
+}
+
+func (c *clientCodec) Close() error {
+	return c.c.rwc.Close()
+}
+
+type serverCodec struct {
+	c   *conn
+	req requestHeader
+}
+
+// NewServerCodec returns a new rpc.ServerCodec using igbinary on conn.
+func NewServerCodec(conn io.ReadWriteCloser) rpc.ServerCodec {
+	return &serverCodec{c: newConn(conn)}
+}
+
+func (c *serverCodec) ReadRequestHeader(r *rpc.Request) error {
+	c.req = requestHeader{}
+	if err := c.c.readHeader(&c.req); err != nil {
+		return err
+	}
+	r.ServiceMethod = c.req.Method
+	r.Seq = c.req.Seq
+	return nil
+}
+
+func (c *serverCodec) ReadRequestBody(body interface{}) error {
+	return c.c.readBody(body)
+}
+
+func (c *serverCodec) WriteResponse(r *rpc.Response, body interface{}) error {
+	header := responseHeader{Method: r.ServiceMethod, Seq: r.Seq, Error: r.Error}
+	if r.Error != `` {
+		body = nil
+	}
+	b, err := igbinary.Marshal(body)
+	if err != nil {
+		// The call still gets its response, with the error and a null body.
+		header.Error = `rpc: cannot marshal reply: ` + err.Error()
+		if b, err = igbinary.Marshal(nil); err != nil {
+			return err
+		}
+	}
+	return c.c.write(header, b)
+}
+
+func (c *serverCodec) Close() error {
+	return c.c.rwc.Close()
+}
+
+// NewClient returns a new rpc.Client to handle requests to the set of
+// services at the other end of the connection.
+func NewClient(conn io.ReadWriteCloser) *rpc.Client {
+	return rpc.NewClientWithCodec(NewClientCodec(conn))
+}
+
+// Dial connects to an igbinary RPC server at the specified network address.
+func Dial(network, address string) (*rpc.Client, error) {
+	conn, err := net.Dial(network, address)
+	if err != nil {
+		return nil, err
+	}
+	return NewClient(conn), nil
+}
+
+// ServeConn runs the igbinary server on a single connection. ServeConn
+// blocks, serving the connection until the client hangs up.
+func ServeConn(conn io.ReadWriteCloser) {
+	rpc.ServeCodec(NewServerCodec(conn))
+}
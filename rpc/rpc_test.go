@@ -0,0 +1,219 @@
+package rpc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"github.com/stretchr/testify/suite"
+	"github.com/zarken-go/igbinary"
+	"io"
+	"net"
+	"net/rpc"
+	"sync"
+	"testing"
+)
+
+type Args struct {
+	A int64 `igbinary:"a"`
+	B int64 `igbinary:"b"`
+}
+
+type Quotient struct {
+	Quo int64 `igbinary:"quo"`
+	Rem int64 `igbinary:"rem"`
+}
+
+type Arith int64
+
+func (*Arith) Multiply(args Args, reply *int64) error {
+	*reply = args.A * args.B
+	return nil
+}
+
+func (*Arith) Divide(args Args, reply *Quotient) error {
+	if args.B == 0 {
+		return errors.New(`divide by zero`)
+	}
+	*reply = Quotient{Quo: args.A / args.B, Rem: args.A % args.B}
+	return nil
+}
+
+// Callback cannot be marshalled.
+type Callback struct {
+	F func() `igbinary:"f"`
+}
+
+func (*Arith) Callback(args Args, reply *Callback) error {
+	reply.F = func() {}
+	return nil
+}
+
+type RPCSuite struct {
+	suite.Suite
+}
+
+// dial returns a client talking to a server with Arith over a pipe.
+func (Suite *RPCSuite) dial() *rpc.Client {
+	Server := rpc.NewServer()
+	Suite.Require().Nil(Server.Register(new(Arith)))
+	ClientConn, ServerConn := net.Pipe()
+	go Server.ServeCodec(NewServerCodec(ServerConn))
+	return NewClient(ClientConn)
+}
+
+// frame returns v marshalled and prefixed with its length.
+func (Suite *RPCSuite) frame(v interface{}) []byte {
+	B, err := igbinary.Marshal(v)
+	Suite.Require().Nil(err)
+	Size := make([]byte, 4)
+	binary.BigEndian.PutUint32(Size, uint32(len(B)))
+	return append(Size, B...)
+}
+
+func (Suite *RPCSuite) TestCall() {
+	Client := Suite.dial()
+	defer Client.Close()
+
+	var Product int64
+	Suite.Nil(Client.Call(`Arith.Multiply`, Args{A: 7, B: 8}, &Product))
+	Suite.Equal(int64(56), Product)
+
+	var Reply Quotient
+	Suite.Nil(Client.Call(`Arith.Divide`, Args{A: 17, B: 5}, &Reply))
+	Suite.Equal(Quotient{Quo: 3, Rem: 2}, Reply)
+
+	err := Client.Call(`Arith.Divide`, Args{A: 1}, &Reply)
+	Suite.Equal(rpc.ServerError(`divide by zero`), err)
+
+	err = Client.Call(`Arith.Missing`, Args{}, &Reply)
+	Suite.EqualError(err, `rpc: can't find method Arith.Missing`)
+
+	// The connection is still usable after errors.
+	Suite.Nil(Client.Call(`Arith.Multiply`, Args{A: 2, B: 3}, &Product))
+	Suite.Equal(int64(6), Product)
+}
+
+func (Suite *RPCSuite) TestConcurrentCalls() {
+	Client := Suite.dial()
+	defer Client.Close()
+
+	var Group sync.WaitGroup
+	for I := int64(0); I < 20; I++ {
+		Group.Add(1)
+		go func(I int64) {
+			defer Group.Done()
+			var Product int64
+			Suite.Nil(Client.Call(`Arith.Multiply`, Args{A: I, B: I}, &Product))
+			Suite.Equal(I*I, Product)
+		}(I)
+	}
+	Group.Wait()
+}
+
+func (Suite *RPCSuite) TestWireFormat() {
+	ClientConn, ServerConn := net.Pipe()
+	defer ClientConn.Close()
+	Server := rpc.NewServer()
+	Suite.Require().Nil(Server.Register(new(Arith)))
+	go Server.ServeCodec(NewServerCodec(ServerConn))
+
+	Codec := newConn(ClientConn)
+	for Seq := uint64(1); Seq <= 2; Seq++ {
+		// A request as a PHP client writes it, each document on its own.
+		var Request bytes.Buffer
+		Request.Write(Suite.frame(igbinary.Array{{Key: `method`, Value: `Arith.Multiply`}, {Key: `seq`, Value: int64(Seq)}}))
+		Request.Write(Suite.frame(igbinary.Array{{Key: `a`, Value: int64(Seq)}, {Key: `b`, Value: int64(10)}}))
+		go func() {
+			_, _ = ClientConn.Write(Request.Bytes())
+		}()
+
+		// Every header repeats its keys in full, the string table of a
+		// document does not carry over to the next.
+		Header, err := Codec.readMessage()
+		Suite.Require().Nil(err)
+		Suite.Equal(Suite.frame(responseHeader{Method: `Arith.Multiply`, Seq: Seq})[4:], Header)
+		var Value interface{}
+		Suite.Nil(igbinary.Unmarshal(Header, &Value))
+		Suite.Equal(igbinary.Array{
+			{Key: `method`, Value: `Arith.Multiply`},
+			{Key: `seq`, Value: int64(Seq)},
+			{Key: `error`, Value: ``},
+		}, Value)
+
+		Body, err := Codec.readMessage()
+		Suite.Require().Nil(err)
+		var Product int64
+		Suite.Nil(igbinary.Unmarshal(Body, &Product))
+		Suite.Equal(int64(Seq)*10, Product)
+	}
+}
+
+func (Suite *RPCSuite) TestErrorResponse() {
+	var Buffer bytes.Buffer
+	Codec := NewServerCodec(nopCloser{&Buffer})
+	Suite.Nil(Codec.WriteResponse(&rpc.Response{ServiceMethod: `Arith.Divide`, Seq: 3, Error: `divide by zero`}, &Quotient{}))
+
+	// The body of an error is null.
+	Client := NewClientCodec(nopCloser{&Buffer})
+	var Response rpc.Response
+	Suite.Nil(Client.ReadResponseHeader(&Response))
+	Suite.Equal(rpc.Response{ServiceMethod: `Arith.Divide`, Seq: 3, Error: `divide by zero`}, Response)
+	var Body interface{}
+	Suite.Nil(Client.ReadResponseBody(&Body))
+	Suite.Nil(Body)
+
+	// A null error reads as success.
+	Buffer.Write(Suite.frame(igbinary.Array{{Key: `method`, Value: `Arith.Divide`}, {Key: `seq`, Value: int64(4)}, {Key: `error`, Value: nil}}))
+	Buffer.Write(Suite.frame(igbinary.Array{{Key: `quo`, Value: int64(1)}}))
+	Suite.Nil(Client.ReadResponseHeader(&Response))
+	Suite.Equal(rpc.Response{ServiceMethod: `Arith.Divide`, Seq: 4}, Response)
+	Suite.Nil(Client.ReadResponseBody(nil))
+}
+
+func (Suite *RPCSuite) TestMarshalErrors() {
+	Client := Suite.dial()
+	defer Client.Close()
+
+	// A reply that cannot be marshalled is answered with the error.
+	var Reply Callback
+	err := Client.Call(`Arith.Callback`, Args{}, &Reply)
+	Suite.Equal(rpc.ServerError(`rpc: cannot marshal reply: igbinary: Encode(unsupported func())`), err)
+
+	// Arguments that cannot be marshalled fail the call before anything is
+	// written.
+	var Product int64
+	err = Client.Call(`Arith.Multiply`, Callback{F: func() {}}, &Product)
+	Suite.EqualError(err, `igbinary: Encode(unsupported func())`)
+	var Buffer bytes.Buffer
+	Codec := NewClientCodec(nopCloser{&Buffer})
+	Suite.Error(Codec.WriteRequest(&rpc.Request{ServiceMethod: `Arith.Multiply`, Seq: 1}, Callback{F: func() {}}))
+	Suite.Zero(Buffer.Len())
+
+	// The stream is still in step.
+	Suite.Nil(Client.Call(`Arith.Multiply`, Args{A: 2, B: 3}, &Product))
+	Suite.Equal(int64(6), Product)
+}
+
+func (Suite *RPCSuite) TestReadErrors() {
+	Codec := NewServerCodec(nopCloser{bytes.NewBuffer(nil)})
+	Suite.Equal(io.EOF, Codec.ReadRequestHeader(&rpc.Request{}))
+
+	// A header without its body.
+	Codec = NewServerCodec(nopCloser{bytes.NewBuffer(Suite.frame(requestHeader{Method: `Arith.Multiply`}))})
+	Suite.Equal(io.ErrUnexpectedEOF, Codec.ReadRequestHeader(&rpc.Request{}))
+
+	Codec = NewServerCodec(nopCloser{bytes.NewBuffer([]byte{0xff, 0xff, 0xff, 0xff})})
+	Suite.EqualError(Codec.ReadRequestHeader(&rpc.Request{}), `rpc: message of 4294967295 bytes exceeds MaxMessageSize`)
+}
+
+type nopCloser struct {
+	io.ReadWriter
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
+
+func TestRPCSuite(t *testing.T) {
+	suite.Run(t, new(RPCSuite))
+}
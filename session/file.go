@@ -0,0 +1,72 @@
+package session
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// FileStore reads the sessions PHP's files save handler keeps in the
+// session.save_path directory, as sess_<id> files.
+type FileStore struct {
+	// Dir is the directory holding the session files.
+	Dir string
+	// Depth is the number of subdirectory levels, named after the leading
+	// characters of the session ID.
+	Depth int
+	// Handler is the session.serialize_handler the files are written with.
+	Handler Handler
+}
+
+// NewFileStore returns a FileStore for a session.save_path setting, either
+// a directory or "N;/path" and "N;MODE;/path" for N levels of
+// subdirectories.
+func NewFileStore(savePath string, handler Handler) (*FileStore, error) {
+	s := &FileStore{Dir: savePath, Handler: handler}
+	parts := strings.Split(savePath, `;`)
+	if len(parts) > 3 {
+		return nil, fmt.Errorf(`session: invalid save path %q`, savePath)
+	}
+	if len(parts) > 1 {
+		depth, err := strconv.Atoi(parts[0])
+		if err != nil || depth < 0 {
+			return nil, fmt.Errorf(`session: invalid save path depth %q`, parts[0])
+		}
+		s.Depth, s.Dir = depth, parts[len(parts)-1]
+	}
+	return s, nil
+}
+
+// Path returns the file of the session id. IDs are restricted to the
+// characters PHP generates them from, a-z, A-Z, 0-9, ',' and '-'.
+func (s *FileStore) Path(id string) (string, error) {
+	if id == `` || len(id) < s.Depth {
+		return ``, fmt.Errorf(`session: invalid session id %q`, id)
+	}
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == ',' || c == '-') {
+			return ``, fmt.Errorf(`session: invalid session id %q`, id)
+		}
+	}
+	elems := []string{s.Dir}
+	for i := 0; i < s.Depth; i++ {
+		elems = append(elems, id[i:i+1])
+	}
+	return filepath.Join(append(elems, `sess_`+id)...), nil
+}
+
+// Load reads and decodes the session id. Errors of missing files satisfy
+// os.IsNotExist.
+func (s *FileStore) Load(id string) (Data, error) {
+	path, err := s.Path(id)
+	if err != nil {
+		return nil, err
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Unmarshal(s.Handler, b)
+}
@@ -0,0 +1,55 @@
+package session
+
+import (
+	"github.com/stretchr/testify/suite"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type FileSuite struct {
+	suite.Suite
+}
+
+func (Suite *FileSuite) TestNewFileStore() {
+	for SavePath, Expected := range map[string]FileStore{
+		`/var/lib/php/sessions`:       {Dir: `/var/lib/php/sessions`, Handler: PHP},
+		`2;/var/lib/php/sessions`:     {Dir: `/var/lib/php/sessions`, Depth: 2, Handler: PHP},
+		`2;0600;/var/lib/php/session`: {Dir: `/var/lib/php/session`, Depth: 2, Handler: PHP},
+	} {
+		Store, err := NewFileStore(SavePath, PHP)
+		Suite.Nil(err)
+		Suite.Equal(Expected, *Store)
+	}
+
+	_, err := NewFileStore(`x;/tmp`, PHP)
+	Suite.EqualError(err, `session: invalid save path depth "x"`)
+}
+
+func (Suite *FileSuite) TestLoad() {
+	Dir, err := ioutil.TempDir(``, `session`)
+	Suite.Require().Nil(err)
+	defer os.RemoveAll(Dir)
+
+	Store, err := NewFileStore(`1;`+Dir, PHP)
+	Suite.Require().Nil(err)
+	Path, err := Store.Path(`abc123`)
+	Suite.Require().Nil(err)
+	Suite.Equal(filepath.Join(Dir, `a`, `sess_abc123`), Path)
+
+	Suite.Require().Nil(os.Mkdir(filepath.Join(Dir, `a`), 0o700))
+	Suite.Require().Nil(ioutil.WriteFile(Path, []byte(`count|i:3;`), 0o600))
+	Session, err := Store.Load(`abc123`)
+	Suite.Nil(err)
+	Suite.Equal(Data{{Name: `count`, Value: int64(3)}}, Session)
+
+	_, err = Store.Load(`missing`)
+	Suite.True(os.IsNotExist(err))
+	_, err = Store.Load(`../../etc/passwd`)
+	Suite.EqualError(err, `session: invalid session id "../../etc/passwd"`)
+}
+
+func TestFileSuite(t *testing.T) {
+	suite.Run(t, new(FileSuite))
+}
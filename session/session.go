@@ -0,0 +1,209 @@
+// Package session reads and writes PHP session data, the serialized
+// $_SESSION array, in the formats of PHP's session.serialize_handler
+// setting. Values use the generic types of package igbinary, as returned by
+// Decoder.DecodeInterface.
+package session
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/zarken-go/igbinary"
+	"github.com/zarken-go/igbinary/internal/php"
+	"github.com/zarken-go/igbinary/phpserialize"
+	"strconv"
+)
+
+// Handler names a session.serialize_handler format.
+type Handler string
+
+const (
+	// Igbinary writes $_SESSION with igbinary_serialize.
+	Igbinary Handler = `igbinary`
+	// PHP writes each variable as name|value, PHP's default.
+	PHP Handler = `php`
+	// PHPBinary writes each variable as a length byte, the name and the
+	// value.
+	PHPBinary Handler = `php_binary`
+	// PHPSerialize writes $_SESSION with serialize.
+	PHPSerialize Handler = `php_serialize`
+)
+
+// binaryMaxName is the longest variable name php_binary writes, larger
+// lengths flag the undefined variables of PHP 5.
+const binaryMaxName = 0x7f
+
+// Variable is a session variable.
+type Variable struct {
+	Name  string
+	Value interface{}
+}
+
+// Data holds the variables of a session in order.
+type Data []Variable
+
+// Get returns the value of the variable name.
+func (s Data) Get(name string) (interface{}, bool) {
+	for _, v := range s {
+		if v.Name == name {
+			return v.Value, true
+		}
+	}
+	return nil, false
+}
+
+// Set sets the variable name, appending it when it is not set yet.
+func (s *Data) Set(name string, value interface{}) {
+	for i := range *s {
+		if (*s)[i].Name == name {
+			(*s)[i].Value = value
+			return
+		}
+	}
+	*s = append(*s, Variable{Name: name, Value: value})
+}
+
+// Delete removes the variable name.
+func (s *Data) Delete(name string) {
+	for i := range *s {
+		if (*s)[i].Name == name {
+			*s = append((*s)[:i], (*s)[i+1:]...)
+			return
+		}
+	}
+}
+
+// Unmarshal decodes session data written by handler. Empty data is an
+// empty session.
+func Unmarshal(handler Handler, data []byte) (Data, error) {
+	if len(data) == 0 {
+		return Data{}, nil
+	}
+	switch handler {
+	case Igbinary:
+		var v interface{}
+		if err := igbinary.Unmarshal(data, &v); err != nil {
+			return nil, err
+		}
+		return fromArray(v)
+	case PHPSerialize:
+		v, err := phpserialize.Unmarshal(data)
+		if err != nil {
+			return nil, err
+		}
+		return fromArray(v)
+	case PHP:
+		return unmarshalVariables(data, func(pos int) (string, int, error) {
+			i := bytes.IndexByte(data[pos:], '|')
+			if i < 0 {
+				return ``, 0, fmt.Errorf(`session: offset %d: missing '|' after variable name`, pos)
+			}
+			return string(data[pos : pos+i]), pos + i + 1, nil
+		})
+	case PHPBinary:
+		return unmarshalVariables(data, func(pos int) (string, int, error) {
+			n := int(data[pos])
+			if n > binaryMaxName {
+				return ``, 0, fmt.Errorf(`session: offset %d: undefined variables are not supported`, pos)
+			}
+			if pos+1+n > len(data) {
+				return ``, 0, fmt.Errorf(`session: offset %d: variable name of length %d exceeds data`, pos, n)
+			}
+			return string(data[pos+1 : pos+1+n]), pos + 1 + n, nil
+		})
+	}
+	return nil, fmt.Errorf(`session: unsupported handler %q`, handler)
+}
+
+// unmarshalVariables decodes the variables of the php and php_binary
+// formats, name reads the variable name at an offset and returns the offset
+// of its value. The values share one reference table.
+func unmarshalVariables(data []byte, name func(pos int) (string, int, error)) (Data, error) {
+	d := phpserialize.NewDecoder(data)
+	s := Data{}
+	for pos := 0; pos < len(data); {
+		n, next, err := name(pos)
+		if err != nil {
+			return nil, err
+		}
+		v, next, err := d.DecodeAt(next)
+		if err != nil {
+			return nil, err
+		}
+		s = append(s, Variable{Name: n, Value: v})
+		pos = next
+	}
+	return s, nil
+}
+
+// fromArray returns the variables of the decoded $_SESSION array v.
+func fromArray(v interface{}) (Data, error) {
+	arr, ok := v.(igbinary.Array)
+	if !ok {
+		return nil, fmt.Errorf(`session: data holds %T, not an array`, v)
+	}
+	s := make(Data, 0, len(arr))
+	for _, entry := range arr {
+		var name string
+		switch k := entry.Key.(type) {
+		case string:
+			name = k
+		case int64:
+			name = strconv.FormatInt(k, 10)
+		}
+		s = append(s, Variable{Name: name, Value: entry.Value})
+	}
+	return s, nil
+}
+
+// Marshal encodes s in the format of handler. Values other than the
+// generic igbinary types are converted to them first, for the php formats
+// through an igbinary round trip.
+func Marshal(handler Handler, s Data) ([]byte, error) {
+	switch handler {
+	case Igbinary:
+		return igbinary.Marshal(s.array())
+	case PHPSerialize:
+		arr := s.array()
+		for i := range arr {
+			v, err := php.Generic(arr[i].Value)
+			if err != nil {
+				return nil, err
+			}
+			arr[i].Value = v
+		}
+		return phpserialize.Marshal(arr)
+	case PHP, PHPBinary:
+		e := phpserialize.NewEncoder()
+		var b []byte
+		for _, v := range s {
+			if handler == PHP {
+				if bytes.ContainsAny([]byte(v.Name), `|!`) {
+					return nil, fmt.Errorf(`session: variable name %q contains '|' or '!'`, v.Name)
+				}
+				b = append(append(b, v.Name...), '|')
+			} else {
+				if len(v.Name) > binaryMaxName {
+					return nil, fmt.Errorf(`session: variable name %q longer than %d bytes`, v.Name, binaryMaxName)
+				}
+				b = append(append(b, byte(len(v.Name))), v.Name...)
+			}
+			value, err := php.Generic(v.Value)
+			if err != nil {
+				return nil, err
+			}
+			if b, err = e.Append(b, value); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	}
+	return nil, fmt.Errorf(`session: unsupported handler %q`, handler)
+}
+
+func (s Data) array() igbinary.Array {
+	arr := make(igbinary.Array, 0, len(s))
+	for _, v := range s {
+		arr = append(arr, igbinary.Entry{Key: v.Name, Value: v.Value})
+	}
+	return arr
+}
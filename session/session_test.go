@@ -0,0 +1,96 @@
+package session
+
+import (
+	"github.com/stretchr/testify/suite"
+	"github.com/zarken-go/igbinary"
+	"testing"
+)
+
+type SessionSuite struct {
+	suite.Suite
+}
+
+type testUser struct {
+	Name string `igbinary:"name"`
+}
+
+var testUserArray = igbinary.Array{{Key: `name`, Value: `Ann`}}
+
+func (Suite *SessionSuite) TestHandlers() {
+	Session := Data{{Name: `user`, Value: testUserArray}, {Name: `count`, Value: int64(3)}}
+	for Handler, Expected := range map[Handler]string{
+		PHP:          `user|a:1:{s:4:"name";s:3:"Ann";}count|i:3;`,
+		PHPBinary:    "\x04user" + `a:1:{s:4:"name";s:3:"Ann";}` + "\x05count" + `i:3;`,
+		PHPSerialize: `a:2:{s:4:"user";a:1:{s:4:"name";s:3:"Ann";}s:5:"count";i:3;}`,
+		Igbinary: "\x00\x00\x00\x02\x14\x02\x11\x04user\x14\x01\x11\x04name\x11\x03Ann" +
+			"\x11\x05count\x06\x03",
+	} {
+		B, err := Marshal(Handler, Session)
+		Suite.Nil(err, Handler)
+		Suite.Equal(Expected, string(B), Handler)
+
+		Decoded, err := Unmarshal(Handler, B)
+		Suite.Nil(err, Handler)
+		Suite.Equal(Session, Decoded, Handler)
+	}
+}
+
+func (Suite *SessionSuite) TestGoValues() {
+	// Go values are converted to the generic types for the php formats.
+	for _, Handler := range []Handler{PHP, PHPBinary, PHPSerialize, Igbinary} {
+		B, err := Marshal(Handler, Data{{Name: `user`, Value: testUser{Name: `Ann`}}})
+		Suite.Nil(err, Handler)
+		Decoded, err := Unmarshal(Handler, B)
+		Suite.Nil(err, Handler)
+		Suite.Equal(Data{{Name: `user`, Value: testUserArray}}, Decoded, Handler)
+	}
+}
+
+func (Suite *SessionSuite) TestSharedReferences() {
+	// The php formats share one reference table between variables.
+	Decoded, err := Unmarshal(PHP, []byte(`a|O:3:"Foo":0:{}b|r:1;`))
+	Suite.Require().Nil(err)
+	A, _ := Decoded.Get(`a`)
+	B, _ := Decoded.Get(`b`)
+	Suite.Same(A, B)
+
+	Encoded, err := Marshal(PHPBinary, Decoded)
+	Suite.Nil(err)
+	Suite.Equal("\x01a"+`O:3:"Foo":0:{}`+"\x01b"+`r:1;`, string(Encoded))
+}
+
+func (Suite *SessionSuite) TestData() {
+	var Session Data
+	Session.Set(`a`, int64(1))
+	Session.Set(`b`, int64(2))
+	Session.Set(`a`, int64(3))
+	Value, ok := Session.Get(`a`)
+	Suite.True(ok)
+	Suite.Equal(int64(3), Value)
+
+	Session.Delete(`a`)
+	_, ok = Session.Get(`a`)
+	Suite.False(ok)
+	Suite.Equal(Data{{Name: `b`, Value: int64(2)}}, Session)
+
+	Empty, err := Unmarshal(PHP, nil)
+	Suite.Nil(err)
+	Suite.Equal(Data{}, Empty)
+}
+
+func (Suite *SessionSuite) TestErrors() {
+	_, err := Unmarshal(PHP, []byte(`i:1;`))
+	Suite.EqualError(err, `session: offset 0: missing '|' after variable name`)
+	_, err = Unmarshal(PHPBinary, []byte("\x85a"))
+	Suite.EqualError(err, `session: offset 0: undefined variables are not supported`)
+	_, err = Unmarshal(PHPSerialize, []byte(`i:1;`))
+	Suite.EqualError(err, `session: data holds int64, not an array`)
+	_, err = Unmarshal(`wddx`, []byte(`x`))
+	Suite.EqualError(err, `session: unsupported handler "wddx"`)
+	_, err = Marshal(PHP, Data{{Name: `a|b`}})
+	Suite.EqualError(err, `session: variable name "a|b" contains '|' or '!'`)
+}
+
+func TestSessionSuite(t *testing.T) {
+	suite.Run(t, new(SessionSuite))
+}
@@ -0,0 +1,189 @@
+package igbinary
+
+// Classes of the PHP built-ins with Go adapters.
+const (
+	StdClass              = `stdClass`
+	ArrayObjectClass      = `ArrayObject`
+	ArrayIteratorClass    = `ArrayIterator`
+	SplFixedArrayClass    = `SplFixedArray`
+	SplObjectStorageClass = `SplObjectStorage`
+)
+
+// ArrayObject models PHP's ArrayObject and ArrayIterator, serialized by
+// __serialize as the list [flags, storage, members, iterator class].
+type ArrayObject struct {
+	// Class is ArrayObject, ArrayIterator or a subclass, empty encodes as
+	// ArrayObject.
+	Class string
+	Flags int64
+	// Storage holds the entries. A wrapped object contributes its
+	// properties.
+	Storage Array
+	// Members holds the properties set on the ArrayObject itself.
+	Members Array
+	// IteratorClass is empty for the default ArrayIterator.
+	IteratorClass string
+}
+
+// MarshalIgbinary implements Marshaler.
+func (a ArrayObject) MarshalIgbinary(e *Encoder) error {
+	class := a.Class
+	if class == `` {
+		class = ArrayObjectClass
+	}
+	var iteratorClass interface{}
+	if a.IteratorClass != `` {
+		iteratorClass = a.IteratorClass
+	}
+	storage, members := a.Storage, a.Members
+	if storage == nil {
+		storage = Array{}
+	}
+	if members == nil {
+		members = Array{}
+	}
+	return e.encodeList(class, a.Flags, storage, members, iteratorClass)
+}
+
+// UnmarshalIgbinary implements Unmarshaler.
+func (a *ArrayObject) UnmarshalIgbinary(d *Decoder) error {
+	*a = ArrayObject{}
+	class, err := d.decodeList(func(class string, i int64, v interface{}) error {
+		var ok bool
+		switch i {
+		case 0:
+			a.Flags, ok = v.(int64)
+		case 1:
+			switch storage := v.(type) {
+			case nil:
+				ok = true
+			case Array:
+				a.Storage, ok = storage, true
+			case *Object:
+				a.Storage, ok = storage.Props, true
+			}
+		case 2:
+			a.Members, ok = v.(Array)
+		case 3:
+			a.IteratorClass, ok = v.(string)
+			ok = ok || v == nil
+		default:
+			ok = true
+		}
+		if !ok {
+			return decodeErrorF(`invalid %s entry %d of type %T`, class, i, v)
+		}
+		return nil
+	})
+	a.Class = class
+	return err
+}
+
+// SplFixedArray models PHP's SplFixedArray, serialized as an object whose
+// integer keyed properties are the elements. Properties set on the object
+// itself are dropped.
+type SplFixedArray []interface{}
+
+// MarshalIgbinary implements Marshaler.
+func (a SplFixedArray) MarshalIgbinary(e *Encoder) error {
+	return e.encodeList(SplFixedArrayClass, a...)
+}
+
+// UnmarshalIgbinary implements Unmarshaler.
+func (a *SplFixedArray) UnmarshalIgbinary(d *Decoder) error {
+	*a = SplFixedArray{}
+	_, err := d.decodeList(func(class string, i int64, v interface{}) error {
+		if i != int64(len(*a)) {
+			return decodeErrorF(`invalid %s index %d`, class, i)
+		}
+		*a = append(*a, v)
+		return nil
+	})
+	return err
+}
+
+// SplObjectStorageEntry is an object attached to an SplObjectStorage along
+// with its data.
+type SplObjectStorageEntry struct {
+	Object interface{}
+	Data   interface{}
+}
+
+// SplObjectStorage models PHP's SplObjectStorage, serialized by __serialize
+// as [[object, data, ...], members]. Properties set on the storage itself
+// are dropped.
+type SplObjectStorage []SplObjectStorageEntry
+
+// MarshalIgbinary implements Marshaler.
+func (s SplObjectStorage) MarshalIgbinary(e *Encoder) error {
+	storage := make(Array, 0, 2*len(s))
+	for _, entry := range s {
+		storage = append(storage,
+			Entry{Key: int64(len(storage)), Value: entry.Object},
+			Entry{Key: int64(len(storage) + 1), Value: entry.Data})
+	}
+	return e.encodeList(SplObjectStorageClass, storage, Array{})
+}
+
+// UnmarshalIgbinary implements Unmarshaler.
+func (s *SplObjectStorage) UnmarshalIgbinary(d *Decoder) error {
+	*s = SplObjectStorage{}
+	_, err := d.decodeList(func(class string, i int64, v interface{}) error {
+		if i != 0 {
+			return nil
+		}
+		storage, ok := v.(Array)
+		if !ok || len(storage)%2 != 0 {
+			return decodeErrorF(`invalid %s storage of type %T`, class, v)
+		}
+		for j := 0; j < len(storage); j += 2 {
+			*s = append(*s, SplObjectStorageEntry{Object: storage[j].Value, Data: storage[j+1].Value})
+		}
+		return nil
+	})
+	return err
+}
+
+// encodeList encodes an object of class whose properties are the list
+// values, the layout __serialize produces.
+func (e *Encoder) encodeList(class string, values ...interface{}) error {
+	if err := e.EncodeObjectLen(class, len(values)); err != nil {
+		return err
+	}
+	for i, v := range values {
+		if err := e.EncodeInt64(int64(i)); err != nil {
+			return err
+		}
+		if err := e.Encode(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeList decodes an object, calling fn for each of its integer keyed
+// properties and skipping string keyed ones, and returns its class.
+func (d *Decoder) decodeList(fn func(class string, i int64, v interface{}) error) (string, error) {
+	class, n, err := d.DecodeObjectLen()
+	if err != nil {
+		return ``, err
+	}
+	for i := 0; i < n; i++ {
+		k, err := d.decodeKey()
+		if err != nil {
+			return ``, err
+		}
+		v, err := d.DecodeInterface()
+		if err != nil {
+			return ``, err
+		}
+		key, ok := k.(int64)
+		if !ok {
+			continue
+		}
+		if err := fn(class, key, v); err != nil {
+			return ``, err
+		}
+	}
+	return class, nil
+}
@@ -0,0 +1,120 @@
+package igbinary
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/suite"
+	"testing"
+)
+
+type SplSuite struct {
+	suite.Suite
+}
+
+func (Suite *SplSuite) TestArrayObject() {
+	// new ArrayObject(['a' => 1, 'b' => 2]) as serialized by PHP 7.4 and later
+	B, err := Marshal(&Object{Class: ArrayObjectClass, Props: Array{
+		{Key: int64(0), Value: int64(0)},
+		{Key: int64(1), Value: Array{{Key: `a`, Value: int64(1)}, {Key: `b`, Value: int64(2)}}},
+		{Key: int64(2), Value: Array{}},
+		{Key: int64(3), Value: nil},
+	}})
+	Suite.Require().Nil(err)
+
+	Encoded, err := Marshal(ArrayObject{Storage: Array{{Key: `a`, Value: int64(1)}, {Key: `b`, Value: int64(2)}}})
+	Suite.Nil(err)
+	Suite.Equal(B, Encoded)
+
+	var Decoded ArrayObject
+	Suite.Nil(Unmarshal(B, &Decoded))
+	Suite.Equal(ArrayObject{
+		Class:   ArrayObjectClass,
+		Storage: Array{{Key: `a`, Value: int64(1)}, {Key: `b`, Value: int64(2)}},
+		Members: Array{},
+	}, Decoded)
+
+	Iterator := ArrayObject{Class: ArrayIteratorClass, Flags: 2, Storage: Array{{Key: int64(0), Value: `x`}},
+		Members: Array{{Key: `p`, Value: true}}, IteratorClass: `Foo`}
+	B, err = Marshal(Iterator)
+	Suite.Require().Nil(err)
+	Decoded = ArrayObject{}
+	Suite.Nil(Unmarshal(B, &Decoded))
+	Suite.Equal(Iterator, Decoded)
+
+	B, err = Marshal(&Object{Class: ArrayObjectClass, Props: Array{{Key: int64(0), Value: `x`}}})
+	Suite.Require().Nil(err)
+	Suite.EqualError(Unmarshal(B, &Decoded), `igbinary: Decode(invalid ArrayObject entry 0 of type string)`)
+}
+
+func (Suite *SplSuite) TestSplFixedArray() {
+	Fixed := SplFixedArray{int64(1), `two`, nil}
+	B, err := Marshal(Fixed)
+	Suite.Require().Nil(err)
+
+	var Decoded SplFixedArray
+	Suite.Nil(Unmarshal(B, &Decoded))
+	Suite.Equal(Fixed, Decoded)
+
+	// PHP 8.2 appends properties set on the object to the elements.
+	B, err = Marshal(&Object{Class: SplFixedArrayClass, Props: Array{
+		{Key: int64(0), Value: int64(1)}, {Key: `p`, Value: int64(2)},
+	}})
+	Suite.Require().Nil(err)
+	Suite.Nil(Unmarshal(B, &Decoded))
+	Suite.Equal(SplFixedArray{int64(1)}, Decoded)
+}
+
+func (Suite *SplSuite) TestSplObjectStorage() {
+	Foo := &Object{Class: `Foo`, Props: Array{}}
+	Storage := SplObjectStorage{{Object: Foo, Data: `first`}, {Object: &Object{Class: `Bar`, Props: Array{}}, Data: Foo}}
+	B, err := Marshal(Storage)
+	Suite.Require().Nil(err)
+
+	var Decoded SplObjectStorage
+	Suite.Nil(Unmarshal(B, &Decoded))
+	Suite.Equal(Storage, Decoded)
+	Suite.Same(Decoded[0].Object, Decoded[1].Data)
+
+	B, err = Marshal(&Object{Class: SplObjectStorageClass, Props: Array{
+		{Key: int64(0), Value: Array{{Key: int64(0), Value: Foo}}},
+	}})
+	Suite.Require().Nil(err)
+	Suite.EqualError(Unmarshal(B, &Decoded), `igbinary: Decode(invalid SplObjectStorage storage of type igbinary.Array)`)
+}
+
+func (Suite *SplSuite) TestStdClass() {
+	B, err := Marshal(&Object{Class: StdClass, Props: Array{
+		{Key: `name`, Value: `Ann`}, {Key: `email`, Value: `ann@example.com`},
+	}})
+	Suite.Require().Nil(err)
+
+	var Map map[string]interface{}
+	Suite.Nil(Unmarshal(B, &Map))
+	Suite.Equal(map[string]interface{}{`name`: `Ann`, `email`: `ann@example.com`}, Map)
+
+	var Strings map[string]string
+	Suite.Nil(Unmarshal(B, &Strings))
+	Suite.Equal(map[string]string{`name`: `Ann`, `email`: `ann@example.com`}, Strings)
+
+	var Profile testProfile
+	Suite.Nil(Unmarshal(B, &Profile))
+	Suite.Equal(testProfile{Name: `Ann`, Email: `ann@example.com`}, Profile)
+
+	var buf bytes.Buffer
+	Encoder := NewEncoder(&buf)
+	Encoder.SetMapsAsStdClass(true)
+	Encoder.SetSortMapKeys(true)
+	Suite.Require().Nil(Encoder.Encode(map[string]interface{}{
+		`name`: `Ann`, `tags`: map[int]string{0: `a`},
+	}))
+
+	var Value interface{}
+	Suite.Require().Nil(Unmarshal(buf.Bytes(), &Value))
+	Suite.Equal(&Object{Class: StdClass, Props: Array{
+		{Key: `name`, Value: `Ann`},
+		{Key: `tags`, Value: Array{{Key: int64(0), Value: `a`}}},
+	}}, Value)
+}
+
+func TestSplSuite(t *testing.T) {
+	suite.Run(t, new(SplSuite))
+}
@@ -0,0 +1,255 @@
+// Package symfony reads and writes the items of Symfony Cache pools:
+// values marshalled by DefaultMarshaller with igbinary or serialize, the
+// metadata adapters store around them and the files of FilesystemAdapter.
+package symfony
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"github.com/zarken-go/igbinary"
+	"github.com/zarken-go/igbinary/internal/php"
+	"github.com/zarken-go/igbinary/phpserialize"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// expiryOffset is CacheItem::METADATA_EXPIRY_OFFSET, subtracted from
+// expiry timestamps to pack them.
+const expiryOffset = 1527506807
+
+// Magic bytes around the packed metadata key of plain adapters.
+const (
+	metadataKeyLen   = 10
+	metadataKeyStart = 0x9d
+	metadataKeyEnd   = 0x5f
+)
+
+// Marshaller marshals values like DefaultMarshaller, with igbinary when
+// Igbinary is set, as Symfony does when the extension is loaded, and with
+// serialize otherwise. Unmarshal detects the format of each value.
+type Marshaller struct {
+	Igbinary bool
+}
+
+// Marshal returns the marshalled value of v.
+func (m Marshaller) Marshal(v interface{}) ([]byte, error) {
+	if m.Igbinary {
+		return igbinary.Marshal(v)
+	}
+	value, err := php.Generic(v)
+	if err != nil {
+		return nil, err
+	}
+	return phpserialize.Marshal(value)
+}
+
+// Unmarshal decodes the marshalled value data into v, as igbinary.Unmarshal
+// does. Like DefaultMarshaller, data whose second byte is ':' is read with
+// serialize and other data with igbinary, except for the serialized null.
+func (m Marshaller) Unmarshal(data []byte, v interface{}) error {
+	if len(data) < 2 || data[1] == ':' || string(data) == `N;` {
+		value, err := phpserialize.Unmarshal(data)
+		if err != nil {
+			return err
+		}
+		return php.Assign(value, v)
+	}
+	return igbinary.Unmarshal(data, v)
+}
+
+// Item is a cache item with its metadata.
+type Item struct {
+	Value interface{}
+	// Expiry is the time the item expires at, zero if it does not.
+	Expiry time.Time
+	// Ctime is the time computing the value took, in milliseconds as
+	// stored by Symfony.
+	Ctime time.Duration
+	// Tags are the tags of items of tag aware adapters.
+	Tags []string
+}
+
+// Decode decodes the value of the item into v, as igbinary.Unmarshal does.
+func (i Item) Decode(v interface{}) error {
+	return php.Assign(i.Value, v)
+}
+
+func (i Item) hasMetadata() bool {
+	return !i.Expiry.IsZero() || i.Ctime != 0
+}
+
+// packMetadata packs expiry and ctime as pack('VN', ...) does.
+func (i Item) packMetadata() []byte {
+	var expiry int64
+	if !i.Expiry.IsZero() {
+		expiry = i.Expiry.Unix()
+	}
+	b := make([]byte, 8)
+	binary.LittleEndian.PutUint32(b, uint32(expiry-expiryOffset))
+	binary.BigEndian.PutUint32(b[4:], uint32(i.Ctime/time.Millisecond))
+	return b
+}
+
+// unpackMetadata sets the expiry and ctime packed in b. Items saved without
+// expiry pack 0, which unpacks to 2^32.
+func (i *Item) unpackMetadata(b []byte) {
+	if expiry := int64(binary.LittleEndian.Uint32(b)) + expiryOffset; expiry != 1<<32 {
+		i.Expiry = time.Unix(expiry, 0)
+	}
+	i.Ctime = time.Duration(binary.BigEndian.Uint32(b[4:])) * time.Millisecond
+}
+
+// MarshalItem returns item in the layout of adapters without tags, such as
+// RedisAdapter and FilesystemAdapter: the value, or when it has metadata an
+// array with the value under a key packing the metadata. Tags are dropped.
+func (m Marshaller) MarshalItem(item Item) ([]byte, error) {
+	if !item.hasMetadata() {
+		return m.Marshal(item.Value)
+	}
+	key := append([]byte{metadataKeyStart}, item.packMetadata()...)
+	key = append(key, metadataKeyEnd)
+	return m.Marshal(igbinary.Array{{Key: string(key), Value: item.Value}})
+}
+
+// UnmarshalItem decodes an item in the layout of adapters without tags.
+func (m Marshaller) UnmarshalItem(data []byte) (Item, error) {
+	var v interface{}
+	if err := m.Unmarshal(data, &v); err != nil {
+		return Item{}, err
+	}
+	item := Item{Value: v}
+	if arr, ok := v.(igbinary.Array); ok && len(arr) == 1 {
+		k, _ := arr[0].Key.(string)
+		if len(k) == metadataKeyLen && k[0] == metadataKeyStart && k[5] == 0 && k[9] == metadataKeyEnd {
+			item.Value = arr[0].Value
+			item.unpackMetadata([]byte(k[1:9]))
+		}
+	}
+	return item, nil
+}
+
+// MarshalTaggedItem returns item in the layout of tag aware adapters, such
+// as RedisTagAwareAdapter: an array of the value, its tags and the packed
+// metadata.
+func (m Marshaller) MarshalTaggedItem(item Item) ([]byte, error) {
+	tags := make(igbinary.Array, 0, len(item.Tags))
+	for _, tag := range item.Tags {
+		tags = append(tags, igbinary.Entry{Key: tag, Value: tag})
+	}
+	arr := igbinary.Array{{Key: `value`, Value: item.Value}, {Key: `tags`, Value: tags}}
+	if item.hasMetadata() {
+		arr = append(arr, igbinary.Entry{Key: `meta`, Value: string(item.packMetadata())})
+	}
+	return m.Marshal(arr)
+}
+
+// UnmarshalTaggedItem decodes an item in the layout of tag aware adapters.
+func (m Marshaller) UnmarshalTaggedItem(data []byte) (Item, error) {
+	var v interface{}
+	if err := m.Unmarshal(data, &v); err != nil {
+		return Item{}, err
+	}
+	arr, ok := v.(igbinary.Array)
+	if !ok {
+		return Item{}, fmt.Errorf(`symfony: tagged item holds %T, not an array`, v)
+	}
+
+	var item Item
+	var hasValue bool
+	for _, entry := range arr {
+		switch entry.Key {
+		case `value`:
+			item.Value, hasValue = entry.Value, true
+		case `tags`:
+			tags, ok := entry.Value.(igbinary.Array)
+			if !ok {
+				return Item{}, fmt.Errorf(`symfony: tags of type %T`, entry.Value)
+			}
+			for _, tag := range tags {
+				s, ok := tag.Value.(string)
+				if !ok {
+					return Item{}, fmt.Errorf(`symfony: tag of type %T`, tag.Value)
+				}
+				item.Tags = append(item.Tags, s)
+			}
+		case `meta`:
+			meta, ok := entry.Value.(string)
+			if !ok || len(meta) != 8 {
+				return Item{}, fmt.Errorf(`symfony: invalid metadata %q`, entry.Value)
+			}
+			item.unpackMetadata([]byte(meta))
+		}
+	}
+	if !hasValue {
+		return Item{}, fmt.Errorf(`symfony: tagged item without a value`)
+	}
+	return item, nil
+}
+
+// File is a cache file of FilesystemAdapter: the expiry timestamp and the
+// URL encoded item ID on a line each, followed by the marshalled item.
+type File struct {
+	// ExpiresAt is zero for files that do not expire.
+	ExpiresAt time.Time
+	ID        string
+	Data      []byte
+}
+
+// ParseFile parses the contents of a cache file.
+func ParseFile(b []byte) (File, error) {
+	lines := bytes.SplitN(b, []byte("\n"), 3)
+	if len(lines) != 3 {
+		return File{}, fmt.Errorf(`symfony: cache file without expiry and ID lines`)
+	}
+	expiresAt, err := strconv.ParseInt(string(lines[0]), 10, 64)
+	if err != nil {
+		return File{}, fmt.Errorf(`symfony: invalid expiry %q`, lines[0])
+	}
+	id, err := url.PathUnescape(string(bytes.TrimRight(lines[1], " \t\r\x00\x0b")))
+	if err != nil {
+		return File{}, fmt.Errorf(`symfony: invalid ID %q`, lines[1])
+	}
+	f := File{ID: id, Data: lines[2]}
+	if expiresAt != 0 {
+		f.ExpiresAt = time.Unix(expiresAt, 0)
+	}
+	return f, nil
+}
+
+// Expired reports whether the file expired at now, FilesystemAdapter
+// deletes such files instead of reading them.
+func (f File) Expired(now time.Time) bool {
+	return !f.ExpiresAt.IsZero() && !now.Before(f.ExpiresAt)
+}
+
+// Bytes returns the contents of the cache file.
+func (f File) Bytes() []byte {
+	var expiresAt int64
+	if !f.ExpiresAt.IsZero() {
+		expiresAt = f.ExpiresAt.Unix()
+	}
+	b := strconv.AppendInt(nil, expiresAt, 10)
+	b = append(b, '\n')
+	b = append(b, rawURLEncode(f.ID)...)
+	b = append(b, '\n')
+	return append(b, f.Data...)
+}
+
+// rawURLEncode escapes s like PHP's rawurlencode, all bytes but letters,
+// digits and "-_.~".
+func rawURLEncode(s string) string {
+	const hex = `0123456789ABCDEF`
+	var b []byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' ||
+			c == '-' || c == '_' || c == '.' || c == '~' {
+			b = append(b, c)
+		} else {
+			b = append(b, '%', hex[c>>4], hex[c&15])
+		}
+	}
+	return string(b)
+}
@@ -0,0 +1,146 @@
+package symfony
+
+import (
+	"github.com/stretchr/testify/suite"
+	"github.com/zarken-go/igbinary"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type testUser struct {
+	Name string `igbinary:"name"`
+}
+
+type SymfonySuite struct {
+	suite.Suite
+}
+
+// readFixture parses a FilesystemAdapter file of testdata.
+func (Suite *SymfonySuite) readFixture(name string) File {
+	B, err := ioutil.ReadFile(filepath.Join(`testdata`, name))
+	Suite.Require().Nil(err)
+	F, err := ParseFile(B)
+	Suite.Require().Nil(err)
+	return F
+}
+
+func (Suite *SymfonySuite) TestFilesystemFixtures() {
+	// $cache->get('user.7', function (ItemInterface $item) {
+	//     $item->expiresAt(new DateTime('@1700000000'));
+	//     return ['name' => 'Ann'];
+	// });
+	// computed in 25ms, with serialize.
+	F := Suite.readFixture(`serialize.cache`)
+	Suite.Equal(`user.7`, F.ID)
+	Suite.Equal(time.Unix(1700000000, 0), F.ExpiresAt)
+	Suite.True(F.Expired(time.Unix(1700000000, 0)))
+	Suite.False(F.Expired(time.Unix(1699999999, 0)))
+
+	Cached, err := Marshaller{}.UnmarshalItem(F.Data)
+	Suite.Require().Nil(err)
+	Suite.Equal(time.Unix(1700000000, 0), Cached.Expiry)
+	Suite.Equal(25*time.Millisecond, Cached.Ctime)
+	var User testUser
+	Suite.Nil(Cached.Decode(&User))
+	Suite.Equal(testUser{Name: `Ann`}, User)
+
+	// The same under the key 'user 7' without expiry, with igbinary.
+	F = Suite.readFixture(`igbinary.cache`)
+	Suite.Equal(`user 7`, F.ID)
+	Suite.True(F.ExpiresAt.IsZero())
+	Suite.False(F.Expired(time.Now()))
+
+	Cached, err = Marshaller{}.UnmarshalItem(F.Data)
+	Suite.Require().Nil(err)
+	Suite.Equal(igbinary.Array{{Key: `name`, Value: `Ann`}}, Cached.Value)
+	Suite.Equal(time.Unix(1700000000, 0), Cached.Expiry)
+
+	// Writing the item back reproduces the file.
+	B, err := Marshaller{Igbinary: true}.MarshalItem(Cached)
+	Suite.Require().Nil(err)
+	Suite.Equal(F.Data, B)
+	Expected, err := ioutil.ReadFile(filepath.Join(`testdata`, `igbinary.cache`))
+	Suite.Require().Nil(err)
+	Suite.Equal(Expected, File{ID: `user 7`, Data: B}.Bytes())
+}
+
+func (Suite *SymfonySuite) TestTaggedFixture() {
+	B, err := ioutil.ReadFile(filepath.Join(`testdata`, `tagged.igbinary`))
+	Suite.Require().Nil(err)
+
+	Cached, err := Marshaller{}.UnmarshalTaggedItem(B)
+	Suite.Require().Nil(err)
+	Suite.Equal(Item{
+		Value:  `bar`,
+		Expiry: time.Unix(1700000000, 0),
+		Ctime:  25 * time.Millisecond,
+		Tags:   []string{`a`, `b`},
+	}, Cached)
+
+	Encoded, err := Marshaller{Igbinary: true}.MarshalTaggedItem(Cached)
+	Suite.Require().Nil(err)
+	Suite.Equal(B, Encoded)
+}
+
+func (Suite *SymfonySuite) TestRoundTrip() {
+	for _, M := range []Marshaller{{}, {Igbinary: true}} {
+		for _, Cached := range []Item{
+			{Value: `plain`},
+			{Value: int64(7), Ctime: 3 * time.Millisecond},
+			{Value: igbinary.Array{{Key: int64(0), Value: true}}, Expiry: time.Unix(1800000000, 0)},
+		} {
+			B, err := M.MarshalItem(Cached)
+			Suite.Require().Nil(err)
+			Decoded, err := M.UnmarshalItem(B)
+			Suite.Nil(err)
+			Suite.Equal(Cached, Decoded)
+
+			Cached.Tags = []string{`tag`}
+			B, err = M.MarshalTaggedItem(Cached)
+			Suite.Require().Nil(err)
+			Decoded, err = M.UnmarshalTaggedItem(B)
+			Suite.Nil(err)
+			Suite.Equal(Cached, Decoded)
+		}
+	}
+}
+
+func (Suite *SymfonySuite) TestMarshaller() {
+	var Value interface{}
+	Suite.Nil(Marshaller{}.Unmarshal([]byte(`b:0;`), &Value))
+	Suite.Equal(false, Value)
+	var Null, IgbinaryNull interface{}
+	Suite.Nil(Marshaller{}.Unmarshal([]byte(`N;`), &Null))
+	Suite.Nil(Null)
+	Suite.Nil(Marshaller{}.Unmarshal([]byte{0, 0, 0, 2, 0}, &IgbinaryNull))
+	Suite.Nil(IgbinaryNull)
+	Suite.NotNil(Marshaller{}.Unmarshal(nil, &Null))
+
+	B, err := Marshaller{}.Marshal(testUser{Name: `Ann`})
+	Suite.Nil(err)
+	Suite.Equal(`a:1:{s:4:"name";s:3:"Ann";}`, string(B))
+
+	_, err = Marshaller{}.UnmarshalTaggedItem([]byte(`s:3:"bar";`))
+	Suite.EqualError(err, `symfony: tagged item holds string, not an array`)
+	_, err = Marshaller{}.UnmarshalTaggedItem([]byte(`a:1:{s:4:"tags";a:0:{}}`))
+	Suite.EqualError(err, `symfony: tagged item without a value`)
+}
+
+func (Suite *SymfonySuite) TestFile() {
+	F := File{ExpiresAt: time.Unix(1700000000, 0), ID: `a/b c+d~`, Data: []byte("x\ny")}
+	Suite.Equal("1700000000\na%2Fb%20c%2Bd~\nx\ny", string(F.Bytes()))
+	Parsed, err := ParseFile(F.Bytes())
+	Suite.Nil(err)
+	Suite.Equal(F, Parsed)
+
+	_, err = ParseFile([]byte("0\n"))
+	Suite.EqualError(err, `symfony: cache file without expiry and ID lines`)
+	_, err = ParseFile([]byte("soon\nid\n"))
+	Suite.EqualError(err, `symfony: invalid expiry "soon"`)
+}
+
+func TestSymfonySuite(t *testing.T) {
+	suite.Run(t, new(SymfonySuite))
+}
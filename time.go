@@ -0,0 +1,471 @@
+package igbinary
+
+import (
+	"github.com/vmihailenco/tagparser"
+	"github.com/zarken-go/igbinary/igcode"
+	"github.com/zarken-go/igbinary/internal/structtag"
+	"math"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Classes of the PHP date objects time.Time and time.Duration map to.
+// Subclasses such as Carbon\Carbon decode as well, they serialize the same
+// properties.
+const (
+	DateTimeClass          = `DateTime`
+	DateTimeImmutableClass = `DateTimeImmutable`
+	DateIntervalClass      = `DateInterval`
+)
+
+// phpDateLayout is the layout of the date property of PHP's DateTime.
+const phpDateLayout = `2006-01-02 15:04:05.000000`
+
+// PHP timezone types of the timezone_type property.
+const (
+	timezoneOffset       = 1
+	timezoneAbbreviation = 2
+	timezoneIdentifier   = 3
+)
+
+// timezoneAbbreviations maps the abbreviations PHP writes for timezone type
+// 2 to their offset in seconds. Ambiguous abbreviations resolve as PHP's
+// timelib does, e.g. IST is India Standard Time.
+var timezoneAbbreviations = map[string]int{
+	`UTC`: 0, `GMT`: 0, `Z`: 0, `WET`: 0,
+	`BST`: 3600, `CET`: 3600, `WEST`: 3600,
+	`CEST`: 7200, `EET`: 7200, `SAST`: 7200,
+	`EEST`: 10800, `MSK`: 10800,
+	`IST`: 19800,
+	`JST`: 32400, `KST`: 32400,
+	`AEST`: 36000, `AEDT`: 39600, `NZST`: 43200, `NZDT`: 46800,
+	`AST`: -14400, `ADT`: -10800,
+	`EST`: -18000, `EDT`: -14400,
+	`CST`: -21600, `CDT`: -18000,
+	`MST`: -25200, `MDT`: -21600,
+	`PST`: -28800, `PDT`: -25200,
+	`AKST`: -32400, `AKDT`: -28800, `HST`: -36000,
+}
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+)
+
+// isTime reports whether typ is time.Time or a type defined from it, such
+// as type Stamp time.Time, which encode alike.
+func isTime(typ reflect.Type) bool {
+	return typ == timeType || typ.Kind() == reflect.Struct && typ.ConvertibleTo(timeType)
+}
+
+// EncodeTime encodes t as a PHP DateTime.
+func (e *Encoder) EncodeTime(t time.Time) error {
+	return e.EncodeDateTime(DateTimeClass, t)
+}
+
+// EncodeDateTime encodes t as an object of class, one of DateTimeClass,
+// DateTimeImmutableClass or a subclass. Locations named by an IANA
+// identifier and UTC keep their name, any other location is written as its
+// offset at t.
+func (e *Encoder) EncodeDateTime(class string, t time.Time) error {
+	timezoneType, timezone := timezoneOffset, t.Format(`-07:00`)
+	if name := t.Location().String(); name == `UTC` || strings.Contains(name, `/`) {
+		timezoneType, timezone = timezoneIdentifier, name
+	}
+
+	if err := e.EncodeObjectLen(class, 3); err != nil {
+		return err
+	}
+	if err := e.EncodeStringKey(`date`); err != nil {
+		return err
+	}
+	if err := e.EncodeString(t.Format(phpDateLayout)); err != nil {
+		return err
+	}
+	if err := e.EncodeStringKey(`timezone_type`); err != nil {
+		return err
+	}
+	if err := e.EncodeInt64(int64(timezoneType)); err != nil {
+		return err
+	}
+	if err := e.EncodeStringKey(`timezone`); err != nil {
+		return err
+	}
+	return e.EncodeString(timezone)
+}
+
+// DecodeTime decodes a DateTime object of any of the three timezone types,
+// a Unix timestamp or a string in RFC 3339 or PHP's "Y-m-d H:i:s" format.
+// Timestamps decode in UTC, null decodes as the zero time.
+//
+//nolint:gocyclo
+func (d *Decoder) DecodeTime() (time.Time, error) {
+	c, err := d.PeekCode()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	switch {
+	case c == igcode.Nil:
+		return time.Time{}, d.DecodeNil()
+	case igcode.IsInteger(c):
+		n, err := d.DecodeInt64()
+		return time.Unix(n, 0).UTC(), err
+	case c == igcode.Double:
+		f, err := d.DecodeFloat64()
+		sec := int64(f)
+		return time.Unix(sec, int64((f-float64(sec))*1e9)).UTC(), err
+	case igcode.IsString(c):
+		s, err := d.DecodeString()
+		if err != nil {
+			return time.Time{}, err
+		}
+		return parseTime(s, ``)
+	case igcode.IsRef(c):
+		v, err := d.decodeRef()
+		if err != nil {
+			return time.Time{}, err
+		}
+		if t, ok := v.(time.Time); ok {
+			return t, nil
+		}
+		return time.Time{}, decodeErrorF(`reference to %T decoding time`, v)
+	case !igcode.IsObject(c):
+		return time.Time{}, decodeErrorF(`invalid code=%x decoding time`, c)
+	}
+
+	class, n, err := d.DecodeObjectLen()
+	if err != nil {
+		return time.Time{}, err
+	}
+	slot := len(d.refs) - 1
+	if err := d.enter(); err != nil {
+		return time.Time{}, err
+	}
+	defer d.leave()
+
+	var date, timezone string
+	var timezoneType int
+	for i := 0; i < n; i++ {
+		key, err := d.DecodeString()
+		if err != nil {
+			return time.Time{}, err
+		}
+		switch key {
+		case `date`:
+			date, err = d.DecodeString()
+		case `timezone_type`:
+			timezoneType, err = d.DecodeInt()
+		case `timezone`:
+			timezone, err = d.DecodeString()
+		default:
+			err = d.Skip()
+		}
+		if err != nil {
+			return time.Time{}, err
+		}
+	}
+	if date == `` || timezoneType == 0 {
+		return time.Time{}, decodeErrorF(`%s is not a DateTime`, class)
+	}
+
+	loc, err := phpLocation(timezoneType, timezone)
+	if err != nil {
+		return time.Time{}, err
+	}
+	t, err := time.ParseInLocation(phpDateLayout, date, loc)
+	if err != nil {
+		return time.Time{}, decodeErrorF(`invalid date %q`, date)
+	}
+	d.refs[slot] = t
+	return t, nil
+}
+
+// DecodeTimeLayout is DecodeTime parsing strings in layout.
+func (d *Decoder) DecodeTimeLayout(layout string) (time.Time, error) {
+	c, err := d.PeekCode()
+	if err != nil || !igcode.IsString(c) {
+		return d.DecodeTime()
+	}
+	s, err := d.DecodeString()
+	if err != nil {
+		return time.Time{}, err
+	}
+	return parseTime(s, layout)
+}
+
+// phpLocation returns the location of a DateTime timezone.
+func phpLocation(timezoneType int, timezone string) (*time.Location, error) {
+	switch timezoneType {
+	case timezoneOffset:
+		t, err := time.Parse(`-07:00`, timezone)
+		if err != nil {
+			return nil, decodeErrorF(`invalid timezone offset %q`, timezone)
+		}
+		_, offset := t.Zone()
+		return time.FixedZone(timezone, offset), nil
+	case timezoneAbbreviation:
+		offset, ok := timezoneAbbreviations[strings.ToUpper(timezone)]
+		if !ok {
+			return nil, decodeErrorF(`unknown timezone abbreviation %q`, timezone)
+		}
+		return time.FixedZone(timezone, offset), nil
+	case timezoneIdentifier:
+		loc, err := time.LoadLocation(timezone)
+		if err != nil {
+			return nil, decodeErrorF(`unknown timezone %q`, timezone)
+		}
+		return loc, nil
+	}
+	return nil, decodeErrorF(`invalid timezone_type %d`, timezoneType)
+}
+
+// parseTime parses s using layout, or RFC 3339 and PHP's default format
+// when layout is empty.
+func parseTime(s, layout string) (time.Time, error) {
+	if s == `` {
+		return time.Time{}, nil
+	}
+	layouts := []string{time.RFC3339Nano, `2006-01-02 15:04:05`, phpDateLayout}
+	if layout != `` {
+		layouts = []string{layout}
+	}
+	for _, layout := range layouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, decodeErrorF(`invalid time %q`, s)
+}
+
+// EncodeDuration encodes v as a PHP DateInterval of days, hours, minutes,
+// seconds and microseconds, the way DateTime::diff creates them.
+func (e *Encoder) EncodeDuration(v time.Duration) error {
+	invert := int64(0)
+	if v < 0 {
+		invert, v = 1, -v
+	}
+	days := int64(v / (24 * time.Hour))
+	props := []struct {
+		key   string
+		value int64
+	}{
+		{`y`, 0},
+		{`m`, 0},
+		{`d`, days},
+		{`h`, int64(v/time.Hour) % 24},
+		{`i`, int64(v/time.Minute) % 60},
+		{`s`, int64(v/time.Second) % 60},
+	}
+
+	if err := e.EncodeObjectLen(DateIntervalClass, len(props)+3); err != nil {
+		return err
+	}
+	for _, p := range props {
+		if err := e.EncodeStringKey(p.key); err != nil {
+			return err
+		}
+		if err := e.EncodeInt64(p.value); err != nil {
+			return err
+		}
+	}
+	if err := e.EncodeStringKey(`f`); err != nil {
+		return err
+	}
+	if err := e.EncodeFloat64(float64(v%time.Second/time.Microsecond) / 1e6); err != nil {
+		return err
+	}
+	if err := e.EncodeStringKey(`invert`); err != nil {
+		return err
+	}
+	if err := e.EncodeInt64(invert); err != nil {
+		return err
+	}
+	if err := e.EncodeStringKey(`days`); err != nil {
+		return err
+	}
+	return e.EncodeInt64(days)
+}
+
+// DecodeDuration decodes a PHP DateInterval. Intervals counting years or
+// months have no fixed length and only decode when their days property is
+// set. Integers decode as nanoseconds.
+func (d *Decoder) DecodeDuration() (time.Duration, error) {
+	c, err := d.PeekCode()
+	if err != nil {
+		return 0, err
+	}
+	switch {
+	case c == igcode.Nil:
+		return 0, d.DecodeNil()
+	case igcode.IsInteger(c):
+		n, err := d.DecodeInt64()
+		return time.Duration(n), err
+	case !igcode.IsObject(c):
+		return 0, decodeErrorF(`invalid code=%x decoding duration`, c)
+	}
+
+	class, n, err := d.DecodeObjectLen()
+	if err != nil {
+		return 0, err
+	}
+	if err := d.enter(); err != nil {
+		return 0, err
+	}
+	defer d.leave()
+
+	var fields [6]int64 // y, m, d, h, i, s
+	var f float64
+	var invert bool
+	days := int64(-1)
+	for i := 0; i < n; i++ {
+		key, err := d.DecodeString()
+		if err != nil {
+			return 0, err
+		}
+		if i := strings.Index(`ymdhis`, key); len(key) == 1 && i >= 0 {
+			fields[i], err = d.DecodeInt64()
+		} else {
+			switch key {
+			case `f`:
+				f, err = d.DecodeFloat64()
+			case `invert`:
+				var v int64
+				v, err = d.DecodeInt64()
+				invert = v != 0
+			case `days`:
+				days, err = d.intervalDays()
+			default:
+				err = d.Skip()
+			}
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if days < 0 {
+		if fields[0] != 0 || fields[1] != 0 {
+			return 0, decodeErrorF(`%s of years or months has no fixed duration`, class)
+		}
+		days = fields[2]
+	}
+	v := time.Duration(days)*24*time.Hour +
+		time.Duration(fields[3])*time.Hour +
+		time.Duration(fields[4])*time.Minute +
+		time.Duration(fields[5])*time.Second +
+		time.Duration(math.Round(f*1e6))*time.Microsecond
+	if invert {
+		v = -v
+	}
+	return v, nil
+}
+
+// intervalDays decodes the days property of a DateInterval, false when the
+// interval was not created by DateTime::diff, as -1.
+func (d *Decoder) intervalDays() (int64, error) {
+	c, err := d.PeekCode()
+	if err != nil {
+		return 0, err
+	}
+	if c == igcode.BoolFalse {
+		return -1, d.skipExpected(igcode.BoolFalse)
+	}
+	return d.DecodeInt64()
+}
+
+func encodeTimeValue(e *Encoder, v reflect.Value) error {
+	return e.EncodeTime(v.Convert(timeType).Interface().(time.Time))
+}
+
+func decodeTimeValue(d *Decoder, v reflect.Value) error {
+	t, err := d.DecodeTime()
+	if err != nil {
+		return err
+	}
+	v.Set(reflect.ValueOf(t).Convert(v.Type()))
+	return nil
+}
+
+func encodeDurationValue(e *Encoder, v reflect.Value) error {
+	return e.EncodeDuration(time.Duration(v.Int()))
+}
+
+func decodeDurationValue(d *Decoder, v reflect.Value) error {
+	n, err := d.DecodeDuration()
+	if err != nil {
+		return err
+	}
+	v.SetInt(int64(n))
+	return nil
+}
+
+// timeFieldCodec returns the encoder and decoder of a time.Time or
+// *time.Time field selecting another mode than DateTime through its tag:
+//
+//	immutable    a DateTimeImmutable object
+//	unix         an integer Unix timestamp
+//	format=...   a string in the given Go layout, such as format=2006-01-02
+func timeFieldCodec(typ reflect.Type, tag *tagparser.Tag) (encoderFunc, decoderFunc, bool) {
+	elem := typ
+	if elem.Kind() == reflect.Ptr {
+		elem = elem.Elem()
+	}
+	if !isTime(elem) {
+		return nil, nil, false
+	}
+
+	var encode func(*Encoder, time.Time) error
+	var decode func(*Decoder) (time.Time, error)
+	if tag.HasOption(`immutable`) {
+		encode = func(e *Encoder, t time.Time) error {
+			return e.EncodeDateTime(DateTimeImmutableClass, t)
+		}
+	} else if tag.HasOption(`unix`) {
+		encode = func(e *Encoder, t time.Time) error {
+			return e.EncodeInt64(t.Unix())
+		}
+	} else if layout, ok := structtag.Option(tag, `format`); ok {
+		encode = func(e *Encoder, t time.Time) error {
+			return e.EncodeString(t.Format(layout))
+		}
+		decode = func(d *Decoder) (time.Time, error) {
+			return d.DecodeTimeLayout(layout)
+		}
+	} else {
+		return nil, nil, false
+	}
+	if decode == nil {
+		decode = (*Decoder).DecodeTime
+	}
+
+	encoder := func(e *Encoder, v reflect.Value) error {
+		if v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return e.EncodeNil()
+			}
+			v = v.Elem()
+		}
+		return encode(e, v.Convert(timeType).Interface().(time.Time))
+	}
+	decoder := func(d *Decoder, v reflect.Value) error {
+		if v.Kind() == reflect.Ptr {
+			if d.hasNilCode() {
+				v.Set(reflect.Zero(v.Type()))
+				return d.DecodeNil()
+			}
+			if v.IsNil() {
+				v.Set(reflect.New(elem))
+			}
+			v = v.Elem()
+		}
+		t, err := decode(d)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(t).Convert(v.Type()))
+		return nil
+	}
+	return encoder, decoder, true
+}
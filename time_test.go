@@ -0,0 +1,204 @@
+package igbinary
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/suite"
+	"testing"
+	"time"
+)
+
+type TimeSuite struct {
+	suite.Suite
+}
+
+type testEvent struct {
+	Created  time.Time     `igbinary:"created"`
+	Updated  time.Time     `igbinary:"updated,immutable"`
+	Seen     *time.Time    `igbinary:"seen,unix"`
+	Day      time.Time     `igbinary:"day,format=2006-01-02"`
+	At       *time.Time    `igbinary:"at,format=15:04"`
+	Duration time.Duration `igbinary:"duration"`
+}
+
+func dateTime(class, date string, timezoneType int64, timezone string) *Object {
+	return &Object{Class: class, Props: Array{
+		{Key: `date`, Value: date},
+		{Key: `timezone_type`, Value: timezoneType},
+		{Key: `timezone`, Value: timezone},
+	}}
+}
+
+func (Suite *TimeSuite) TestEncodeTime() {
+	Amsterdam, err := time.LoadLocation(`Europe/Amsterdam`)
+	Suite.Require().Nil(err)
+
+	for _, Case := range []struct {
+		Time     time.Time
+		Expected *Object
+	}{
+		{time.Date(2021, 3, 4, 5, 6, 7, 8900, time.UTC),
+			dateTime(DateTimeClass, `2021-03-04 05:06:07.000008`, 3, `UTC`)},
+		{time.Date(2021, 7, 1, 12, 0, 0, 0, Amsterdam),
+			dateTime(DateTimeClass, `2021-07-01 12:00:00.000000`, 3, `Europe/Amsterdam`)},
+		{time.Date(2021, 7, 1, 12, 0, 0, 0, time.FixedZone(``, -5*3600-1800)),
+			dateTime(DateTimeClass, `2021-07-01 12:00:00.000000`, 1, `-05:30`)},
+	} {
+		B, err := Marshal(Case.Time)
+		Suite.Nil(err)
+		Expected, err := Marshal(Case.Expected)
+		Suite.Nil(err)
+		Suite.Equal(Expected, B)
+	}
+}
+
+func (Suite *TimeSuite) TestDecodeTime() {
+	for _, Case := range []struct {
+		Value    interface{}
+		Expected string
+	}{
+		{dateTime(DateTimeClass, `2021-07-01 12:00:00.000000`, 1, `+02:00`), `2021-07-01T12:00:00+02:00`},
+		{dateTime(DateTimeImmutableClass, `2021-01-01 12:00:00.500000`, 2, `EST`), `2021-01-01T12:00:00.5-05:00`},
+		{dateTime(DateTimeClass, `2021-07-01 12:00:00.000000`, 2, `IST`), `2021-07-01T12:00:00+05:30`},
+		{dateTime(`Carbon\Carbon`, `2021-07-01 12:00:00.000000`, 3, `Europe/Amsterdam`), `2021-07-01T12:00:00+02:00`},
+		{int64(1625140800), `2021-07-01T12:00:00Z`},
+		{`2021-07-01T12:00:00+02:00`, `2021-07-01T12:00:00+02:00`},
+		{`2021-07-01 12:00:00`, `2021-07-01T12:00:00Z`},
+		{nil, `0001-01-01T00:00:00Z`},
+	} {
+		B, err := Marshal(Case.Value)
+		Suite.Require().Nil(err)
+		var Time time.Time
+		Suite.Nil(Unmarshal(B, &Time))
+		Suite.Equal(Case.Expected, Time.Format(time.RFC3339Nano))
+	}
+
+	B, err := Marshal(dateTime(DateTimeClass, `2021-07-01 12:00:00.000000`, 3, `Mars/Olympus`))
+	Suite.Require().Nil(err)
+	var Time time.Time
+	Suite.EqualError(Unmarshal(B, &Time), `igbinary: Decode(unknown timezone "Mars/Olympus")`)
+
+	B, err = Marshal(&Object{Class: `Foo`})
+	Suite.Require().Nil(err)
+	Suite.EqualError(Unmarshal(B, &Time), `igbinary: Decode(Foo is not a DateTime)`)
+}
+
+func (Suite *TimeSuite) TestObjectRef() {
+	Created := dateTime(DateTimeClass, `2021-07-01 12:00:00.000000`, 3, `UTC`)
+	B, err := Marshal(Array{{Key: `created`, Value: Created}, {Key: `updated`, Value: Created}})
+	Suite.Require().Nil(err)
+
+	var Event testEvent
+	Suite.Require().Nil(Unmarshal(B, &Event))
+	Suite.Equal(`2021-07-01T12:00:00Z`, Event.Updated.Format(time.RFC3339))
+	Suite.Equal(Event.Created, Event.Updated)
+}
+
+func (Suite *TimeSuite) TestFieldModes() {
+	Time := time.Date(2021, 7, 1, 12, 30, 0, 0, time.UTC)
+	B, err := Marshal(testEvent{Created: Time, Updated: Time, Seen: &Time, Day: Time, Duration: 90 * time.Minute})
+	Suite.Require().Nil(err)
+
+	var Value interface{}
+	Suite.Require().Nil(Unmarshal(B, &Value))
+	Props := Value.(Array)
+	Suite.Equal(DateTimeClass, Props[0].Value.(*Object).Class)
+	Suite.Equal(DateTimeImmutableClass, Props[1].Value.(*Object).Class)
+	Suite.Equal(int64(1625142600), Props[2].Value)
+	Suite.Equal(`2021-07-01`, Props[3].Value)
+	Suite.Nil(Props[4].Value)
+	Suite.Equal(DateIntervalClass, Props[5].Value.(*Object).Class)
+
+	var Event testEvent
+	Suite.Require().Nil(Unmarshal(B, &Event))
+	Suite.Equal(testEvent{
+		Created: Time, Updated: Time, Seen: &Time,
+		Day: time.Date(2021, 7, 1, 0, 0, 0, 0, time.UTC), Duration: 90 * time.Minute,
+	}, Event)
+
+	At := time.Date(0, 1, 1, 9, 45, 0, 0, time.UTC)
+	B, err = Marshal(testEvent{At: &At})
+	Suite.Require().Nil(err)
+	Event = testEvent{}
+	Suite.Require().Nil(Unmarshal(B, &Event))
+	Suite.Equal(At, *Event.At)
+}
+
+type testStamp time.Time
+
+type testLog struct {
+	Logged testStamp  `igbinary:"logged"`
+	Day    *testStamp `igbinary:"day,format=2006-01-02"`
+}
+
+func (Suite *TimeSuite) TestDefinedTimeType() {
+	Time := time.Date(2021, 7, 1, 0, 0, 0, 0, time.UTC)
+	Day := testStamp(Time)
+	B, err := Marshal(testLog{Logged: testStamp(Time), Day: &Day})
+	Suite.Require().Nil(err)
+
+	var Value interface{}
+	Suite.Require().Nil(Unmarshal(B, &Value))
+	Suite.Equal(dateTime(DateTimeClass, `2021-07-01 00:00:00.000000`, 3, `UTC`), Value.(Array)[0].Value)
+	Suite.Equal(`2021-07-01`, Value.(Array)[1].Value)
+
+	var Log testLog
+	Suite.Require().Nil(Unmarshal(B, &Log))
+	Suite.Equal(Time, time.Time(Log.Logged))
+	Suite.Equal(Time, time.Time(*Log.Day))
+}
+
+func (Suite *TimeSuite) TestDuration() {
+	for _, Duration := range []time.Duration{0, 1500 * time.Millisecond, -(49*time.Hour + 3*time.Minute + time.Microsecond)} {
+		B, err := Marshal(Duration)
+		Suite.Require().Nil(err)
+		var Decoded time.Duration
+		Suite.Nil(Unmarshal(B, &Decoded))
+		Suite.Equal(Duration, Decoded)
+	}
+
+	// new DateInterval('P1DT2H') on PHP 8.2
+	B, err := Marshal(&Object{Class: DateIntervalClass, Props: Array{
+		{Key: `y`, Value: int64(0)}, {Key: `m`, Value: int64(0)}, {Key: `d`, Value: int64(1)},
+		{Key: `h`, Value: int64(2)}, {Key: `i`, Value: int64(0)}, {Key: `s`, Value: int64(0)},
+		{Key: `f`, Value: float64(0)}, {Key: `invert`, Value: int64(0)},
+		{Key: `days`, Value: false}, {Key: `from_string`, Value: false},
+	}})
+	Suite.Require().Nil(err)
+	var Duration time.Duration
+	Suite.Nil(Unmarshal(B, &Duration))
+	Suite.Equal(26*time.Hour, Duration)
+
+	B, err = Marshal(&Object{Class: DateIntervalClass, Props: Array{
+		{Key: `m`, Value: int64(1)}, {Key: `days`, Value: false},
+	}})
+	Suite.Require().Nil(err)
+	Suite.EqualError(Unmarshal(B, &Duration),
+		`igbinary: Decode(DateInterval of years or months has no fixed duration)`)
+}
+
+func (Suite *TimeSuite) TestMaxDepth() {
+	B, err := Marshal(testLog{Logged: testStamp(time.Unix(0, 0))})
+	Suite.Require().Nil(err)
+	Decoder := NewDecoder(bytes.NewReader(B))
+	Suite.Require().Nil(Decoder.DecodeHeader())
+	_, err = Decoder.DecodeArrayLen()
+	Suite.Require().Nil(err)
+	_, err = Decoder.DecodeString()
+	Suite.Require().Nil(err)
+
+	// A DateTime nested deeper than the limit.
+	Decoder.depth = maxDepth
+	_, err = Decoder.DecodeTimeLayout(time.RFC3339)
+	Suite.EqualError(err, `igbinary: Decode(exceeded max depth of 10000)`)
+
+	B, err = Marshal(time.Hour)
+	Suite.Require().Nil(err)
+	Decoder = NewDecoder(bytes.NewReader(B[4:]))
+	Decoder.depth = maxDepth
+	_, err = Decoder.DecodeDuration()
+	Suite.EqualError(err, `igbinary: Decode(exceeded max depth of 10000)`)
+}
+
+func TestTimeSuite(t *testing.T) {
+	suite.Run(t, new(TimeSuite))
+}
@@ -0,0 +1,263 @@
+package igbinary
+
+import (
+	"fmt"
+	"github.com/zarken-go/igbinary/igcode"
+)
+
+// TokenKind identifies the kind of a Token.
+type TokenKind uint8
+
+const (
+	NullToken TokenKind = iota
+	BoolToken
+	IntToken
+	FloatToken
+	StringToken
+	// ArrayStartToken starts an array of Len entries. Each entry follows as
+	// a key token (IntToken or StringToken) and the tokens of its value.
+	ArrayStartToken
+	// ObjectStartToken starts an object of class Class holding Len
+	// properties, laid out like array entries.
+	ObjectStartToken
+	// SerializedObjectToken is an object of class Class implementing PHP's
+	// Serializable interface, Data holds its payload.
+	SerializedObjectToken
+	// RefToken is a PHP reference (&$value) to the entry Ref of the
+	// reference table.
+	RefToken
+	// ObjectRefToken is another occurrence of the object at entry Ref of the
+	// reference table.
+	ObjectRefToken
+	// NewRefToken marks the following value as a PHP reference.
+	NewRefToken
+)
+
+var tokenKindNames = [...]string{
+	NullToken:             `Null`,
+	BoolToken:             `Bool`,
+	IntToken:              `Int`,
+	FloatToken:            `Float`,
+	StringToken:           `String`,
+	ArrayStartToken:       `ArrayStart`,
+	ObjectStartToken:      `ObjectStart`,
+	SerializedObjectToken: `SerializedObject`,
+	RefToken:              `Ref`,
+	ObjectRefToken:        `ObjectRef`,
+	NewRefToken:           `NewRef`,
+}
+
+func (k TokenKind) String() string {
+	if int(k) < len(tokenKindNames) {
+		return tokenKindNames[k]
+	}
+	return fmt.Sprintf(`TokenKind(%d)`, k)
+}
+
+// Token is a single element of an igbinary stream. Only the fields
+// belonging to its Kind are set. Strings and class names are always
+// resolved, string IDs never surface as tokens.
+type Token struct {
+	Kind   TokenKind
+	Bool   bool
+	Int    int64
+	Float  float64
+	String string
+	Class  string
+	Len    int
+	Ref    int
+	Data   []byte
+}
+
+// TokenReader is implemented by Decoder.
+type TokenReader interface {
+	Token() (Token, error)
+}
+
+// TokenWriter is implemented by Encoder.
+type TokenWriter interface {
+	WriteToken(Token) error
+}
+
+// Token returns the next token of the stream. Array and object entries are
+// not delimited, callers count them using Len.
+//
+//nolint:gocyclo
+func (d *Decoder) Token() (Token, error) {
+	c, err := d.PeekCode()
+	if err != nil {
+		return Token{}, err
+	}
+
+	pending := d.flags&refPendingFlag != 0
+	d.flags &= ^refPendingFlag
+
+	var t Token
+	switch {
+	case c == igcode.Nil:
+		t.Kind = NullToken
+		err = d.DecodeNil()
+	case c == igcode.BoolFalse, c == igcode.BoolTrue:
+		t.Kind = BoolToken
+		t.Bool, err = d.DecodeBool()
+	case igcode.IsInteger(c):
+		t.Kind = IntToken
+		t.Int, err = d.DecodeInt64()
+	case c == igcode.Double:
+		t.Kind = FloatToken
+		t.Float, err = d.DecodeFloat64()
+	case igcode.IsString(c):
+		t.Kind = StringToken
+		t.String, err = d.DecodeString()
+	case igcode.IsArray(c):
+		t.Kind = ArrayStartToken
+		t.Len, err = d.DecodeArrayLen()
+		return t, err
+	case igcode.IsObject(c):
+		return d.objectToken()
+	case igcode.IsRef(c):
+		t.Kind = RefToken
+		if c >= igcode.ObjectRef8 {
+			t.Kind = ObjectRefToken
+		}
+		t.Ref, err = d.refID()
+		return t, err
+	case c == igcode.SimpleRef:
+		d.flags |= refPendingFlag
+		return Token{Kind: NewRefToken}, d.skipExpected(igcode.SimpleRef)
+	default:
+		return Token{}, decodeErrorF(`invalid code=%x decoding token`, c)
+	}
+	if err != nil {
+		return Token{}, err
+	}
+	if pending {
+		d.addRef(t.value())
+	}
+	return t, nil
+}
+
+func (d *Decoder) objectToken() (Token, error) {
+	class, err := d.className()
+	if err != nil {
+		return Token{}, err
+	}
+	d.addRef(nil)
+
+	c, err := d.PeekCode()
+	if err != nil {
+		return Token{}, err
+	}
+	if igcode.IsObjectSer(c) {
+		data, err := d.serializedData()
+		return Token{Kind: SerializedObjectToken, Class: class, Data: data}, err
+	}
+	n, err := d.arrayLen()
+	return Token{Kind: ObjectStartToken, Class: class, Len: n}, err
+}
+
+// value returns the scalar held by t as DecodeInterface would.
+func (t Token) value() interface{} {
+	switch t.Kind {
+	case BoolToken:
+		return t.Bool
+	case IntToken:
+		return t.Int
+	case FloatToken:
+		return t.Float
+	case StringToken:
+		return t.String
+	}
+	return nil
+}
+
+// WriteToken writes a single token, the mirror of Decoder.Token. Strings
+// and class names are deduplicated by the Encoder, the string IDs of the
+// original stream do not need to be preserved. The Encoder counts the
+// entries of the arrays and objects written so that string keys are
+// deduplicated as keys, e.g. under DedupKeys.
+func (e *Encoder) WriteToken(t Token) error {
+	key := len(e.tokens) > 0 && e.tokens[len(e.tokens)-1]%2 == 0
+	if err := e.writeToken(t, key); err != nil {
+		return err
+	}
+	switch t.Kind {
+	case NewRefToken:
+	case ArrayStartToken, ObjectStartToken:
+		if t.Len > 0 {
+			e.tokens = append(e.tokens, 2*t.Len)
+			break
+		}
+		e.endToken()
+	default:
+		e.endToken()
+	}
+	return nil
+}
+
+// endToken counts a key or value written by WriteToken, closing the arrays
+// and objects it completes.
+func (e *Encoder) endToken() {
+	for n := len(e.tokens); n > 0; n-- {
+		e.tokens[n-1]--
+		if e.tokens[n-1] > 0 {
+			return
+		}
+		e.tokens = e.tokens[:n-1]
+	}
+}
+
+//nolint:gocyclo
+func (e *Encoder) writeToken(t Token, key bool) error {
+	pending := e.refPending
+	e.refPending = false
+
+	switch t.Kind {
+	case NullToken:
+		if pending {
+			e.refID++
+		}
+		return e.EncodeNil()
+	case BoolToken:
+		if pending {
+			e.refID++
+		}
+		return e.EncodeBool(t.Bool)
+	case IntToken:
+		if pending {
+			e.refID++
+		}
+		return e.EncodeInt64(t.Int)
+	case FloatToken:
+		if pending {
+			e.refID++
+		}
+		return e.EncodeFloat64(t.Float)
+	case StringToken:
+		if pending {
+			e.refID++
+		}
+		if key {
+			return e.EncodeStringKey(t.String)
+		}
+		return e.EncodeString(t.String)
+	case ArrayStartToken:
+		return e.EncodeArrayLen(t.Len)
+	case ObjectStartToken:
+		return e.EncodeObjectLen(t.Class, t.Len)
+	case SerializedObjectToken:
+		e.refID++
+		if err := e.encodeClassName(t.Class); err != nil {
+			return err
+		}
+		return e.encodeSerializedData(t.Data)
+	case RefToken:
+		return e.encodeRef(igcode.ArrayRef8, t.Ref)
+	case ObjectRefToken:
+		return e.encodeRef(igcode.ObjectRef8, t.Ref)
+	case NewRefToken:
+		e.refPending = true
+		return e.writeBytes(igcode.SimpleRef)
+	}
+	return fmt.Errorf(`igbinary: Encode(unsupported token %s)`, t.Kind)
+}
@@ -0,0 +1,99 @@
+package igbinary
+
+import (
+	"bytes"
+	"encoding/hex"
+	"github.com/stretchr/testify/suite"
+	"io"
+	"testing"
+)
+
+type TokenSuite struct {
+	suite.Suite
+}
+
+func (Suite *TokenSuite) TestTokens() {
+	// [0 => true, 'k' => new Foo(k: 1.5), 'r' => $foo, 'a' => &$x, 'b' => $x, 'k' => 'k']
+	B, err := hex.DecodeString(`1406060005` + `11016b1703466f6f14010e000c3ff8000000000000` +
+		`1101722201` + `110161250606` + `1101620103` + `0e000e00`)
+	Suite.Require().Nil(err)
+
+	Decoder := NewDecoder(bytes.NewReader(B))
+	var Tokens []Token
+	for {
+		Token, err := Decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		Suite.Require().Nil(err)
+		Tokens = append(Tokens, Token)
+	}
+
+	Suite.Equal([]Token{
+		{Kind: ArrayStartToken, Len: 6},
+		{Kind: IntToken}, {Kind: BoolToken, Bool: true},
+		{Kind: StringToken, String: `k`}, {Kind: ObjectStartToken, Class: `Foo`, Len: 1},
+		{Kind: StringToken, String: `k`}, {Kind: FloatToken, Float: 1.5},
+		{Kind: StringToken, String: `r`}, {Kind: ObjectRefToken, Ref: 1},
+		{Kind: StringToken, String: `a`}, {Kind: NewRefToken}, {Kind: IntToken, Int: 6},
+		{Kind: StringToken, String: `b`}, {Kind: RefToken, Ref: 3},
+		{Kind: StringToken, String: `k`}, {Kind: StringToken, String: `k`},
+	}, Tokens)
+	Suite.Equal([]interface{}{nil, nil, int64(6)}, Decoder.refs)
+
+	var buf bytes.Buffer
+	Encoder := NewEncoder(&buf)
+	for _, Token := range Tokens {
+		Suite.Nil(Encoder.WriteToken(Token))
+	}
+	Suite.Equal(B, buf.Bytes())
+	Suite.Equal(uint(3), Encoder.refID)
+}
+
+func (Suite *TokenSuite) TestDedupKeys() {
+	// ['k' => 'k', 'a' => ['k' => 'k'], 'b' => new Foo(k: 'k')]
+	var buf bytes.Buffer
+	Encoder := NewEncoder(&buf)
+	Encoder.SetStringDedup(DedupKeys)
+	for _, Token := range []Token{
+		{Kind: ArrayStartToken, Len: 3},
+		{Kind: StringToken, String: `k`}, {Kind: StringToken, String: `k`},
+		{Kind: StringToken, String: `a`}, {Kind: ArrayStartToken, Len: 1},
+		{Kind: StringToken, String: `k`}, {Kind: StringToken, String: `k`},
+		{Kind: StringToken, String: `b`}, {Kind: ObjectStartToken, Class: `Foo`, Len: 1},
+		{Kind: StringToken, String: `k`}, {Kind: StringToken, String: `k`},
+	} {
+		Suite.Nil(Encoder.WriteToken(Token))
+	}
+	Suite.Empty(Encoder.tokens)
+
+	// Keys reuse the first 'k', values are written in full.
+	Suite.Equal(`1403`+`11016b`+`11016b`+`110161`+`1401`+`0e00`+`11016b`+
+		`110162`+`1703466f6f`+`1401`+`0e00`+`11016b`, hex.EncodeToString(buf.Bytes()))
+
+	var Expected bytes.Buffer
+	Encoder = NewEncoder(&Expected)
+	Encoder.SetStringDedup(DedupKeys)
+	Suite.Nil(Encoder.Encode(Array{
+		{Key: `k`, Value: `k`},
+		{Key: `a`, Value: Array{{Key: `k`, Value: `k`}}},
+		{Key: `b`, Value: &Object{Class: `Foo`, Props: Array{{Key: `k`, Value: `k`}}}},
+	}))
+	Suite.Equal(Expected.Bytes(), buf.Bytes())
+}
+
+func (Suite *TokenSuite) TestSerializedObject() {
+	Decoder := NewDecoder(bytes.NewReader([]byte("\x17\x03Foo\x1d\x01x")))
+	Serialized, err := Decoder.Token()
+	Suite.Nil(err)
+	Suite.Equal(Token{Kind: SerializedObjectToken, Class: `Foo`, Data: []byte(`x`)}, Serialized)
+
+	_, err = NewDecoder(bytes.NewReader([]byte{0x30})).Token()
+	Suite.EqualError(err, `igbinary: Decode(invalid code=30 decoding token)`)
+	Suite.EqualError(NewEncoder(&bytes.Buffer{}).WriteToken(Token{Kind: 99}),
+		`igbinary: Encode(unsupported token TokenKind(99))`)
+}
+
+func TestTokenSuite(t *testing.T) {
+	suite.Run(t, new(TokenSuite))
+}
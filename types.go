@@ -2,6 +2,7 @@ package igbinary
 
 import (
 	"github.com/vmihailenco/tagparser"
+	"github.com/zarken-go/igbinary/internal/structtag"
 	"reflect"
 	"sync"
 )
@@ -11,6 +12,23 @@ type (
 	decoderFunc func(*Decoder, reflect.Value) error
 )
 
+// Marshaler is implemented by types encoding themselves using the
+// primitives of the Encoder, such as the methods generated by igbinarygen.
+type Marshaler interface {
+	MarshalIgbinary(*Encoder) error
+}
+
+// Unmarshaler is implemented by types decoding themselves using the
+// primitives of the Decoder, such as the methods generated by igbinarygen.
+type Unmarshaler interface {
+	UnmarshalIgbinary(*Decoder) error
+}
+
+var (
+	marshalerType   = reflect.TypeOf((*Marshaler)(nil)).Elem()
+	unmarshalerType = reflect.TypeOf((*Unmarshaler)(nil)).Elem()
+)
+
 var (
 	typeEncMap sync.Map
 	typeDecMap sync.Map
@@ -44,8 +62,15 @@ func (m *structCache) Fields(typ reflect.Type, tag string) *fields {
 }
 
 type field struct {
-	name  string
+	name string
+	// key is name mangled for the visibility set by the protected and
+	// private tag options, class is the visibility as in MangleProperty.
+	key   string
+	class string
 	index []int
+	// tagged is set when the struct tag names the field, it wins over
+	// untagged fields of the same name and depth.
+	tagged bool
 	// omitEmpty bool
 	encoder encoderFunc
 	decoder decoderFunc
@@ -63,10 +88,36 @@ var (
 	defaultStructTag = `igbinary`
 )
 
+// getFields returns the fields of typ. The fields of embedded structs
+// without a tag name are promoted as Go promotes them: the shallowest field
+// of a name wins, a tie goes to the only tagged field at that depth and
+// fields tied otherwise are dropped.
 func getFields(typ reflect.Type, fallbackTag string) *fields {
 	fs := newFields(typ)
+	list, class := appendFields(nil, typ, fallbackTag, nil, map[reflect.Type]bool{})
+	fs.Class = class
+
+	for _, field := range list {
+		if dominant := dominantField(list, field.name); dominant == field {
+			field.key = MangleProperty(field.class, field.name)
+			fs.Add(field)
+		}
+	}
+
+	return fs
+}
+
+// appendFields appends the fields of typ, at index within the outermost
+// struct, along with those of its embedded structs. It returns the class
+// set by a blank field of typ. visiting holds the structs being collected,
+// a struct embedding itself is not expanded again.
+func appendFields(list []*field, typ reflect.Type, fallbackTag string, index []int, visiting map[reflect.Type]bool) ([]*field, string) {
+	visiting[typ] = true
+	defer delete(visiting, typ)
 
 	// var omitEmpty bool
+	var class string
+	var private []*field
 	for i := 0; i < typ.NumField(); i++ {
 		f := typ.Field(i)
 
@@ -76,33 +127,105 @@ func getFields(typ reflect.Type, fallbackTag string) *fields {
 		}
 
 		tag := tagparser.Parse(tagStr)
+		if f.Name == "_" {
+			if option, ok := structtag.Option(tag, "class"); ok {
+				class = option
+			}
+			continue
+		}
 		if tag.Name == "-" {
 			continue
 		}
+		fieldIndex := append(append([]int(nil), index...), i)
+
+		if f.Anonymous && tag.Name == "" {
+			embedded := f.Type
+			if embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct && !isTime(embedded) {
+				if !visiting[embedded] {
+					list, _ = appendFields(list, embedded, fallbackTag, fieldIndex, visiting)
+				}
+				continue
+			}
+		}
+		if f.PkgPath != "" {
+			continue
+		}
 
 		field := &field{
-			name:  tag.Name,
-			index: f.Index,
+			name:   tag.Name,
+			index:  fieldIndex,
+			tagged: tag.Name != "",
 			// omitEmpty: omitEmpty || tag.HasOption("omitempty"),
 		}
 
 		field.encoder = getEncoder(f.Type)
 		field.decoder = getDecoder(f.Type)
+		if encoder, decoder, ok := timeFieldCodec(f.Type, tag); ok {
+			field.encoder, field.decoder = encoder, decoder
+		}
 
 		if field.name == "" {
 			field.name = f.Name
 		}
+		if tag.HasOption("protected") {
+			field.class = "*"
+		} else if option, ok := structtag.Option(tag, "private"); ok {
+			field.class = option
+			if option == "" {
+				private = append(private, field)
+			}
+		}
 
-		fs.Add(field)
+		list = append(list, field)
 	}
 
-	return fs
+	// Private properties without a class belong to the class of the struct
+	// declaring them.
+	for _, field := range private {
+		field.class = class
+		if field.class == "" {
+			field.class = typ.Name()
+		}
+	}
+
+	return list, class
+}
+
+// dominantField returns the field of list named name that is promoted, or
+// nil when fields of that name hide each other.
+func dominantField(list []*field, name string) *field {
+	var dominant *field
+	var tie bool
+	for _, field := range list {
+		if field.name != name {
+			continue
+		}
+		switch {
+		case dominant == nil || len(field.index) < len(dominant.index):
+			dominant, tie = field, false
+		case len(field.index) > len(dominant.index):
+		case field.tagged && !dominant.tagged:
+			dominant, tie = field, false
+		case field.tagged == dominant.tagged:
+			tie = true
+		}
+	}
+	if tie {
+		return nil
+	}
+	return dominant
 }
 
 type fields struct {
 	Type reflect.Type
 	Map  map[string]*field
 	List []*field
+	// Class is set by the class option of a blank field, structs with a
+	// class encode as PHP objects.
+	Class string
 	// AsArray bool
 
 	// hasOmitEmpty bool
@@ -117,8 +240,23 @@ func (fs *fields) Add(field *field) {
 	//}
 }
 
+// Lookup returns the field for the property key. Mangled keys match the
+// field of their plain name, unless the field declares a visibility, in
+// which case the key must carry it.
+func (fs *fields) Lookup(key string) *field {
+	class, name := DemangleProperty(key)
+	f := fs.Map[name]
+	if f == nil || f.class != "" && f.class != class {
+		return nil
+	}
+	return f
+}
+
 func (f *field) DecodeValue(d *Decoder, strct reflect.Value) error {
-	v := fieldByIndexAlloc(strct, f.index)
+	v, ok := fieldByIndexAlloc(strct, f.index)
+	if !ok {
+		return decodeErrorF(`cannot allocate embedded field %s`, f.name)
+	}
 	if f.decoder == nil {
 		return decodeErrorF(`could not find decoder for field %s`, f.name)
 	}
@@ -145,27 +283,28 @@ func fieldByIndex(v reflect.Value, index []int) (_ reflect.Value, ok bool) {
 	return v, true
 }
 
-func fieldByIndexAlloc(v reflect.Value, index []int) reflect.Value {
+// fieldByIndexAlloc returns the field at index, allocating nil embedded
+// struct pointers on the way. It reports false when such a pointer cannot
+// be set, as for embedded pointers to unexported types.
+func fieldByIndexAlloc(v reflect.Value, index []int) (reflect.Value, bool) {
 	if len(index) == 1 {
-		return v.Field(index[0])
+		return v.Field(index[0]), true
 	}
 
-	/*
-		for i, idx := range index {
-			if i > 0 {
-				var ok bool
-				v, ok = indirectNil(v)
-				if !ok {
-					return v
+	for i, idx := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				if !v.CanSet() {
+					return v, false
 				}
+				v.Set(reflect.New(v.Type().Elem()))
 			}
-			v = v.Field(idx)
+			v = v.Elem()
 		}
+		v = v.Field(idx)
+	}
 
-		return v
-	*/
-
-	panic(`unsupported`)
+	return v, true
 }
 
 func (f *field) EncodeValue(e *Encoder, strct reflect.Value) error {
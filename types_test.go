@@ -107,6 +107,138 @@ func (Suite *DecodeSuite) TestTypes() {
 	}
 }
 
+type testPoint struct {
+	X, Y int64
+}
+
+// testPoint encodes as the list [X, Y].
+func (p testPoint) MarshalIgbinary(e *Encoder) error {
+	if err := e.EncodeArrayLen(2); err != nil {
+		return err
+	}
+	for i, v := range []int64{p.X, p.Y} {
+		if err := e.EncodeInt64(int64(i)); err != nil {
+			return err
+		}
+		if err := e.EncodeInt64(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *testPoint) UnmarshalIgbinary(d *Decoder) error {
+	if _, err := d.DecodeArrayLen(); err != nil {
+		return err
+	}
+	for _, v := range []*int64{&p.X, &p.Y} {
+		if _, err := d.DecodeInt64(); err != nil {
+			return err
+		}
+		var err error
+		if *v, err = d.DecodeInt64(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (Suite *DecodeSuite) TestUnmarshaler() {
+	var Point testPoint
+	Suite.Nil(Unmarshal([]byte{igcode.Array8, 2,
+		igcode.PosInt8, 0, igcode.PosInt8, 1, igcode.PosInt8, 1, igcode.NegInt8, 2}, &Point))
+	Suite.Equal(testPoint{X: 1, Y: -2}, Point)
+
+	Points := map[string]*testPoint{}
+	Suite.Nil(Unmarshal([]byte{igcode.Array8, 2,
+		igcode.String8, 1, 'a', igcode.Array8, 2,
+		igcode.PosInt8, 0, igcode.PosInt8, 1, igcode.PosInt8, 1, igcode.PosInt8, 2,
+		igcode.String8, 1, 'b', igcode.Nil}, &Points))
+	Suite.Equal(map[string]*testPoint{`a`: {X: 1, Y: 2}, `b`: nil}, Points)
+}
+
+func (Suite *EncodeSuite) TestMarshaler() {
+	Suite.assertMarshal(testPoint{X: 1, Y: -2}, `14020600060106010702`)
+	Suite.assertMarshal(&testPoint{X: 1, Y: -2}, `14020600060106010702`)
+	Suite.assertMarshal((*testPoint)(nil), `00`)
+}
+
+type Embedded struct {
+	V int
+}
+
+type testInner struct {
+	W int
+}
+
+type testOuter struct {
+	*Embedded
+	*testInner
+}
+
+type testAdmin struct {
+	_ struct{} `igbinary:",class=App\\Admin"`
+	testAccount
+	// ID hides the id of testAccount.
+	ID    string `igbinary:"id"`
+	Level int64  `igbinary:"level"`
+}
+
+type testContact struct {
+	Name  string `igbinary:"name"`
+	Phone string `igbinary:"phone"`
+}
+
+// testCard embeds two structs with a name at the same depth, neither is
+// promoted.
+type testCard struct {
+	testProfile
+	testContact
+}
+
+func (Suite *DecodeSuite) TestEmbeddedPointer() {
+	var Outer testOuter
+	Suite.Nil(Unmarshal([]byte("\x14\x01\x11\x01V\x06\x03"), &Outer))
+	Suite.Equal(&Embedded{V: 3}, Outer.Embedded)
+
+	// Embedded pointers of unexported types cannot be set.
+	Suite.EqualError(Unmarshal([]byte("\x14\x01\x11\x01W\x06\x01"), &Outer), `igbinary: Decode(cannot allocate embedded field W)`)
+	Suite.Nil(Outer.testInner)
+
+	// Fields behind nil pointers encode as null.
+	B, err := Marshal(Outer)
+	Suite.Require().Nil(err)
+	var Value interface{}
+	Suite.Nil(Unmarshal(B, &Value))
+	Suite.Equal(Array{{Key: `V`, Value: int64(3)}, {Key: `W`, Value: nil}}, Value)
+}
+
+func (Suite *DecodeSuite) TestEmbeddedFields() {
+	Admin := testAdmin{testAccount: testAccount{Balance: 2, Token: `t`, Owner: `o`}, ID: `a`, Level: 3}
+	B, err := Marshal(Admin)
+	Suite.Require().Nil(err)
+
+	var Value interface{}
+	Suite.Require().Nil(Unmarshal(B, &Value))
+	Suite.Equal(&Object{Class: `App\Admin`, Props: Array{
+		{Key: "\x00*\x00balance", Value: int64(2)},
+		{Key: "\x00App\\Account\x00token", Value: `t`},
+		{Key: "\x00App\\Model\x00owner", Value: `o`},
+		{Key: `id`, Value: `a`},
+		{Key: `level`, Value: int64(3)},
+	}}, Value)
+
+	var Decoded testAdmin
+	Suite.Nil(Unmarshal(B, &Decoded))
+	Suite.Equal(Admin, Decoded)
+
+	B, err = Marshal(testCard{testProfile{Name: `a`, Email: `e`}, testContact{Name: `b`, Phone: `p`}})
+	Suite.Require().Nil(err)
+	Value = nil
+	Suite.Require().Nil(Unmarshal(B, &Value))
+	Suite.Equal(Array{{Key: `email`, Value: `e`}, {Key: `phone`, Value: `p`}}, Value)
+}
+
 func indirect(viface interface{}) interface{} {
 	v := reflect.ValueOf(viface)
 	for v.Kind() == reflect.Ptr {
@@ -0,0 +1,58 @@
+package igbinary
+
+import "bytes"
+
+// Valid reports whether data is a well-formed igbinary document: an
+// optional version header followed by exactly one value whose codes,
+// lengths, string IDs and reference IDs are all valid. It returns the error
+// decoding would fail with. Arrays and objects are checked without being
+// built, but strings, class names and serialized data are still read into
+// memory as Token returns them.
+func Valid(data []byte) error {
+	d := NewDecoder(bytes.NewReader(data))
+	if d.peekHeader() {
+		if err := d.DecodeHeader(); err != nil {
+			return err
+		}
+	}
+	if err := d.validValue(); err != nil {
+		return err
+	}
+	if d.More() {
+		return decodeErrorF(`trailing data after value`)
+	}
+	return nil
+}
+
+func (d *Decoder) validValue() error {
+	t, err := d.Token()
+	if err != nil {
+		return err
+	}
+	switch t.Kind {
+	case NewRefToken:
+		if err := d.enter(); err != nil {
+			return err
+		}
+		defer d.leave()
+		return d.validValue()
+	case RefToken, ObjectRefToken:
+		if t.Ref >= len(d.refs) {
+			return decodeErrorF(`reference id %d not found`, t.Ref)
+		}
+	case ArrayStartToken, ObjectStartToken:
+		if err := d.enter(); err != nil {
+			return err
+		}
+		defer d.leave()
+		for i := 0; i < t.Len; i++ {
+			if _, err := d.decodeKey(); err != nil {
+				return err
+			}
+			if err := d.validValue(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package igbinary
+
+import (
+	"encoding/hex"
+	"github.com/stretchr/testify/suite"
+	"testing"
+)
+
+type ValidSuite struct {
+	suite.Suite
+}
+
+func (Suite *ValidSuite) TestValid() {
+	tests := []struct {
+		hex    string
+		errStr string
+	}{
+		{hex: `00`},
+		{hex: `0000000200`},
+		{hex: `14021101612506011101620101`},
+		{hex: `140206001703466f6f140006012201`},
+		{hex: ``, errStr: `EOF`},
+		{hex: `0000000300`, errStr: `igbinary: Decode(trailing data after value)`},
+		{hex: `060105`, errStr: `igbinary: Decode(trailing data after value)`},
+		{hex: `30`, errStr: `igbinary: Decode(invalid code=30 decoding token)`},
+		{hex: `0e00`, errStr: `igbinary: Decode(string id 0 not found)`},
+		{hex: `14010c00`, errStr: `igbinary: Decode(invalid code=c decoding array key)`},
+		{hex: `14020601`, errStr: `EOF`},
+		{hex: `140106002205`, errStr: `igbinary: Decode(reference id 5 not found)`},
+		{hex: `110561`, errStr: `unexpected EOF`},
+	}
+	for _, test := range tests {
+		B, err := hex.DecodeString(test.hex)
+		Suite.Require().Nil(err, test.hex)
+		if test.errStr == `` {
+			Suite.Nil(Valid(B), test.hex)
+		} else {
+			Suite.EqualError(Valid(B), test.errStr, test.hex)
+		}
+	}
+}
+
+func TestValidSuite(t *testing.T) {
+	suite.Run(t, new(ValidSuite))
+}
@@ -0,0 +1,79 @@
+package igbinary
+
+import "strconv"
+
+// Array is a PHP array decoded without a Go destination type. PHP arrays are
+// ordered maps keyed by int64 or string, Array keeps the original key order.
+type Array []Entry
+
+// Entry is a single key/value pair of an Array.
+type Entry struct {
+	Key   interface{}
+	Value interface{}
+}
+
+// Get returns the value stored under key. Integer keys may be given as any Go
+// integer type, numeric string keys match their integer form as in PHP.
+func (a Array) Get(key interface{}) (interface{}, bool) {
+	key, ok := arrayKey(key)
+	if !ok {
+		return nil, false
+	}
+	for _, e := range a {
+		if k, _ := arrayKey(e.Key); k == key {
+			return e.Value, true
+		}
+	}
+	return nil, false
+}
+
+// IsList reports whether the keys of a are the integers 0 to len(a)-1 in
+// order, i.e. whether PHP's array_is_list() would return true.
+func (a Array) IsList() bool {
+	for i, e := range a {
+		if k, ok := e.Key.(int64); !ok || k != int64(i) {
+			return false
+		}
+	}
+	return true
+}
+
+// Object is a PHP object decoded without a Go destination type.
+type Object struct {
+	Class string
+	Props Array
+}
+
+// SerializedObject is a PHP object of a class implementing Serializable. Its
+// payload is produced by the class itself and is opaque to igbinary.
+type SerializedObject struct {
+	Class string
+	Data  []byte
+}
+
+// ArrayKey returns key the way PHP stores it as an array index: integers as
+// int64 and strings holding the canonical decimal form of an integer as that
+// integer. It reports false for keys of other types.
+func ArrayKey(key interface{}) (interface{}, bool) {
+	return arrayKey(key)
+}
+
+// arrayKey normalises key the way PHP does when it is used as an array index.
+func arrayKey(key interface{}) (interface{}, bool) {
+	switch k := key.(type) {
+	case int64:
+		return k, true
+	case int:
+		return int64(k), true
+	case int32:
+		return int64(k), true
+	case uint32:
+		return int64(k), true
+	case string:
+		if n, err := strconv.ParseInt(k, 10, 64); err == nil && strconv.FormatInt(n, 10) == k {
+			return n, true
+		}
+		return k, true
+	}
+	return nil, false
+}